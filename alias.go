@@ -0,0 +1,72 @@
+package flags
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// AddAlias adds an alias which expands to the given arguments when given in
+// place of a command name, so that with `AddAlias("st", "status", "--short")`
+// the arguments `st -b` are dispatched as `status --short -b`. Aliases do not
+// shadow commands and are not expanded recursively.
+func (prog *Program) AddAlias(name string, args ...string) {
+	defer prog.lock()()
+	prog.Aliases = copyOnWrite(prog, prog.Aliases)
+	if prog.Aliases == nil {
+		prog.Aliases = make(map[string][]string)
+	}
+	prog.Aliases[name] = args
+}
+
+// LoadAliases adds the aliases defined in the file at the given path, such
+// as a file of user defined shorthands in the home directory. Each line of
+// the file has the form `name = command args...` with the arguments split
+// using shell quoting rules. Blank lines and lines starting with `#` are
+// ignored. A missing file is not an error.
+func (prog *Program) LoadAliases(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, expansion, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return fmt.Errorf(msg("%s:%d: expected `name = command`"), path, n)
+		}
+		args, err := Split(expansion)
+		if err != nil {
+			return fmt.Errorf(msg("%s:%d: %v"), path, n, err)
+		}
+		if len(args) == 0 {
+			return fmt.Errorf(msg("%s:%d: alias `%s` is empty"), path, n, name)
+		}
+		prog.AddAlias(name, args...)
+	}
+	return scanner.Err()
+}
+
+// expandAlias replaces the alias at the head of the arguments with its
+// expansion. Arguments starting with a command name are returned as is.
+func (prog Program) expandAlias(args []string) []string {
+	if _, ok := prog.Map[args[0]]; ok {
+		return args
+	}
+	expansion, ok := prog.Aliases[args[0]]
+	if !ok {
+		return args
+	}
+	return append(append([]string(nil), expansion...), args[1:]...)
+}
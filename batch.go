@@ -0,0 +1,68 @@
+package flags
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// BatchError is the error of a command line of a batch which failed.
+type BatchError struct {
+	Line int
+	Args []string
+	Err  error
+}
+
+// Error satisfies the error interface.
+func (e *BatchError) Error() string {
+	return fmt.Sprintf(msg("line %d: %v"), e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *BatchError) Unwrap() error { return e.Err }
+
+// Batch creates a command which reads command lines from the reader, or from
+// the standard input if the reader is nil, and dispatches each of them to
+// the commands of the program in a single process. The commands are given
+// an empty standard input when the lines are read from the standard input.
+// Blank lines and lines starting with `#` are skipped. Failing lines do not
+// stop the batch; their errors are returned joined as BatchErrors carrying
+// the line numbers.
+func Batch(prog *Program, r io.Reader) Command {
+	return func(ctx *Context) error {
+		ctx.setDefaults()
+		in := r
+		if in == nil {
+			in = ctx.Stdin
+		}
+		cmd := prog.Compile()
+		scanner := bufio.NewScanner(in)
+		errs := []error{}
+
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+			args, err := Split(text)
+			if err != nil {
+				errs = append(errs, &BatchError{line, nil, err})
+				continue
+			}
+
+			sub := ctx.child(ctx.Name, ctx.Desc, args)
+			if r == nil {
+				sub.Stdin = strings.NewReader("")
+			}
+			if err := sub.run(cmd); ExitCode(err) != ExitSuccess {
+				errs = append(errs, &BatchError{line, args, err})
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			errs = append(errs, err)
+		}
+		return errors.Join(errs...)
+	}
+}
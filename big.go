@@ -0,0 +1,99 @@
+package flags
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// BigIntValue represents an arbitrary precision integer argument value.
+type BigIntValue big.Int
+
+// NewBigIntValue creates a new BigIntValue. Zero is used if init is nil.
+func NewBigIntValue(init *big.Int) *BigIntValue {
+	p := new(big.Int)
+	if init != nil {
+		p.Set(init)
+	}
+	return (*BigIntValue)(p)
+}
+
+// Set will set attempt to convert the given string to a value. Base prefixes
+// such as `0x` and underscores between digits are accepted.
+func (p *BigIntValue) Set(s string) error {
+	x, ok := new(big.Int).SetString(s, 0)
+	if !ok {
+		return fmt.Errorf(msg("`%s` cannot be interpreted as %T"), s, x)
+	}
+	*p = BigIntValue(*x)
+	return nil
+}
+
+// String satisfies the fmt.Stringer interface.
+func (p *BigIntValue) String() string {
+	return (*big.Int)(p).String()
+}
+
+// BigFloatPrec is the mantissa precision in bits used for a BigFloatValue
+// created without an initial value.
+const BigFloatPrec = 256
+
+// BigFloatValue represents an arbitrary precision floating point argument
+// value. Values are parsed with the precision of the initial value.
+type BigFloatValue big.Float
+
+// NewBigFloatValue creates a new BigFloatValue. Zero with BigFloatPrec bits
+// of precision is used if init is nil.
+func NewBigFloatValue(init *big.Float) *BigFloatValue {
+	p := new(big.Float).SetPrec(BigFloatPrec)
+	if init != nil {
+		p.SetPrec(init.Prec()).Set(init)
+	}
+	return (*BigFloatValue)(p)
+}
+
+// Set will set attempt to convert the given string to a value.
+func (p *BigFloatValue) Set(s string) error {
+	x, ok := new(big.Float).SetPrec((*big.Float)(p).Prec()).SetString(s)
+	if !ok {
+		return fmt.Errorf(msg("`%s` cannot be interpreted as %T"), s, x)
+	}
+	*p = BigFloatValue(*x)
+	return nil
+}
+
+// String satisfies the fmt.Stringer interface.
+func (p *BigFloatValue) String() string {
+	return (*big.Float)(p).Text('g', -1)
+}
+
+// BigInt adds an arbitrary precision integer flag to the optional argument
+// list.
+func (opt *Optional) BigInt(short rune, long string, init *big.Int, usage string) *big.Int {
+	value := NewBigIntValue(init)
+	opt.Register(short, long, value, usage)
+	return (*big.Int)(value)
+}
+
+// BigFloat adds an arbitrary precision floating point flag to the optional
+// argument list.
+func (opt *Optional) BigFloat(short rune, long string, init *big.Float, usage string) *big.Float {
+	value := NewBigFloatValue(init)
+	opt.Register(short, long, value, usage)
+	return (*big.Float)(value)
+}
+
+// BigInt adds an arbitrary precision integer to the positional argument
+// list.
+func (pos *Positional) BigInt(name, usage string) *big.Int {
+	value := NewBigIntValue(nil)
+	pos.Register(name, value, usage)
+	return (*big.Int)(value)
+}
+
+// BigFloat adds an arbitrary precision floating point number to the
+// positional argument list.
+func (pos *Positional) BigFloat(name, usage string) *big.Float {
+	value := NewBigFloatValue(nil)
+	pos.Register(name, value, usage)
+	return (*big.Float)(value)
+}
@@ -0,0 +1,205 @@
+package flags
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BindValue represents repeated `path=value` argument values which are bound
+// to the fields of a target struct, as in `--set replicas=3 --set
+// image.tag=v2`. The path is a dot separated list of field names, matched to
+// the `flag` or `json` tag of a field or otherwise to its name ignoring case
+// and dashes. Pointers to structs are allocated as needed and a path segment
+// following a map with string keys is taken as the key. Values are converted
+// to the type of the field, where slices take comma separated elements, or
+// by a value of the type registered under the name in the `type` tag of the
+// field, as in `type:"ipnet"`, whose content is then assigned to the field as
+// by DecodeArguments.
+type BindValue struct {
+	target reflect.Value
+	init   reflect.Value
+	pairs  []string
+}
+
+// NewBindValue creates a new BindValue binding to the target, which must be a
+// pointer to a struct.
+func NewBindValue(target interface{}) *BindValue {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		panic(fmt.Errorf("bind target must be a pointer to a struct, got %T", target))
+	}
+	init := reflect.New(rv.Elem().Type()).Elem()
+	init.Set(rv.Elem())
+	return &BindValue{target: rv.Elem(), init: init}
+}
+
+// Set will set attempt to bind the given pair to the target.
+func (v *BindValue) Set(s string) error {
+	path, value, ok := strings.Cut(s, "=")
+	if !ok || path == "" {
+		return fmt.Errorf(msg("`%s` is not a path=value pair"), s)
+	}
+	if err := bindPath(v.target, strings.Split(path, "."), value); err != nil {
+		return fmt.Errorf(msg("cannot bind `%s`: %v"), s, err)
+	}
+	v.pairs = append(v.pairs, s)
+	return nil
+}
+
+// Reset restores the fields of the target to their initial values. Maps
+// within the target are not copied and keep entries set since.
+func (v *BindValue) Reset() {
+	v.target.Set(v.init)
+	v.pairs = nil
+}
+
+// Type returns the name of the value type.
+func (v *BindValue) Type() string { return "path=value" }
+
+// String satisfies the fmt.Stringer interface.
+func (v *BindValue) String() string {
+	return strings.Join(v.pairs, ",")
+}
+
+// Bind adds a repeatable `path=value` flag bound to the fields of the target
+// struct to the optional argument list.
+func (opt *Optional) Bind(short rune, long string, target interface{}, usage string) {
+	opt.Register(short, long, NewBindValue(target), usage)
+}
+
+// bindPath sets the field at the path within rv to the value.
+func bindPath(rv reflect.Value, path []string, value string) error {
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		rv = rv.Elem()
+	}
+	if len(path) == 0 {
+		return bindScalar(rv, value)
+	}
+	switch rv.Kind() {
+	case reflect.Struct:
+		field, typ, ok := fieldByPath(rv, path[0])
+		if !ok {
+			return fmt.Errorf(msg("no field named `%s`"), path[0])
+		}
+		if typ != "" && len(path) == 1 {
+			return bindTyped(field, typ, value)
+		}
+		return bindPath(field, path[1:], value)
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf(msg("map keys of type %s are not supported"), rv.Type().Key())
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+		key := reflect.ValueOf(path[0]).Convert(rv.Type().Key())
+		elem := reflect.New(rv.Type().Elem()).Elem()
+		if old := rv.MapIndex(key); old.IsValid() {
+			elem.Set(old)
+		}
+		if err := bindPath(elem, path[1:], value); err != nil {
+			return err
+		}
+		rv.SetMapIndex(key, elem)
+		return nil
+	default:
+		return fmt.Errorf(msg("`%s` cannot be set within %s"), path[0], rv.Type())
+	}
+}
+
+// fieldByPath returns the exported field of the struct matching the name and
+// the value type named in its `type` tag.
+func fieldByPath(rv reflect.Value, name string) (reflect.Value, string, bool) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		tag := field.Tag.Get("flag")
+		if tag == "" {
+			tag, _, _ = strings.Cut(field.Tag.Get("json"), ",")
+		}
+		if tag == "-" {
+			continue
+		}
+		if tag == name || (tag == "" && normalizeFieldName(field.Name) == normalizeFieldName(name)) {
+			return rv.Field(i), field.Tag.Get("type"), true
+		}
+	}
+	return reflect.Value{}, "", false
+}
+
+// bindTyped converts the string with a value of the registered type and
+// assigns its content to rv.
+func bindTyped(rv reflect.Value, typ, s string) error {
+	value, err := NewValue(typ)
+	if err != nil {
+		return err
+	}
+	if err := value.Set(s); err != nil {
+		return err
+	}
+	return assignValue(rv, value)
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// bindScalar converts the string to the type of rv and sets it.
+func bindScalar(rv reflect.Value, s string) error {
+	if u, ok := rv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	if rv.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf(msg("`%s` cannot be interpreted as a duration"), s)
+		}
+		rv.SetInt(int64(d))
+		return nil
+	}
+	var err error
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(s)
+	case reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(s)
+		rv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		n, err = strconv.ParseInt(s, 10, rv.Type().Bits())
+		rv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var n uint64
+		n, err = strconv.ParseUint(s, 10, rv.Type().Bits())
+		rv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		f, err = strconv.ParseFloat(s, rv.Type().Bits())
+		rv.SetFloat(f)
+	case reflect.Slice:
+		elems := strings.Split(s, ",")
+		slice := reflect.MakeSlice(rv.Type(), len(elems), len(elems))
+		for i, elem := range elems {
+			if err := bindScalar(slice.Index(i), elem); err != nil {
+				return err
+			}
+		}
+		rv.Set(slice)
+		return nil
+	default:
+		return fmt.Errorf(msg("fields of type %s are not supported"), rv.Type())
+	}
+	if err != nil {
+		return fmt.Errorf(msg("`%s` cannot be interpreted as %s"), s, rv.Type())
+	}
+	return nil
+}
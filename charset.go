@@ -0,0 +1,198 @@
+package flags
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// Charsets maps the names of character encodings to the encodings available
+// to EncodedValue. Names are looked up case insensitively with underscores
+// treated as hyphens. Register additional encodings by adding to the map
+// before parsing.
+var Charsets = map[string]encoding.Encoding{
+	"utf-8":       unicode.UTF8,
+	"utf-8-bom":   unicode.UTF8BOM,
+	"utf-16":      unicode.UTF16(unicode.LittleEndian, unicode.UseBOM),
+	"utf-16le":    unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM),
+	"utf-16be":    unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM),
+	"shift-jis":   japanese.ShiftJIS,
+	"sjis":        japanese.ShiftJIS,
+	"cp932":       japanese.ShiftJIS,
+	"windows-31j": japanese.ShiftJIS,
+	"euc-jp":      japanese.EUCJP,
+}
+
+// LookupCharset returns the character encoding with the given name. UTF-8 is
+// returned for an empty name.
+func LookupCharset(name string) (encoding.Encoding, error) {
+	if name == "" {
+		return unicode.UTF8, nil
+	}
+	key := strings.ReplaceAll(strings.ToLower(name), "_", "-")
+	if enc, ok := Charsets[key]; ok {
+		return enc, nil
+	}
+	return nil, fmt.Errorf(msg("`%s` is not a known character encoding"), name)
+}
+
+// CharsetValue represents a character encoding name argument value.
+type CharsetValue string
+
+// NewCharsetValue creates a new CharsetValue.
+func NewCharsetValue(init string) *CharsetValue {
+	p := new(string)
+	*p = init
+	return (*CharsetValue)(p)
+}
+
+// Set will set attempt to convert the given string to a value.
+func (p *CharsetValue) Set(s string) error {
+	if _, err := LookupCharset(s); err != nil {
+		return err
+	}
+	*p = CharsetValue(s)
+	return nil
+}
+
+// String satisfies the fmt.Stringer interface.
+func (p CharsetValue) String() string {
+	return string(p)
+}
+
+// EncodedValue represents a file argument value for creating whose content is
+// written in a character encoding other than UTF-8, such as Shift-JIS. Runes
+// which cannot be represented in the encoding are replaced. The value is a
+// writer encoding its input into the file, which is created when written to
+// if it is pending.
+type EncodedValue struct {
+	// File is the file written to, whose fields such as Append may be set
+	// before parsing.
+	File *CreateValue
+
+	// Charset is the name of the character encoding, looked up in Charsets
+	// when the value is first written to. UTF-8 is used if empty.
+	Charset string
+
+	w *transform.Writer
+}
+
+// NewEncodedValue creates a new EncodedValue writing in the given character
+// encoding.
+func NewEncodedValue(init *os.File, charset string) *EncodedValue {
+	return &EncodedValue{File: NewCreateValue(init), Charset: charset}
+}
+
+// Set will set attempt to convert the given string to a value.
+func (v *EncodedValue) Set(s string) error {
+	if err := v.flush(); err != nil {
+		return err
+	}
+	return v.File.Set(s)
+}
+
+// String satisfies the fmt.Stringer interface.
+func (v *EncodedValue) String() string {
+	return v.File.String()
+}
+
+// Fd returns the file descriptor of the file.
+func (v *EncodedValue) Fd() uintptr {
+	return v.File.Fd()
+}
+
+// Writer returns the value for writing after checking that the character
+// encoding is known, creating the file if it is pending.
+func (v *EncodedValue) Writer() (io.WriteCloser, error) {
+	if _, err := v.writer(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (v *EncodedValue) writer() (io.Writer, error) {
+	if v.w != nil {
+		return v.w, nil
+	}
+	enc, err := LookupCharset(v.Charset)
+	if err != nil {
+		return nil, err
+	}
+	f, err := v.File.Writer()
+	if err != nil {
+		return nil, err
+	}
+	v.w = transform.NewWriter(f, encoding.ReplaceUnsupported(enc.NewEncoder()))
+	return v.w, nil
+}
+
+// Write encodes p into the file.
+func (v *EncodedValue) Write(p []byte) (int, error) {
+	w, err := v.writer()
+	if err != nil {
+		return 0, err
+	}
+	return w.Write(p)
+}
+
+// flush the pending output of the encoder.
+func (v *EncodedValue) flush() error {
+	if v.w == nil {
+		return nil
+	}
+	w := v.w
+	v.w = nil
+	return w.Close()
+}
+
+// Close the file if it was created by the value after flushing the encoder.
+// The initial file is left open.
+func (v *EncodedValue) Close() error {
+	err := v.flush()
+	if cerr := v.File.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Reset closes the file if it was created by the value and restores the
+// initial file.
+func (v *EncodedValue) Reset() {
+	v.Close()
+	v.File.Reset()
+}
+
+// Encoded adds a file for writing in a character encoding to the optional
+// argument list. Unless encoding is empty, a flag with the long name encoding
+// is added as well for selecting the character encoding, which defaults to
+// charset. The file will be closed after the command returns if parsed with
+// Context.Parse.
+func (opt *Optional) Encoded(short rune, long, encoding string, init *os.File, charset, usage string) *EncodedValue {
+	value := NewEncodedValue(init, charset)
+	opt.Register(short, long, value, usage)
+	if encoding != "" {
+		opt.Register(0, encoding, (*CharsetValue)(&value.Charset), "character encoding of --"+long)
+	}
+	return value
+}
+
+// Charset adds a character encoding name flag to the optional argument list.
+func (opt *Optional) Charset(short rune, long, init, usage string) *string {
+	value := NewCharsetValue(init)
+	opt.Register(short, long, value, usage)
+	return (*string)(value)
+}
+
+// EncodedOutput adds a file written in the given character encoding which
+// when omitted will write to os.Stdout, like Output.
+func (pos *Positional) EncodedOutput(charset, usage string) *EncodedValue {
+	value := NewEncodedValue(os.Stdout, charset)
+	pos.Out = &Argument{value, usage}
+	return value
+}
@@ -0,0 +1,128 @@
+package flags
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+)
+
+// Digests maps the names of the algorithms accepted by ChecksumValue to
+// their constructors.
+var Digests = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// ChecksumValue represents a file argument value for opening whose content is
+// verified against a digest given along with the path as `path#sha256=hex`.
+// The file is opened and verified when set, so the command receives the
+// verified file positioned at its start. Lazy has no effect.
+type ChecksumValue struct {
+	*OpenValue
+	algorithm string
+	digest    []byte
+}
+
+// NewChecksumValue creates a new ChecksumValue.
+func NewChecksumValue(init *os.File) *ChecksumValue {
+	return &ChecksumValue{OpenValue: NewOpenValue(init)}
+}
+
+// splitDigest splits the argument into the path, the algorithm, and the
+// expected digest.
+func splitDigest(s string) (string, string, []byte, error) {
+	i := strings.LastIndexByte(s, '#')
+	if i < 0 {
+		return "", "", nil, fmt.Errorf(msg("`%s` has no digest, expected `path#sha256=hex`"), s)
+	}
+	path, spec := s[:i], s[i+1:]
+	algorithm, sum, ok := strings.Cut(spec, "=")
+	if _, known := Digests[algorithm]; !ok || !known {
+		return "", "", nil, fmt.Errorf(msg("`%s` is not a known digest algorithm"), algorithm)
+	}
+	digest, err := hex.DecodeString(sum)
+	if err != nil || len(digest) != Digests[algorithm]().Size() {
+		return "", "", nil, fmt.Errorf(msg("`%s` is not a valid %s digest"), sum, algorithm)
+	}
+	return path, algorithm, digest, nil
+}
+
+// Set will set attempt to convert the given string to a value.
+func (v *ChecksumValue) Set(s string) error {
+	path, algorithm, digest, err := splitDigest(s)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	h := Digests[algorithm]()
+	if _, err := io.Copy(h, f); err != nil {
+		f.Close()
+		return err
+	}
+	if sum := h.Sum(nil); string(sum) != string(digest) {
+		f.Close()
+		return fmt.Errorf(msg("`%s` has %s digest `%x`, expected `%x`"), path, algorithm, sum, digest)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	v.OpenValue.Close()
+	v.File, v.opened = f, true
+	v.algorithm, v.digest = algorithm, digest
+	return nil
+}
+
+// Digest returns the algorithm and the hex encoded digest the file was
+// verified against, or empty strings if no file was set.
+func (v *ChecksumValue) Digest() (string, string) {
+	return v.algorithm, hex.EncodeToString(v.digest)
+}
+
+// String satisfies the fmt.Stringer interface.
+func (v *ChecksumValue) String() string {
+	if v.algorithm == "" {
+		return v.OpenValue.String()
+	}
+	return fmt.Sprintf("%s#%s=%x", v.OpenValue.String(), v.algorithm, v.digest)
+}
+
+// Close the file if it was opened by the value. The initial file is left
+// open.
+func (v *ChecksumValue) Close() error {
+	v.algorithm, v.digest = "", nil
+	return v.OpenValue.Close()
+}
+
+// Reset closes the file if it was opened by the value and restores the
+// initial file.
+func (v *ChecksumValue) Reset() {
+	v.Close()
+	v.OpenValue.Reset()
+}
+
+// Checksum adds a file for reading verified against a digest given as
+// `path#sha256=hex` to the optional argument list. The file will be closed
+// after the command returns if parsed with Context.Parse.
+func (opt *Optional) Checksum(short rune, long string, init *os.File, usage string) *ChecksumValue {
+	value := NewChecksumValue(init)
+	opt.Register(short, long, value, usage)
+	return value
+}
+
+// Checksum adds a file for reading verified against a digest given as
+// `path#sha256=hex` to the positional argument list. The file will be closed
+// after the command returns if parsed with Context.Parse.
+func (pos *Positional) Checksum(name, usage string) *ChecksumValue {
+	value := NewChecksumValue(nil)
+	pos.Register(name, value, usage)
+	return value
+}
@@ -0,0 +1,206 @@
+package flags
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// RGBA represents a color with 8-bit red, green, blue, and alpha channels.
+// The color channels are not premultiplied by the alpha.
+type RGBA struct {
+	R, G, B, A uint8
+}
+
+// Color returns the color as a color.Color.
+func (c RGBA) Color() color.Color {
+	return color.NRGBA{c.R, c.G, c.B, c.A}
+}
+
+// String returns the color as `#rrggbb`, or as `#rrggbbaa` if it is not
+// opaque.
+func (c RGBA) String() string {
+	if c.A == 0xff {
+		return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+	}
+	return fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)
+}
+
+// ParseColor parses a color given as `#rgb`, `#rgba`, `#rrggbb`, `#rrggbbaa`,
+// `rgb(r, g, b)`, `rgba(r, g, b, a)`, or a CSS color name such as `tomato`.
+// The channels of the functional forms are integers from 0 to 255 or
+// percentages, and the alpha is a number from 0 to 1 or a percentage.
+func ParseColor(s string) (RGBA, error) {
+	t := strings.ToLower(strings.TrimSpace(s))
+	if t == "transparent" {
+		return RGBA{}, nil
+	}
+	if n, ok := colorNames[t]; ok {
+		return RGBA{uint8(n >> 16), uint8(n >> 8), uint8(n), 0xff}, nil
+	}
+	if strings.HasPrefix(t, "#") {
+		if c, ok := parseHexColor(t[1:]); ok {
+			return c, nil
+		}
+		return RGBA{}, fmt.Errorf(msg("`%s` is not a valid hex color"), s)
+	}
+	for _, fn := range []string{"rgba", "rgb"} {
+		if !strings.HasPrefix(t, fn+"(") || !strings.HasSuffix(t, ")") {
+			continue
+		}
+		if c, ok := parseRGBFunc(t[len(fn)+1 : len(t)-1]); ok {
+			return c, nil
+		}
+		return RGBA{}, fmt.Errorf(msg("`%s` is not a valid %s color"), s, fn)
+	}
+	return RGBA{}, fmt.Errorf(msg("`%s` is not a color"), s)
+}
+
+func parseHexColor(s string) (RGBA, bool) {
+	if len(s) == 3 || len(s) == 4 {
+		long := make([]byte, 0, 2*len(s))
+		for i := 0; i < len(s); i++ {
+			long = append(long, s[i], s[i])
+		}
+		s = string(long)
+	}
+	if len(s) != 6 && len(s) != 8 {
+		return RGBA{}, false
+	}
+	if len(s) == 6 {
+		s += "ff"
+	}
+	n, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return RGBA{}, false
+	}
+	return RGBA{uint8(n >> 24), uint8(n >> 16), uint8(n >> 8), uint8(n)}, true
+}
+
+func parseRGBFunc(s string) (RGBA, bool) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 && len(parts) != 4 {
+		return RGBA{}, false
+	}
+	ch := [4]uint8{0, 0, 0, 0xff}
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		scale := 255.0
+		if i == 3 && !strings.HasSuffix(part, "%") {
+			scale = 1
+		}
+		x, ok := parseChannel(part, scale)
+		if !ok {
+			return RGBA{}, false
+		}
+		ch[i] = x
+	}
+	return RGBA{ch[0], ch[1], ch[2], ch[3]}, true
+}
+
+// parseChannel parses a number from 0 to max or a percentage into a channel.
+func parseChannel(s string, max float64) (uint8, bool) {
+	if p, ok := strings.CutSuffix(s, "%"); ok {
+		s, max = p, 100
+	}
+	x, err := strconv.ParseFloat(s, 64)
+	if err != nil || x < 0 || x > max {
+		return 0, false
+	}
+	return uint8(x/max*255 + 0.5), true
+}
+
+// ColorValue represents a color argument value. See ParseColor for the
+// accepted forms.
+type ColorValue RGBA
+
+// NewColorValue creates a new ColorValue.
+func NewColorValue(init RGBA) *ColorValue {
+	p := new(RGBA)
+	*p = init
+	return (*ColorValue)(p)
+}
+
+// Set will set attempt to convert the given string to a value.
+func (p *ColorValue) Set(s string) error {
+	c, err := ParseColor(s)
+	if err != nil {
+		return err
+	}
+	*p = ColorValue(c)
+	return nil
+}
+
+// String satisfies the fmt.Stringer interface.
+func (p ColorValue) String() string {
+	return RGBA(p).String()
+}
+
+// Color adds a color flag to the optional argument list.
+func (opt *Optional) Color(short rune, long string, init RGBA, usage string) *RGBA {
+	value := NewColorValue(init)
+	opt.Register(short, long, value, usage)
+	return (*RGBA)(value)
+}
+
+// Color adds a color to the positional argument list.
+func (pos *Positional) Color(name, usage string) *RGBA {
+	value := NewColorValue(RGBA{})
+	pos.Register(name, value, usage)
+	return (*RGBA)(value)
+}
+
+// colorNames maps the CSS color names to their `0xrrggbb` values.
+var colorNames = map[string]uint32{
+	"aliceblue": 0xf0f8ff, "antiquewhite": 0xfaebd7, "aqua": 0x00ffff,
+	"aquamarine": 0x7fffd4, "azure": 0xf0ffff, "beige": 0xf5f5dc,
+	"bisque": 0xffe4c4, "black": 0x000000, "blanchedalmond": 0xffebcd,
+	"blue": 0x0000ff, "blueviolet": 0x8a2be2, "brown": 0xa52a2a,
+	"burlywood": 0xdeb887, "cadetblue": 0x5f9ea0, "chartreuse": 0x7fff00,
+	"chocolate": 0xd2691e, "coral": 0xff7f50, "cornflowerblue": 0x6495ed,
+	"cornsilk": 0xfff8dc, "crimson": 0xdc143c, "cyan": 0x00ffff,
+	"darkblue": 0x00008b, "darkcyan": 0x008b8b, "darkgoldenrod": 0xb8860b,
+	"darkgray": 0xa9a9a9, "darkgreen": 0x006400, "darkgrey": 0xa9a9a9,
+	"darkkhaki": 0xbdb76b, "darkmagenta": 0x8b008b, "darkolivegreen": 0x556b2f,
+	"darkorange": 0xff8c00, "darkorchid": 0x9932cc, "darkred": 0x8b0000,
+	"darksalmon": 0xe9967a, "darkseagreen": 0x8fbc8f, "darkslateblue": 0x483d8b,
+	"darkslategray": 0x2f4f4f, "darkslategrey": 0x2f4f4f, "darkturquoise": 0x00ced1,
+	"darkviolet": 0x9400d3, "deeppink": 0xff1493, "deepskyblue": 0x00bfff,
+	"dimgray": 0x696969, "dimgrey": 0x696969, "dodgerblue": 0x1e90ff,
+	"firebrick": 0xb22222, "floralwhite": 0xfffaf0, "forestgreen": 0x228b22,
+	"fuchsia": 0xff00ff, "gainsboro": 0xdcdcdc, "ghostwhite": 0xf8f8ff,
+	"gold": 0xffd700, "goldenrod": 0xdaa520, "gray": 0x808080,
+	"green": 0x008000, "greenyellow": 0xadff2f, "grey": 0x808080,
+	"honeydew": 0xf0fff0, "hotpink": 0xff69b4, "indianred": 0xcd5c5c,
+	"indigo": 0x4b0082, "ivory": 0xfffff0, "khaki": 0xf0e68c,
+	"lavender": 0xe6e6fa, "lavenderblush": 0xfff0f5, "lawngreen": 0x7cfc00,
+	"lemonchiffon": 0xfffacd, "lightblue": 0xadd8e6, "lightcoral": 0xf08080,
+	"lightcyan": 0xe0ffff, "lightgoldenrodyellow": 0xfafad2, "lightgray": 0xd3d3d3,
+	"lightgreen": 0x90ee90, "lightgrey": 0xd3d3d3, "lightpink": 0xffb6c1,
+	"lightsalmon": 0xffa07a, "lightseagreen": 0x20b2aa, "lightskyblue": 0x87cefa,
+	"lightslategray": 0x778899, "lightslategrey": 0x778899, "lightsteelblue": 0xb0c4de,
+	"lightyellow": 0xffffe0, "lime": 0x00ff00, "limegreen": 0x32cd32,
+	"linen": 0xfaf0e6, "magenta": 0xff00ff, "maroon": 0x800000,
+	"mediumaquamarine": 0x66cdaa, "mediumblue": 0x0000cd, "mediumorchid": 0xba55d3,
+	"mediumpurple": 0x9370db, "mediumseagreen": 0x3cb371, "mediumslateblue": 0x7b68ee,
+	"mediumspringgreen": 0x00fa9a, "mediumturquoise": 0x48d1cc, "mediumvioletred": 0xc71585,
+	"midnightblue": 0x191970, "mintcream": 0xf5fffa, "mistyrose": 0xffe4e1,
+	"moccasin": 0xffe4b5, "navajowhite": 0xffdead, "navy": 0x000080,
+	"oldlace": 0xfdf5e6, "olive": 0x808000, "olivedrab": 0x6b8e23,
+	"orange": 0xffa500, "orangered": 0xff4500, "orchid": 0xda70d6,
+	"palegoldenrod": 0xeee8aa, "palegreen": 0x98fb98, "paleturquoise": 0xafeeee,
+	"palevioletred": 0xdb7093, "papayawhip": 0xffefd5, "peachpuff": 0xffdab9,
+	"peru": 0xcd853f, "pink": 0xffc0cb, "plum": 0xdda0dd,
+	"powderblue": 0xb0e0e6, "purple": 0x800080, "rebeccapurple": 0x663399,
+	"red": 0xff0000, "rosybrown": 0xbc8f8f, "royalblue": 0x4169e1,
+	"saddlebrown": 0x8b4513, "salmon": 0xfa8072, "sandybrown": 0xf4a460,
+	"seagreen": 0x2e8b57, "seashell": 0xfff5ee, "sienna": 0xa0522d,
+	"silver": 0xc0c0c0, "skyblue": 0x87ceeb, "slateblue": 0x6a5acd,
+	"slategray": 0x708090, "slategrey": 0x708090, "snow": 0xfffafa,
+	"springgreen": 0x00ff7f, "steelblue": 0x4682b4, "tan": 0xd2b48c,
+	"teal": 0x008080, "thistle": 0xd8bfd8, "tomato": 0xff6347,
+	"turquoise": 0x40e0d0, "violet": 0xee82ee, "wheat": 0xf5deb3,
+	"white": 0xffffff, "whitesmoke": 0xf5f5f5, "yellow": 0xffff00,
+	"yellowgreen": 0x9acd32,
+}
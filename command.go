@@ -2,8 +2,18 @@ package flags
 
 import (
 	"fmt"
+	"io/ioutil"
+	"maps"
 	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"syscall"
+
+	wrap "gopkg.in/ktnyt/wrap.v1"
 )
 
 func shift(ss []string) (string, []string) {
@@ -18,48 +28,448 @@ func Args() (*Positional, *Optional) {
 // Command represents a executable command.
 type Command func(*Context) error
 
-// CommandDescription carries a command and its description.
+// Middleware wraps a command, for example to attach values to the context
+// before the command runs.
+type Middleware func(Command) Command
+
+// CommandDescription carries a command and its description. Prog is set if
+// the command is a nested program.
 type CommandDescription struct {
 	Desc string
 	Cmd  Command
+	Prog *Program
+	Doc  Doc
+}
+
+// Doc carries the documentation of a command shown in its help in addition to
+// the one line description.
+type Doc struct {
+	// Long is a detailed description of the command.
+	Long string `json:"long,omitempty"`
+
+	// Synopsis replaces the usage line generated from the arguments.
+	Synopsis string `json:"synopsis,omitempty"`
+
+	// Examples are worked examples of using the command.
+	Examples []Example `json:"examples,omitempty"`
+}
+
+// Example represents a worked example of using a command.
+type Example struct {
+	Desc    string `json:"desc,omitempty"`
+	Command string `json:"command"`
 }
 
-// Program represents a list of named commands.
+// Program represents a list of named commands. A Program created with
+// NewProgram is safe for concurrent use, so commands may be added from the
+// init functions of several packages or while the compiled program runs.
+// Fields must not be modified directly once the program is shared.
 type Program struct {
 	Map map[string]CommandDescription
+
+	// Order holds the command names in the order of registration.
+	Order []string
+
+	// KeepOrder lists the commands in the order of registration instead of
+	// alphabetically.
+	KeepOrder bool
+
+	// Doc is shown in the help of the program.
+	Doc Doc
+
+	// Topics map the names of help topics to their texts, shown with
+	// `help <topic>`.
+	Topics map[string]string
+
+	// Aliases map names to the arguments they expand to when given in place
+	// of a command name. See AddAlias.
+	Aliases map[string][]string
+
+	// Renamed maps the former names of renamed commands to their current
+	// names. See Rename.
+	Renamed map[string]string
+
+	// Middlewares wrap each command dispatched by the program, the first
+	// being the outermost.
+	Middlewares []Middleware
+
+	// Observers are notified of each command dispatched by the program.
+	Observers []Observer
+
+	// Chain enables running several commands in one invocation, separated
+	// by the given argument, e.g. `,` for `prog build , test`. The commands
+	// run in order, stopping at the first error, and share the values
+	// attached to the context of the program.
+	Chain string
+
+	// Autocorrect enables running the command with the name closest to an
+	// unknown command name if there is only one such command. The user is
+	// asked to confirm if the standard input is interactive and notified of
+	// the correction otherwise.
+	Autocorrect bool
+
+	// External enables dispatching unknown commands to executables named
+	// `<program>-<command>` found in the PATH, as git does for plugins.
+	External bool
+
+	shared *programShared
+}
+
+// programShared is shared by the copies of a program created with
+// NewProgram, guarding the program and leading the compiled commands of any
+// copy back to it.
+type programShared struct {
+	mu   sync.RWMutex
+	prog *Program
 }
 
 // NewProgram creates a new Program.
 func NewProgram() *Program {
-	return &Program{make(map[string]CommandDescription)}
+	prog := &Program{Map: make(map[string]CommandDescription)}
+	prog.shared = &programShared{prog: prog}
+	return prog
+}
+
+func (prog *Program) lock() func() {
+	if prog.shared == nil {
+		return func() {}
+	}
+	prog.shared.mu.Lock()
+	return prog.shared.mu.Unlock
+}
+
+// snapshot returns a shallow copy of the program. The methods of a program
+// created with NewProgram replace its maps instead of modifying them and
+// only append to its slices, so the copy is not affected by later
+// modifications.
+func (prog *Program) snapshot() Program {
+	if prog.shared != nil {
+		prog.shared.mu.RLock()
+		defer prog.shared.mu.RUnlock()
+	}
+	snap := *prog
+	snap.shared = nil
+	return snap
+}
+
+// copyOnWrite returns a copy of the map to be modified in place of the map
+// if the program is guarded, leaving the map intact for snapshots.
+func copyOnWrite[K comparable, V any](prog *Program, m map[K]V) map[K]V {
+	if prog.shared == nil || m == nil {
+		return m
+	}
+	return maps.Clone(m)
+}
+
+// Clone returns a deep copy of the program which may be modified and compiled
+// independently of the program, e.g. to add commands to a copy of Main in a
+// test without affecting other tests. Nested programs are cloned as well.
+func (prog *Program) Clone() *Program {
+	snap := prog.snapshot()
+	clone := &snap
+	clone.Map = maps.Clone(clone.Map)
+	clone.Order = append([]string(nil), clone.Order...)
+	clone.Topics = maps.Clone(clone.Topics)
+	clone.Aliases = maps.Clone(clone.Aliases)
+	clone.Renamed = maps.Clone(clone.Renamed)
+	clone.Middlewares = append([]Middleware(nil), clone.Middlewares...)
+	clone.Observers = append([]Observer(nil), clone.Observers...)
+	for name, cmd := range clone.Map {
+		if cmd.Prog != nil {
+			cmd.Prog = cmd.Prog.Clone()
+			cmd.Cmd = cmd.Prog.Compile()
+			clone.Map[name] = cmd
+		}
+	}
+	clone.shared = &programShared{prog: clone}
+	return clone
+}
+
+// Reset removes all commands, topics, aliases, middlewares, and observers
+// from the program and clears its settings, leaving it as if it was newly
+// created with NewProgram.
+func (prog *Program) Reset() {
+	defer prog.lock()()
+	prog.Map = make(map[string]CommandDescription)
+	prog.Order = nil
+	prog.KeepOrder = false
+	prog.Doc = Doc{}
+	prog.Topics = nil
+	prog.Aliases = nil
+	prog.Renamed = nil
+	prog.Middlewares = nil
+	prog.Observers = nil
+	prog.Chain = ""
+	prog.Autocorrect = false
+	prog.External = false
 }
 
 // Add a Command with the given name and description.
 func (prog *Program) Add(name, desc string, cmd Command) {
-	prog.Map[name] = CommandDescription{desc, cmd}
+	prog.register(name, CommandDescription{desc, cmd, nil, Doc{}})
+}
+
+// AddProgram adds a nested Program with the given name and description. The
+// commands of the nested program are listed in the help of the program.
+func (prog *Program) AddProgram(name, desc string, sub *Program) {
+	prog.register(name, CommandDescription{desc, sub.Compile(), sub, sub.snapshot().Doc})
+}
+
+// Use appends middlewares wrapping each command dispatched by the program.
+func (prog *Program) Use(mw ...Middleware) {
+	defer prog.lock()()
+	prog.Middlewares = append(prog.Middlewares, mw...)
+}
+
+// Rename keeps the command formerly named old available under that name
+// after it has been renamed to name. The former name forwards to the command
+// while printing a deprecation notice and is marked as deprecated in the
+// help.
+func (prog *Program) Rename(old, name string) {
+	defer prog.lock()()
+	prog.Renamed = copyOnWrite(prog, prog.Renamed)
+	if prog.Renamed == nil {
+		prog.Renamed = make(map[string]string)
+	}
+	prog.Renamed[old] = name
+}
+
+// AddTopic adds a help topic which is not a command but is shown with
+// `help <topic>` unless a command named `help` exists. The first line of the
+// text is listed in the help of the program.
+func (prog *Program) AddTopic(name, text string) {
+	defer prog.lock()()
+	prog.Topics = copyOnWrite(prog, prog.Topics)
+	if prog.Topics == nil {
+		prog.Topics = make(map[string]string)
+	}
+	prog.Topics[name] = text
+}
+
+// Describe attaches the documentation to the command with the given name. It
+// panics if no such command exists.
+func (prog *Program) Describe(name string, doc Doc) {
+	defer prog.lock()()
+	cmd, ok := prog.Map[name]
+	if !ok {
+		panic(fmt.Errorf("no command named `%s`", name))
+	}
+	cmd.Doc = doc
+	prog.Map = copyOnWrite(prog, prog.Map)
+	prog.Map[name] = cmd
 }
 
-// Compile the subcommands into a single command.
+func (prog *Program) register(name string, cmd CommandDescription) {
+	defer prog.lock()()
+	if _, ok := prog.Map[name]; !ok {
+		prog.Order = append(prog.Order, name)
+	}
+	prog.Map = copyOnWrite(prog, prog.Map)
+	prog.Map[name] = cmd
+}
+
+// names returns the command names in the order in which they are listed.
+// Commands missing from Order are listed after the others.
+func (prog Program) names() []string {
+	names := []string{}
+	seen := make(map[string]bool)
+	if prog.KeepOrder {
+		for _, name := range prog.Order {
+			if _, ok := prog.Map[name]; ok && !seen[name] {
+				names = append(names, name)
+				seen[name] = true
+			}
+		}
+	}
+	rest := []string{}
+	for name := range prog.Map {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(names, rest...)
+}
+
+// Compile the subcommands into a single command. Commands added to the
+// program after compiling are dispatched to as well. Unless the program has a
+// command named `help`, `help [command]` prints the help of the program or of
+// the given command.
 func (prog Program) Compile() Command {
+	p := &prog
+	if prog.shared != nil {
+		p = prog.shared.prog
+	}
 	return func(ctx *Context) error {
-		if len(ctx.Args) == 0 {
-			return fmt.Errorf("%s expected a command.\n\n%s", ctx.Name, ListCommands(prog))
+		prog := p.snapshot()
+		if ctx.info != nil {
+			return ctx.inspectProgram(prog)
+		}
+		if prog.Chain != "" {
+			if chain := splitChain(ctx.Args, prog.Chain); len(chain) > 1 {
+				return prog.dispatchChain(ctx, chain)
+			}
+		}
+		return prog.dispatch(ctx, ctx.Args)
+	}
+}
+
+// CompileClone compiles a clone of the program, so that the command is not
+// affected by later modifications of the program, unlike one created with
+// Compile.
+func (prog *Program) CompileClone() Command {
+	return prog.Clone().Compile()
+}
+
+// splitChain splits the arguments on the separator.
+func splitChain(args []string, sep string) [][]string {
+	chain := [][]string{}
+	start := 0
+	for i, arg := range args {
+		if arg == sep {
+			chain = append(chain, args[start:i])
+			start = i + 1
+		}
+	}
+	return append(chain, args[start:])
+}
+
+// dispatchChain dispatches each of the argument lists in order, stopping at
+// the first error. Only the last command is completed when completing.
+func (prog Program) dispatchChain(ctx *Context, chain [][]string) error {
+	if ctx.completing {
+		return prog.dispatch(ctx, chain[len(chain)-1])
+	}
+	for _, args := range chain {
+		if err := prog.dispatch(ctx, args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dispatch the arguments to the command named by the first argument.
+func (prog Program) dispatch(ctx *Context, args []string) error {
+	if len(args) == 0 {
+		return usageError(fmt.Errorf(msg("%s expected a command.")+"\n\n%s", ctx.Name, ListCommands(prog)))
+	}
+	ctx.setDefaults()
+	if !ctx.completing || len(args) > 1 {
+		args = prog.expandAlias(args)
+	}
+	head, tail := shift(args)
+	if ctx.completing && len(tail) == 0 {
+		names := make([]string, 0, len(prog.Map)+len(prog.Aliases))
+		for name := range prog.Map {
+			names = append(names, name)
+		}
+		for name := range prog.Aliases {
+			if _, ok := prog.Map[name]; !ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		return writeCandidates(ctx, head, names)
+	}
+	if strings.HasPrefix(head, "-h") || head == "--help" {
+		if JSONOutput {
+			info := &CommandInfo{Name: ctx.Name, Desc: ctx.Desc, Doc: ctx.doc}
+			inspectContext(info).inspectProgram(prog)
+			writeJSON(ctx.Stdout, info)
+			return ErrHelp
+		}
+		doc := prog.Doc
+		if doc.Long == "" && doc.Synopsis == "" && len(doc.Examples) == 0 {
+			doc = ctx.doc
+		}
+		fmt.Fprintf(ctx.Stdout, "%s: %s\n", ctx.Name, ctx.Desc)
+		if doc.Synopsis != "" {
+			fmt.Fprintf(ctx.Stdout, msg("usage: %s %s")+"\n", ctx.Name, doc.Synopsis)
+		}
+		if doc.Long != "" {
+			fmt.Fprintf(ctx.Stdout, "\n%s\n", wrap.Space(doc.Long, 79))
+		}
+		fmt.Fprintf(ctx.Stdout, "\n%s\n", ListCommands(prog))
+		if len(prog.Topics) > 0 {
+			fmt.Fprintf(ctx.Stdout, "\n%s\n", ListTopics(prog))
 		}
-		head, tail := shift(ctx.Args)
-		if strings.HasPrefix(head, "-h") || head == "--help" {
-			return fmt.Errorf("%s: %s\n\n%s", ctx.Name, ctx.Desc, ListCommands(prog))
+		if len(doc.Examples) > 0 {
+			fmt.Fprintf(ctx.Stdout, "\n%s\n", formatExamples(doc.Examples))
 		}
-		v, ok := prog.Map[head]
+		return ErrHelp
+	}
+	v, ok := prog.Map[head]
+	if name, renamed := prog.Renamed[head]; !ok && renamed {
+		if !ctx.completing {
+			fmt.Fprintf(ctx.Stderr, msg("`%s` is deprecated, use `%s` instead")+"\n", head, name)
+		}
+		head = name
+		v, ok = prog.Map[head]
+	}
+	if !ok && head == "help" {
+		return prog.help(ctx, tail)
+	}
+	if !ok {
+		if prog.External && !ctx.completing {
+			if path, err := exec.LookPath(externalName(ctx.Path(), head)); err == nil {
+				return runExternal(ctx, path, tail)
+			}
+		}
+		if !prog.Autocorrect || ctx.completing {
+			return usageError(fmt.Errorf(msg("unknown command name `%s`"), head))
+		}
+		corrected, ok := ctx.autocorrect(head, prog.names())
 		if !ok {
-			return fmt.Errorf("unknown command name `%s`", head)
+			return usageError(fmt.Errorf(msg("unknown command name `%s`"), head))
+		}
+		head, v = corrected, prog.Map[corrected]
+	}
+	name := fmt.Sprintf("%s %s", ctx.Name, head)
+	sub := ctx.child(name, v.Desc, tail)
+	sub.path, sub.doc = ctx.subPath(head), v.Doc
+	cmd := v.Cmd
+	for i := len(prog.Middlewares) - 1; i >= 0; i-- {
+		cmd = prog.Middlewares[i](cmd)
+	}
+	return observe(prog.Observers, sub, cmd)
+}
+
+// help implements the `help [command]` subcommand provided unless the program
+// has a command named `help`. It prints the help of the program, of the
+// command named by the arguments, which may be nested, or of a help topic.
+func (prog Program) help(ctx *Context, args []string) error {
+	if ctx.completing {
+		if len(args) == 1 {
+			return prog.dispatch(ctx, args)
 		}
-		name := fmt.Sprintf("%s %s", ctx.Name, head)
-		err := v.Cmd(&Context{name, v.Desc, tail})
-		return err
+		return errComplete
+	}
+	if len(args) == 1 {
+		if text, ok := prog.Topics[args[0]]; ok {
+			fmt.Fprintln(ctx.Stdout, strings.TrimRight(text, "\n"))
+			return ErrHelp
+		}
+	}
+	return prog.dispatch(ctx, append(append([]string(nil), args...), "--help"))
+}
+
+func externalName(path []string, head string) string {
+	path[0] = filepath.Base(path[0])
+	return strings.Join(append(path, head), "-")
+}
+
+func runExternal(ctx *Context, path string, args []string) error {
+	cmd := exec.Command(path, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = ctx.Stdin, ctx.Stdout, ctx.Stderr
+	err := cmd.Run()
+	if e, ok := err.(*exec.ExitError); ok {
+		return Exit(e.ExitCode(), nil)
 	}
+	return err
 }
 
-// Main is the main program.
+// Main is the main program. Tests adding commands to it should do so on a
+// Clone instead, so that they do not interfere with each other.
 var Main = NewProgram()
 
 // Add a command to the main program.
@@ -68,12 +478,51 @@ func Add(name, desc string, cmd Command) { Main.Add(name, desc, cmd) }
 // Compile the main program.
 func Compile() Command { return Main.Compile() }
 
-// Run the given command using os.Args.
-func Run(name, desc string, cmd Command) int {
-	ctx := &Context{name, desc, os.Args[1:]}
-	if err := cmd(ctx); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		return 1
+// Exec the given command with the context, reporting any error to the
+// standard error stream of the context and returning the exit status.
+// A first argument of CompleteCommand will make the command write the
+// completion candidates for the remaining arguments instead. A panic in the
+// command is recovered and reported as a PanicError with ExitPanic.
+func Exec(ctx *Context, cmd Command) int {
+	err := execute(ctx, cmd)
+	ctx.report(err)
+	return ExitCode(err)
+}
+
+func execute(ctx *Context, cmd Command) error {
+	ctx.setDefaults()
+	if len(ctx.Args) > 0 && ctx.Args[0] == CompleteCommand {
+		ctx.Args, ctx.completing = ctx.Args[1:], true
 	}
-	return 0
+	return ctx.run(cmd)
+}
+
+// RunArgs runs the given command with the arguments and returns the exit
+// status along with the error returned by the command instead of reporting
+// it. The command reads from os.Stdin and writes to os.Stdout while anything
+// it writes to its standard error stream is discarded. Use ExecArgs to
+// supply the streams as well.
+func RunArgs(name, desc string, args []string, cmd Command) (int, error) {
+	ctx := NewContext(name, desc, args)
+	ctx.Stderr = ioutil.Discard
+	return ExecArgs(ctx, cmd)
+}
+
+// ExecArgs executes the given command with the context like Exec but returns
+// the error returned by the command instead of reporting it.
+func ExecArgs(ctx *Context, cmd Command) (int, error) {
+	err := execute(ctx, cmd)
+	return ExitCode(err), err
+}
+
+// Run the given command using os.Args. The context.Context of the command is
+// canceled on SIGINT or SIGTERM, and the command is given GracePeriod to
+// return before Run returns regardless. The exit status is then 128 plus the
+// signal number, e.g. 130 for SIGINT, so the program should exit with the
+// status returned by Run right away.
+func Run(name, desc string, cmd Command) int {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	return execSignals(NewContext(name, desc, os.Args[1:]), cmd, signals)
 }
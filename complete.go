@@ -0,0 +1,225 @@
+package flags
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CompleteFunc returns the candidate values for an argument which is being
+// completed with the given prefix.
+type CompleteFunc func(prefix string) []string
+
+// CompleteCommand is the name of the hidden command invoked by completion
+// scripts. Its arguments are the words of the command line following the
+// program name, the last word being the one completed. The candidates are
+// written by Context.Parse, so commands should call it before doing any work.
+const CompleteCommand = "__complete"
+
+// errComplete is returned by commands which have written the completions.
+var errComplete = Exit(ExitSuccess, nil)
+
+// CompletionScript returns a completion script for the named program for
+// use with the given shell. The supported shells are `bash` and `zsh`.
+func CompletionScript(shell, name string) (string, error) {
+	fn := "_" + strings.NewReplacer("-", "_", ".", "_").Replace(name) + "_complete"
+	switch shell {
+	case "bash":
+		return fmt.Sprintf(bashCompletion, fn, name, CompleteCommand), nil
+	case "zsh":
+		return fmt.Sprintf(zshCompletion, fn, name, CompleteCommand), nil
+	default:
+		return "", fmt.Errorf("completion for shell `%s` is not supported", shell)
+	}
+}
+
+const bashCompletion = `%[1]s() {
+	local IFS=$'\n'
+	COMPREPLY=($(%[2]s %[3]s "${COMP_WORDS[@]:1:$COMP_CWORD}" 2>/dev/null))
+	if [[ ${#COMPREPLY[@]} -eq 1 && ${COMPREPLY[0]} == */ ]]; then
+		compopt -o nospace
+	fi
+}
+complete -o default -F %[1]s %[2]s
+`
+
+const zshCompletion = `#compdef %[2]s
+%[1]s() {
+	local -a candidates dirs others
+	candidates=("${(@f)$(%[2]s %[3]s "${(@)words[2,$CURRENT]}" 2>/dev/null)}")
+	if [[ -n "${candidates[1]}" ]]; then
+		dirs=("${(@M)candidates:#*/}")
+		others=("${(@)candidates:#*/}")
+		compadd -S '' -a dirs
+		compadd -a others
+	else
+		_files
+	fi
+}
+compdef %[1]s %[2]s
+`
+
+func writeCandidates(ctx *Context, prefix string, candidates []string) error {
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) {
+			fmt.Fprintln(ctx.Stdout, candidate)
+		}
+	}
+	return errComplete
+}
+
+func completeWith(f CompleteFunc, prefix string) []string {
+	if f == nil {
+		return nil
+	}
+	return f(prefix)
+}
+
+// CompleteFiles returns a function completing paths to the files with any of
+// the given extensions, e.g. `.json`, and to directories, which are suffixed
+// with a slash so that the completion may continue inside them. Extensions
+// are matched case insensitively and all files are matched if none is given.
+// Hidden files are only offered if the prefix names them.
+func CompleteFiles(exts ...string) CompleteFunc {
+	return func(prefix string) []string {
+		dir, base := filepath.Split(prefix)
+		path := dir
+		if path == "" {
+			path = "."
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil
+		}
+		candidates := []string{}
+		for _, entry := range entries {
+			name := entry.Name()
+			if !strings.HasPrefix(name, base) || (name[0] == '.' && !strings.HasPrefix(base, ".")) {
+				continue
+			}
+			switch {
+			case entry.IsDir():
+				candidates = append(candidates, dir+name+"/")
+			case hasExtension(name, exts):
+				candidates = append(candidates, dir+name)
+			}
+		}
+		return candidates
+	}
+}
+
+func hasExtension(name string, exts []string) bool {
+	if len(exts) == 0 {
+		return true
+	}
+	name = strings.ToLower(name)
+	for _, ext := range exts {
+		if strings.HasSuffix(name, strings.ToLower(ext)) {
+			return true
+		}
+	}
+	return false
+}
+
+// complete writes the completion candidates for the last argument of the
+// context to the standard output.
+func (ctx *Context) complete(pos *Positional, opt *Optional) error {
+	if pos == nil {
+		pos = newPositional()
+	}
+	if opt == nil {
+		opt = newOptional()
+	}
+	if len(ctx.Args) == 0 {
+		return errComplete
+	}
+	args, word := ctx.Args[:len(ctx.Args)-1], ctx.Args[len(ctx.Args)-1]
+
+	// Find out what the preceding arguments have consumed.
+	pending, index, terminated := "", 0, false
+	for _, arg := range args {
+		tok := lexArg(arg)
+		switch {
+		case terminated || pending != "":
+			if pending == "" {
+				index++
+			}
+			pending = ""
+		case tok.Kind == TerminatorToken:
+			terminated = true
+		case tok.Kind == LongToken:
+			if !tok.HasValue {
+				pending = opt.takesValue(tok.Name)
+			}
+		case tok.Kind == ShortToken || tok.Kind == NumberToken && (Parser{Opt: opt}).isShort(arg):
+			rr := []rune(tok.Name)
+			if opt.AttachedValues {
+				// Skip the leading boolean flags to see whether a value is
+				// attached to the first flag taking one.
+				for len(rr) > 1 && opt.takesValue(opt.Alias[rr[0]]) == "" {
+					rr = rr[1:]
+				}
+			}
+			name, ok := opt.Alias[rr[len(rr)-1]]
+			if ok && (len(rr) == 1 || !opt.AttachedValues) {
+				pending = opt.takesValue(name)
+			}
+		default:
+			index++
+		}
+	}
+
+	switch {
+	case pending != "":
+		return writeCandidates(ctx, word, completeWith(opt.Completions[pending], word))
+
+	case !terminated && strings.HasPrefix(word, "--") && strings.ContainsRune(word, '='):
+		i := strings.IndexByte(word, '=')
+		name, prefix := opt.takesValue(word[2:i]), word[i+1:]
+		if name == "" {
+			return errComplete
+		}
+		candidates := completeWith(opt.Completions[name], prefix)
+		for i, candidate := range candidates {
+			candidates[i] = fmt.Sprintf("--%s=%s", name, candidate)
+		}
+		return writeCandidates(ctx, word, candidates)
+
+	case !terminated && strings.HasPrefix(word, "-"):
+		names := []string{"--help"}
+		for long := range opt.Args {
+			names = append(names, "--"+long)
+		}
+		sort.Strings(names)
+		return writeCandidates(ctx, word, names)
+
+	default:
+		name := pos.nameAt(index)
+		return writeCandidates(ctx, word, completeWith(pos.Completions[name], word))
+	}
+}
+
+// takesValue returns the name of the flag if it takes a value argument.
+func (opt *Optional) takesValue(long string) string {
+	name, err := opt.Lookup(long)
+	if err != nil || isBoolFlag(opt.Args[name].Value) {
+		return ""
+	}
+	return name
+}
+
+// nameAt returns the name of the positional argument receiving the value at
+// the given index.
+func (pos *Positional) nameAt(index int) string {
+	for i, name := range pos.Order {
+		if _, ok := pos.Args[name].Value.(SliceValue); ok {
+			return name
+		}
+		if i == index {
+			return name
+		}
+	}
+	return ""
+}
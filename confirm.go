@@ -0,0 +1,123 @@
+package flags
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrNotConfirmed is returned by Context.Confirm if confirmation is required
+// but no prompt can be shown because the standard input is not a terminal.
+var ErrNotConfirmed = errors.New("confirmation required but the standard input is not a terminal")
+
+// ConfirmValue represents a switch which bypasses the confirmation prompts
+// of Context.Confirm, such as `--yes` or `--force`.
+type ConfirmValue bool
+
+// NewConfirmValue creates a new ConfirmValue.
+func NewConfirmValue(init bool) *ConfirmValue {
+	p := new(bool)
+	*p = init
+	return (*ConfirmValue)(p)
+}
+
+// Set will set attempt to convert the given string to a value.
+func (p *ConfirmValue) Set(s string) error {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf(msg("`%s` cannot be interpreted as %T"), s, v)
+	}
+	*p = ConfirmValue(v)
+	return nil
+}
+
+// String satisfies the fmt.Stringer interface.
+func (p ConfirmValue) String() string {
+	return strconv.FormatBool(bool(p))
+}
+
+// IsBoolFlag reports whether the value can be given as a switch.
+func (p ConfirmValue) IsBoolFlag() bool { return true }
+
+// Confirm adds a switch to the optional argument list which bypasses the
+// confirmation prompts of Context.Confirm when given.
+func (opt *Optional) Confirm(short rune, long string, usage string) *bool {
+	value := NewConfirmValue(false)
+	opt.Register(short, long, value, usage)
+	return (*bool)(value)
+}
+
+// Yes adds the standard `-y, --yes` switch which bypasses the confirmation
+// prompts of Context.Confirm.
+func (opt *Optional) Yes() *bool {
+	return opt.Confirm('y', "yes", "assume yes to all confirmation prompts")
+}
+
+type confirmFlag struct {
+	long  string
+	value *ConfirmValue
+}
+
+func (ctx *Context) collectConfirms(opt *Optional) {
+	ctx.confirms = nil
+	if opt == nil {
+		return
+	}
+	opt.VisitAll(func(long string, arg Argument) {
+		if v, ok := arg.Value.(*ConfirmValue); ok {
+			ctx.confirms = append(ctx.confirms, confirmFlag{long, v})
+		}
+	})
+}
+
+// Confirm asks the user to confirm the given message. The confirmation is
+// given without prompting if any of the switches added with Optional.Confirm
+// were given. Otherwise, the message is written to the standard error stream
+// and a line starting with `y` or `Y` is read from the standard input as a
+// confirmation. Confirm returns ErrNotConfirmed if the standard input is a
+// file other than a terminal.
+func (ctx *Context) Confirm(message string) (bool, error) {
+	ctx.setDefaults()
+	for _, c := range ctx.confirms {
+		if *c.value {
+			return true, nil
+		}
+	}
+	if f, ok := ctx.Stdin.(interface{ Fd() uintptr }); ok && !isTerminal(f.Fd()) {
+		if len(ctx.confirms) > 0 {
+			return false, fmt.Errorf(msg("%w: give --%s to proceed"), ErrNotConfirmed, ctx.confirms[0].long)
+		}
+		return false, ErrNotConfirmed
+	}
+	fmt.Fprintf(ctx.Stderr, msg("%s [y/N]: "), message)
+	line, err := readLine(ctx.Stdin)
+	if err != nil && line == "" {
+		if err == io.EOF {
+			return false, nil
+		}
+		return false, err
+	}
+	line = strings.TrimSpace(line)
+	return strings.HasPrefix(line, "y") || strings.HasPrefix(line, "Y"), nil
+}
+
+// readLine reads a single line one byte at a time so that no input beyond
+// the line is consumed.
+func readLine(r io.Reader) (string, error) {
+	builder := strings.Builder{}
+	p := make([]byte, 1)
+	for {
+		n, err := r.Read(p)
+		if n > 0 {
+			if p[0] == '\n' {
+				return builder.String(), nil
+			}
+			builder.WriteByte(p[0])
+		}
+		if err != nil {
+			return builder.String(), err
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package flags
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors describing the cause of a ConstraintError.
+var (
+	// ErrRequiredFlag indicates that a flag required by the flags given was
+	// not given.
+	ErrRequiredFlag = errors.New("required flag not given")
+
+	// ErrExclusiveFlags indicates that flags which exclude each other were
+	// given together.
+	ErrExclusiveFlags = errors.New("exclusive flags given together")
+)
+
+// ConstraintError represents a violation of a constraint between flags.
+type ConstraintError struct {
+	// Flags are the names of the flags involved, e.g. `--tls-key`.
+	Flags []string
+
+	// Err is the cause of the error.
+	Err error
+
+	message string
+}
+
+// Error satisfies the error interface.
+func (e *ConstraintError) Error() string { return e.message }
+
+// Unwrap returns the cause of the error.
+func (e *ConstraintError) Unwrap() error { return e.Err }
+
+// constraint checks the flags given, reporting whether a flag was given and
+// naming flags through the functions so that the long names may be mapped.
+type constraint func(given func(long string) bool, flag func(long string) string) error
+
+func flagNames(longs []string, flag func(string) string) []string {
+	names := make([]string, len(longs))
+	for i, long := range longs {
+		names[i] = flag(long)
+	}
+	return names
+}
+
+func (opt *Optional) constrain(longs []string, c constraint) {
+	for _, long := range longs {
+		if !opt.Args.Has(long) {
+			panic(fmt.Errorf("optional argument with long name `%s` does not exist", long))
+		}
+	}
+	opt.constraints = append(opt.constraints, c)
+}
+
+// Requires makes the flags with the given long names required if the flag
+// with the long name is given, e.g. `--tls-key` if `--tls-cert` is given.
+func (opt *Optional) Requires(long string, required ...string) {
+	opt.constrain(append([]string{long}, required...), func(given func(string) bool, flag func(string) string) error {
+		if !given(long) {
+			return nil
+		}
+		missing := []string{}
+		for _, r := range required {
+			if !given(r) {
+				missing = append(missing, r)
+			}
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+		names := flagNames(missing, flag)
+		message := fmt.Sprintf(msg("flag `%s` requires `%s`"), flag(long), strings.Join(names, "`, `"))
+		return &ConstraintError{names, ErrRequiredFlag, message}
+	})
+}
+
+// RequireOne makes at least one of the flags with the given long names
+// required, e.g. one of `--input` or `--stdin`.
+func (opt *Optional) RequireOne(longs ...string) {
+	opt.constrain(longs, func(given func(string) bool, flag func(string) string) error {
+		for _, long := range longs {
+			if given(long) {
+				return nil
+			}
+		}
+		names := flagNames(longs, flag)
+		message := fmt.Sprintf(msg("one of `%s` is required"), strings.Join(names, "`, `"))
+		return &ConstraintError{names, ErrRequiredFlag, message}
+	})
+}
+
+// Exclusive makes the flags with the given long names exclude each other so
+// that at most one of them may be given.
+func (opt *Optional) Exclusive(longs ...string) {
+	opt.constrain(longs, func(given func(string) bool, flag func(string) string) error {
+		both := []string{}
+		for _, long := range longs {
+			if given(long) {
+				both = append(both, long)
+			}
+		}
+		if len(both) < 2 {
+			return nil
+		}
+		names := flagNames(both, flag)
+		message := fmt.Sprintf(msg("flags `%s` cannot be given together"), strings.Join(names, "`, `"))
+		return &ConstraintError{names, ErrExclusiveFlags, message}
+	})
+}
+
+// checkConstraints returns the violations of the constraints between the
+// flags given in the last parse.
+func (opt *Optional) checkConstraints() []error {
+	given := func(long string) bool { return opt.changed[long] }
+	flag := func(long string) string { return "--" + long }
+	errs := []error{}
+	for _, c := range opt.constraints {
+		if err := c(given, flag); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
@@ -1,29 +1,313 @@
 package flags
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"time"
 
 	wrap "gopkg.in/ktnyt/wrap.v1"
 )
 
-// Context carries the name, description, and arguments given to a command.
+// Context carries the name, description, arguments, and standard streams
+// given to a command. Commands should read from and write to the streams of
+// the context instead of os.Stdin, os.Stdout, and os.Stderr so that they may
+// be tested and embedded in other programs. A stream left as nil will
+// default to the corresponding stream of the process when executed.
 type Context struct {
 	Name string
 	Desc string
 	Args []string
+
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Rest holds the arguments left unparsed by Parse if the positional
+	// argument definitions have Passthrough set.
+	Rest []string
+
+	cleanups   []func() error
+	completing bool
+	path       []string
+	confirms   []confirmFlag
+	doc        Doc
+	ctx        context.Context
+	info       *CommandInfo
+	timeout    *time.Duration
+	dryRun     *bool
+	profile    bool
+	pos        *Positional
+	opt        *Optional
+	output     *OutputValue
+}
+
+// NewContext creates a new Context using the standard streams of the process.
+func NewContext(name, desc string, args []string) *Context {
+	return &Context{
+		Name:   name,
+		Desc:   desc,
+		Args:   args,
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Path returns the names of the commands leading to the command, starting
+// with the name of the root program, e.g. `["git", "remote", "add"]` for a
+// command whose Name is `git remote add`. It is the Name of the context alone
+// for commands which were not dispatched to by a Program.
+func (ctx *Context) Path() []string {
+	if ctx.path == nil {
+		return []string{ctx.Name}
+	}
+	return append([]string(nil), ctx.path...)
+}
+
+// Root returns the name of the root program.
+func (ctx *Context) Root() string {
+	return ctx.Path()[0]
+}
+
+// subPath returns the path of the subcommand with the given name.
+func (ctx *Context) subPath(name string) []string {
+	return append(ctx.Path(), name)
+}
+
+// child creates the context of a command run on behalf of the command with
+// the given name, description, and arguments. It shares the streams and the
+// settings of the context.
+func (ctx *Context) child(name, desc string, args []string) *Context {
+	return &Context{
+		Name:   name,
+		Desc:   desc,
+		Args:   args,
+		Stdin:  ctx.Stdin,
+		Stdout: ctx.Stdout,
+		Stderr: ctx.Stderr,
+
+		completing: ctx.completing,
+		path:       ctx.path,
+		ctx:        ctx.ctx,
+		timeout:    ctx.timeout,
+		dryRun:     ctx.dryRun,
+		profile:    ctx.profile,
+	}
+}
+
+// Context returns the context.Context of the command, which carries the
+// values attached with SetValue. It defaults to context.Background.
+func (ctx *Context) Context() context.Context {
+	if ctx.ctx == nil {
+		return context.Background()
+	}
+	return ctx.ctx
+}
+
+// WithContext replaces the context.Context of the command.
+func (ctx *Context) WithContext(c context.Context) {
+	ctx.ctx = c
+}
+
+// SetValue attaches a value to the context under the given key, making it
+// available to the command and any subcommands it dispatches to. Keys follow
+// the conventions of context.WithValue.
+func (ctx *Context) SetValue(key, value interface{}) {
+	ctx.ctx = context.WithValue(ctx.Context(), key, value)
+}
+
+// Value returns the value attached to the context under the given key, or nil
+// if there is none.
+func (ctx *Context) Value(key interface{}) interface{} {
+	return ctx.Context().Value(key)
+}
+
+// Defer registers a function to be called after the command returns.
+// Deferred functions are called in the reverse order of registration, even
+// if the command panics.
+func (ctx *Context) Defer(f func() error) {
+	ctx.cleanups = append(ctx.cleanups, f)
+}
+
+func (ctx *Context) run(cmd Command) (err error) {
+	defer func() {
+		for len(ctx.cleanups) > 0 {
+			i := len(ctx.cleanups) - 1
+			f := ctx.cleanups[i]
+			ctx.cleanups = ctx.cleanups[:i]
+			if cerr := f(); err == nil {
+				err = cerr
+			}
+		}
+	}()
+	defer func() {
+		if v := recover(); v != nil {
+			if v == errInspect {
+				err = errInspect
+				return
+			}
+			err = ctx.recoverPanic(v)
+		}
+	}()
+	return cmd(ctx)
+}
+
+// report the error to the standard error stream unless there is nothing to
+// report.
+func (ctx *Context) report(err error) {
+	var e *ExitError
+	switch {
+	case err == nil, errors.Is(err, ErrHelp):
+	case errors.As(err, &e) && e.Err == nil:
+	case JSONOutput:
+		writeJSON(ctx.Stderr, NewErrorInfo(err))
+	default:
+		fmt.Fprintln(ctx.Stderr, err)
+	}
+}
+
+func (ctx *Context) setDefaults() {
+	if ctx.Stdin == nil {
+		ctx.Stdin = os.Stdin
+	}
+	if ctx.Stdout == nil {
+		ctx.Stdout = os.Stdout
+	}
+	if ctx.Stderr == nil {
+		ctx.Stderr = os.Stderr
+	}
+}
+
+// Piped reports whether the standard input of the context is not a
+// terminal, i.e. whether input is being piped or redirected to the command.
+func (ctx *Context) Piped() bool {
+	ctx.setDefaults()
+	if f, ok := ctx.Stdin.(interface{ Fd() uintptr }); ok {
+		return !isTerminal(f.Fd())
+	}
+	return true
+}
+
+// Arg returns the string representation of the positional argument with the
+// given name as parsed by the last call to Parse, or an empty string if there
+// is no such argument.
+func (ctx *Context) Arg(name string) string {
+	if ctx.pos == nil || !ctx.pos.Args.Has(name) {
+		return ""
+	}
+	return ctx.pos.Get(name).String()
 }
 
 // Parse the context arguments using the positional and optional argument
-// definitions given.
+// definitions given. Files opened while parsing are closed after the command
+// returns.
 func (ctx *Context) Parse(pos *Positional, opt *Optional) error {
+	var timeout *Var[time.Duration]
+	if ctx.timeout != nil {
+		if opt == nil {
+			opt = newOptional()
+		}
+		timeout = ctx.addTimeout(opt)
+	}
+	var dryRun *Var[bool]
+	if ctx.dryRun != nil {
+		if opt == nil {
+			opt = newOptional()
+		}
+		dryRun = ctx.addDryRun(opt)
+	}
+	var profile *Var[string]
+	if ctx.profile {
+		if opt == nil {
+			opt = newOptional()
+		}
+		profile = ctx.addProfile(opt)
+	}
+	if ctx.completing {
+		ctx.setDefaults()
+		return ctx.complete(pos, opt)
+	}
+	if ctx.info != nil {
+		ctx.inspect(pos, opt)
+		// Stop the command being inspected even if it ignores the error.
+		panic(errInspect)
+	}
+	ctx.pos, ctx.opt = pos, opt
+	ctx.deferClose(pos, opt)
+	ctx.collectConfirms(opt)
+	ctx.collectOutput(opt)
 	parser := Parser{pos, opt}
 	if err := parser.Parse(ctx.Args); err != nil {
 		name := ctx.Name
-		usage := wrap.Space(Usage(pos, opt), 72-len(name))
-		if err == errHelp {
-			return fmt.Errorf("usage: %s %s\n%s", ctx.Name, usage, Help(pos, opt))
+		usage := Usage(pos, opt)
+		if CompactUsage {
+			usage = ShortUsage(pos, opt)
+		}
+		usage = wrap.Space(usage, 72-len(name))
+		if ctx.doc.Synopsis != "" {
+			usage = ctx.doc.Synopsis
+		}
+		if err == ErrHelp {
+			ctx.setDefaults()
+			if JSONOutput {
+				ctx.writeHelpJSON(pos, opt)
+				return ErrHelp
+			}
+			fmt.Fprintf(ctx.Stdout, msg("usage: %s %s")+"\n", ctx.Name, usage)
+			if ctx.doc.Long != "" {
+				fmt.Fprintf(ctx.Stdout, "\n%s\n", wrap.Space(ctx.doc.Long, 79))
+			}
+			fmt.Fprintf(ctx.Stdout, "%s\n", Help(pos, opt))
+			if len(ctx.doc.Examples) > 0 {
+				fmt.Fprintf(ctx.Stdout, "\n%s\n", formatExamples(ctx.doc.Examples))
+			}
+			return ErrHelp
+		}
+		return usageError(&parseUsageError{err, fmt.Sprintf(msg("usage: %s %s"), ctx.Name, usage)})
+	}
+	if pos != nil {
+		ctx.Rest = pos.Rest()
+	}
+	ctx.setDefaults()
+	ctx.reportSeeds(pos, opt)
+	if timeout != nil {
+		ctx.applyTimeout(timeout.Get())
+	}
+	if dryRun != nil {
+		ctx.dryRun = dryRun.Ptr()
+	}
+	if profile != nil {
+		if err := ctx.startProfile(profile.Get()); err != nil {
+			return err
 		}
-		return fmt.Errorf("%v\nusage: %s %s", err, ctx.Name, usage)
 	}
 	return nil
 }
+
+func (ctx *Context) deferClose(pos *Positional, opt *Optional) {
+	args := []Argument{}
+	if pos != nil {
+		for _, name := range pos.Order {
+			args = append(args, pos.Args[name])
+		}
+		if pos.In != nil {
+			args = append(args, *pos.In)
+		}
+		if pos.Out != nil {
+			args = append(args, *pos.Out)
+		}
+	}
+	if opt != nil {
+		for _, arg := range opt.Args {
+			args = append(args, arg)
+		}
+	}
+	for _, arg := range args {
+		if c, ok := arg.Value.(io.Closer); ok {
+			ctx.Defer(c.Close)
+		}
+	}
+}
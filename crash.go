@@ -0,0 +1,103 @@
+package flags
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+)
+
+// CrashReport describes a panic recovered from a command.
+type CrashReport struct {
+	// Command is the full name of the command which panicked.
+	Command string `json:"command"`
+
+	// Args are the arguments given to the command with the values of
+	// secret flags masked.
+	Args []string `json:"args"`
+
+	// Panic is the value the command panicked with.
+	Panic string `json:"panic"`
+
+	// Stack is the stack trace of the goroutine at the time of the panic.
+	Stack string `json:"stack"`
+}
+
+// CrashHook is called with the report of each panic recovered from a
+// command if not nil, for example to upload the report.
+var CrashHook func(report CrashReport)
+
+// PanicError is the error returned in place of a panic recovered from a
+// command. Commands which panic exit with ExitPanic.
+type PanicError struct {
+	Report CrashReport
+}
+
+// Error satisfies the error interface.
+func (e *PanicError) Error() string {
+	r := e.Report
+	return fmt.Sprintf(
+		msg("panic: %s")+"\n"+msg("command: %s")+"\n"+msg("args: %s")+"\n\n%s",
+		r.Panic, r.Command, strings.Join(r.Args, " "), strings.TrimRight(r.Stack, "\n"),
+	)
+}
+
+// recoverPanic converts the recovered value into an error carrying the crash
+// report.
+func (ctx *Context) recoverPanic(v interface{}) error {
+	report := CrashReport{
+		Command: ctx.Name,
+		Args:    maskArgs(ctx.Args, ctx.opt),
+		Panic:   fmt.Sprint(v),
+		Stack:   string(debug.Stack()),
+	}
+	if CrashHook != nil {
+		CrashHook(report)
+	}
+	return Exit(ExitPanic, &PanicError{report})
+}
+
+// maskArgs returns a copy of the arguments with the values of secret flags
+// replaced by Masked.
+func maskArgs(args []string, opt *Optional) []string {
+	masked := append([]string(nil), args...)
+	if opt == nil || len(opt.Secrets) == 0 {
+		return masked
+	}
+	secret := func(long string) bool {
+		name, err := opt.Lookup(long)
+		return err == nil && opt.Secrets[name]
+	}
+	for i := 0; i < len(masked); i++ {
+		tok := lexArg(masked[i])
+		if tok.Kind == TerminatorToken {
+			break
+		}
+		switch tok.Kind {
+		case LongToken:
+			switch {
+			case !secret(tok.Name):
+			case tok.HasValue:
+				masked[i] = "--" + tok.Name + "=" + Masked
+			case i+1 < len(masked):
+				i++
+				masked[i] = Masked
+			}
+		case ShortToken, NumberToken:
+			runes := []rune(tok.Name)
+			for j, r := range runes {
+				long, ok := opt.Alias[r]
+				if !ok || !opt.Secrets[long] {
+					continue
+				}
+				if j+1 < len(runes) {
+					masked[i] = "-" + string(runes[:j+1]) + Masked
+				} else if i+1 < len(masked) {
+					i++
+					masked[i] = Masked
+				}
+				break
+			}
+		}
+	}
+	return masked
+}
@@ -0,0 +1,246 @@
+package flags
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression.
+type Schedule struct {
+	expr string
+
+	// Each field is a bit set of the allowed values.
+	second, minute, hour, dom, month, dow uint64
+
+	// Whether the day fields were given as `*`, in which case the other day
+	// field alone restricts the days.
+	anyDOM, anyDOW bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var cronFields = []cronField{
+	{"second", 0, 59, nil},
+	{"minute", 0, 59, nil},
+	{"hour", 0, 23, nil},
+	{"day of month", 1, 31, nil},
+	{"month", 1, 12, []string{"", "jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{"day of week", 0, 7, []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a cron expression of five fields (minute, hour, day of
+// month, month, and day of week) or six fields with a leading second field.
+// Fields may be `*`, numbers, ranges such as `1-5`, lists such as `1,15`, and
+// steps such as `*/10`. Months and days of week may be given by their three
+// letter English names, and the macros such as `@daily` are accepted. As in
+// cron, a day matches if either day field matches unless one of them is `*`.
+func ParseCron(s string) (*Schedule, error) {
+	expr := strings.TrimSpace(s)
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf(msg("`%s` does not have 5 or 6 fields"), s)
+	}
+	sched := &Schedule{expr: s}
+	sets := []*uint64{&sched.second, &sched.minute, &sched.hour, &sched.dom, &sched.month, &sched.dow}
+	for i, field := range fields {
+		set, err := cronFields[i].parse(field)
+		if err != nil {
+			return nil, fmt.Errorf(msg("`%s` has an invalid %s field: %v"), s, cronFields[i].name, err)
+		}
+		*sets[i] = set
+	}
+	// Sunday may be given as 0 or 7.
+	if sched.dow&(1<<7) != 0 {
+		sched.dow |= 1
+	}
+	sched.anyDOM, sched.anyDOW = fields[3] == "*", fields[5] == "*"
+	return sched, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	for i, name := range f.names {
+		if name != "" && strings.EqualFold(s, name) {
+			return i, nil
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf(msg("`%s` is not a number from %d to %d"), s, f.min, f.max)
+	}
+	return n, nil
+}
+
+func (f cronField) parse(s string) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(s, ",") {
+		expr, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf(msg("`%s` is not a valid step"), part[i+1:])
+			}
+			expr, step = part[:i], n
+		}
+		lo, hi := f.min, f.max
+		switch lower, upper, isRange := strings.Cut(expr, "-"); {
+		case expr == "*":
+		case isRange:
+			var err error
+			if lo, err = f.value(lower); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(upper); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf(msg("`%s` is a descending range"), expr)
+			}
+		default:
+			n, err := f.value(expr)
+			if err != nil {
+				return 0, err
+			}
+			lo = n
+			if step == 1 {
+				hi = n
+			}
+		}
+		for n := lo; n <= hi; n += step {
+			set |= 1 << uint(n)
+		}
+	}
+	return set, nil
+}
+
+// String returns the expression the schedule was parsed from.
+func (sched *Schedule) String() string { return sched.expr }
+
+func (sched *Schedule) matchDay(t time.Time) bool {
+	dom := sched.dom&(1<<uint(t.Day())) != 0
+	dow := sched.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case sched.anyDOM && sched.anyDOW:
+		return true
+	case sched.anyDOM:
+		return dow
+	case sched.anyDOW:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// Next returns the first time matching the schedule after the given time, or
+// the zero time if there is none within five years.
+func (sched *Schedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Second).Add(time.Second)
+	limit := after.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case sched.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !sched.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case sched.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case sched.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Truncate(time.Minute).Add(time.Minute)
+		case sched.second&(1<<uint(t.Second())) == 0:
+			t = t.Add(time.Second)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// CronValue represents a cron expression argument value.
+type CronValue struct {
+	p **Schedule
+}
+
+// NewCronValue creates a new CronValue. It panics if the initial expression
+// is not empty and not valid.
+func NewCronValue(init string) *CronValue {
+	p := new(*Schedule)
+	if init != "" {
+		sched, err := ParseCron(init)
+		if err != nil {
+			panic(err)
+		}
+		*p = sched
+	}
+	return &CronValue{p}
+}
+
+func (v *CronValue) state() interface{} { return v.p }
+
+// Set will set attempt to convert the given string to a value.
+func (v *CronValue) Set(s string) error {
+	sched, err := ParseCron(s)
+	if err != nil {
+		return err
+	}
+	*v.p = sched
+	return nil
+}
+
+// Get returns the schedule, or nil if no expression was given.
+func (v *CronValue) Get() *Schedule { return *v.p }
+
+// Next returns the next scheduled time after the current time, or the zero
+// time if no expression was given.
+func (v *CronValue) Next() time.Time {
+	if *v.p == nil {
+		return time.Time{}
+	}
+	return (*v.p).Next(time.Now())
+}
+
+// Type returns the name of the value type.
+func (v *CronValue) Type() string { return "cron" }
+
+// String satisfies the fmt.Stringer interface.
+func (v *CronValue) String() string {
+	if *v.p == nil {
+		return ""
+	}
+	return (*v.p).String()
+}
+
+// Cron adds a cron expression flag to the optional argument list.
+func (opt *Optional) Cron(short rune, long string, init string, usage string) *CronValue {
+	value := NewCronValue(init)
+	opt.Register(short, long, value, usage)
+	return value
+}
+
+// Cron adds a cron expression to the positional argument list.
+func (pos *Positional) Cron(name, usage string) *CronValue {
+	value := NewCronValue("")
+	pos.Register(name, value, usage)
+	return value
+}
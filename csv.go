@@ -0,0 +1,147 @@
+package flags
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+)
+
+// CSVValue represents a file argument value for opening whose content is
+// read as CSV records. The file is only read when the records are requested.
+type CSVValue struct {
+	*OpenValue
+
+	// Comma is the field delimiter, a comma by default.
+	Comma rune
+
+	// Comment, if not zero, is the character starting comment lines.
+	Comment rune
+
+	// HasHeader makes the first record the header, which is returned by
+	// Header instead of as a record.
+	HasHeader bool
+
+	header  []string
+	records [][]string
+	read    bool
+}
+
+// NewCSVValue creates a new CSVValue.
+func NewCSVValue(init *os.File) *CSVValue {
+	return &CSVValue{OpenValue: NewOpenValue(init), Comma: ','}
+}
+
+// Set will set attempt to convert the given string to a value.
+func (v *CSVValue) Set(s string) error {
+	v.header, v.records, v.read = nil, nil, false
+	return v.OpenValue.Set(s)
+}
+
+// reader returns a csv.Reader for the file, consuming the header if any.
+func (v *CSVValue) reader() (*csv.Reader, error) {
+	f, err := v.OpenValue.Reader()
+	if err != nil {
+		return nil, err
+	}
+	r := csv.NewReader(f)
+	r.Comma, r.Comment = v.Comma, v.Comment
+	if v.HasHeader {
+		header, err := r.Read()
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		v.header = header
+	}
+	return r, nil
+}
+
+// Records reads all of the records of the file. The records are kept so
+// that later calls return them again.
+func (v *CSVValue) Records() ([][]string, error) {
+	if v.read {
+		return v.records, nil
+	}
+	r, err := v.reader()
+	if err != nil {
+		return nil, err
+	}
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	v.records, v.read = records, true
+	return records, nil
+}
+
+// CSVRecord is a record sent by CSVValue.Stream, or the error which stopped
+// the stream.
+type CSVRecord struct {
+	Fields []string
+	Err    error
+}
+
+// Stream sends the records of the file to the returned channel one by one
+// without keeping them, for files too large to read at once. The channel is
+// closed after the last record or after a record carrying an error. The
+// channel must be drained for the file to be read to the end.
+func (v *CSVValue) Stream() <-chan CSVRecord {
+	ch := make(chan CSVRecord)
+	r, err := v.reader()
+	go func() {
+		defer close(ch)
+		if err != nil {
+			ch <- CSVRecord{Err: err}
+			return
+		}
+		r.ReuseRecord = false
+		for {
+			fields, err := r.Read()
+			switch {
+			case err == io.EOF:
+				return
+			case err != nil:
+				ch <- CSVRecord{Err: err}
+				return
+			}
+			ch <- CSVRecord{Fields: fields}
+		}
+	}()
+	return ch
+}
+
+// Header returns the header of the file if HasHeader is set, once the records
+// have been read.
+func (v *CSVValue) Header() []string { return v.header }
+
+// Type returns the name of the value type.
+func (v *CSVValue) Type() string { return "csv" }
+
+// Close the file if it was opened by the value. The initial file is left
+// open.
+func (v *CSVValue) Close() error {
+	v.header, v.records, v.read = nil, nil, false
+	return v.OpenValue.Close()
+}
+
+// Reset closes the file if it was opened by the value and restores the
+// initial file.
+func (v *CSVValue) Reset() {
+	v.Close()
+	v.OpenValue.Reset()
+}
+
+// CSV adds a CSV file for reading to the optional argument list. The file
+// will be closed after the command returns if parsed with Context.Parse.
+func (opt *Optional) CSV(short rune, long string, init *os.File, usage string) *CSVValue {
+	value := NewCSVValue(init)
+	opt.Register(short, long, value, usage)
+	return value
+}
+
+// CSV adds a CSV file for reading to the positional argument list. The file
+// will be closed after the command returns if parsed with Context.Parse.
+func (pos *Positional) CSV(name, usage string) *CSVValue {
+	value := NewCSVValue(nil)
+	pos.Register(name, value, usage)
+	return value
+}
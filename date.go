@@ -0,0 +1,178 @@
+package flags
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DateLayout is the layout of a date argument.
+const DateLayout = "2006-01-02"
+
+// Date represents a civil date without a time component.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// ParseDate parses a date of the form `YYYY-MM-DD`.
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return Date{}, fmt.Errorf(msg("`%s` is not a date of the form YYYY-MM-DD"), s)
+	}
+	return DateOf(t), nil
+}
+
+// DateOf returns the date on which the time occurs in its location.
+func DateOf(t time.Time) Date {
+	y, m, d := t.Date()
+	return Date{y, m, d}
+}
+
+// In returns the time at the start of the date in the given location.
+func (d Date) In(loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+// IsZero reports whether the date is the zero value.
+func (d Date) IsZero() bool { return d == Date{} }
+
+// Compare returns -1, 0, or 1 if the date is before, equal to, or after the
+// other.
+func (d Date) Compare(other Date) int {
+	switch {
+	case d.Year != other.Year:
+		return compareUint(uint64(d.Year), uint64(other.Year))
+	case d.Month != other.Month:
+		return compareUint(uint64(d.Month), uint64(other.Month))
+	default:
+		return compareUint(uint64(d.Day), uint64(other.Day))
+	}
+}
+
+// Before reports whether the date is before the other.
+func (d Date) Before(other Date) bool { return d.Compare(other) < 0 }
+
+// After reports whether the date is after the other.
+func (d Date) After(other Date) bool { return d.Compare(other) > 0 }
+
+// String satisfies the fmt.Stringer interface.
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// DateRange represents an inclusive range of dates.
+type DateRange struct {
+	From Date
+	To   Date
+}
+
+// ParseDateRange parses a date range of the form `YYYY-MM-DD..YYYY-MM-DD`.
+// The start of the range must not be after the end.
+func ParseDateRange(s string) (DateRange, error) {
+	i := strings.Index(s, "..")
+	if i < 0 {
+		return DateRange{}, fmt.Errorf(msg("`%s` is not a date range of the form YYYY-MM-DD..YYYY-MM-DD"), s)
+	}
+	from, err := ParseDate(s[:i])
+	if err != nil {
+		return DateRange{}, err
+	}
+	to, err := ParseDate(s[i+2:])
+	if err != nil {
+		return DateRange{}, err
+	}
+	if from.After(to) {
+		return DateRange{}, fmt.Errorf(msg("`%s` starts after it ends"), s)
+	}
+	return DateRange{from, to}, nil
+}
+
+// Contains reports whether the date is within the range.
+func (r DateRange) Contains(d Date) bool {
+	return !d.Before(r.From) && !d.After(r.To)
+}
+
+// String satisfies the fmt.Stringer interface.
+func (r DateRange) String() string {
+	return r.From.String() + ".." + r.To.String()
+}
+
+// DateValue represents a date argument value.
+type DateValue Date
+
+// NewDateValue creates a new DateValue.
+func NewDateValue(init Date) *DateValue {
+	p := new(Date)
+	*p = init
+	return (*DateValue)(p)
+}
+
+// Set will set attempt to convert the given string to a value.
+func (p *DateValue) Set(s string) error {
+	d, err := ParseDate(s)
+	if err != nil {
+		return err
+	}
+	*p = DateValue(d)
+	return nil
+}
+
+// String satisfies the fmt.Stringer interface.
+func (p DateValue) String() string {
+	if Date(p).IsZero() {
+		return ""
+	}
+	return Date(p).String()
+}
+
+// DateRangeValue represents a date range argument value.
+type DateRangeValue DateRange
+
+// NewDateRangeValue creates a new DateRangeValue.
+func NewDateRangeValue(init DateRange) *DateRangeValue {
+	p := new(DateRange)
+	*p = init
+	return (*DateRangeValue)(p)
+}
+
+// Set will set attempt to convert the given string to a value.
+func (p *DateRangeValue) Set(s string) error {
+	r, err := ParseDateRange(s)
+	if err != nil {
+		return err
+	}
+	*p = DateRangeValue(r)
+	return nil
+}
+
+// String satisfies the fmt.Stringer interface.
+func (p DateRangeValue) String() string {
+	if (DateRange(p) == DateRange{}) {
+		return ""
+	}
+	return DateRange(p).String()
+}
+
+// Date adds a date flag to the optional argument list.
+func (opt *Optional) Date(short rune, long string, init Date, usage string) *Date {
+	value := NewDateValue(init)
+	opt.Register(short, long, value, usage)
+	return (*Date)(value)
+}
+
+// DateRange adds a date range flag to the optional argument list.
+func (opt *Optional) DateRange(short rune, long string, init DateRange, usage string) *DateRange {
+	value := NewDateRangeValue(init)
+	opt.Register(short, long, value, usage)
+	return (*DateRange)(value)
+}
+
+// Date adds a date to the positional argument list.
+func (pos *Positional) Date(name, usage string) *Date {
+	value := NewDateValue(Date{})
+	pos.Register(name, value, usage)
+	return (*Date)(value)
+}
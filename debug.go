@@ -0,0 +1,72 @@
+package flags
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+)
+
+// DebugEnv is the environment variable which enables the parse trace when
+// set to a true value such as `1`.
+const DebugEnv = "FLAGS_DEBUG"
+
+// Debug receives a trace of parsing at the debug level if not nil: each
+// argument consumed, the flag or positional argument it matched, the value
+// set, and the flags left at their defaults. Values of secret flags are
+// masked. It is set to a logger writing to os.Stderr if DebugEnv is set.
+var Debug = debugLogger(os.Getenv(DebugEnv))
+
+func debugLogger(env string) *slog.Logger {
+	if on, err := strconv.ParseBool(env); err != nil || !on {
+		return nil
+	}
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	return slog.New(slog.NewTextHandler(os.Stderr, opts))
+}
+
+// trace logs the parse event to Debug.
+func trace(msg string, args ...interface{}) {
+	if Debug != nil {
+		Debug.Log(context.Background(), slog.LevelDebug, msg, args...)
+	}
+}
+
+// mask returns the value of the flag or Masked if the flag is secret.
+func (opt *Optional) mask(long, value string) string {
+	if opt.Secrets[long] {
+		return Masked
+	}
+	return value
+}
+
+// maskTrace returns a copy of the arguments to be traced with the values of
+// secret flags masked, including those given in the Windows style.
+func (opt *Optional) maskTrace(args []string) []string {
+	if !opt.SlashFlags {
+		return maskArgs(args, opt)
+	}
+	translated := make([]string, len(args))
+	for i, arg := range args {
+		translated[i] = opt.translateSlash(arg)
+	}
+	masked := maskArgs(translated, opt)
+	for i := range masked {
+		if masked[i] == translated[i] {
+			masked[i] = args[i]
+		}
+	}
+	return masked
+}
+
+// traceDefaults logs the flags not given in the last parse with their values.
+func (opt *Optional) traceDefaults() {
+	if Debug == nil {
+		return
+	}
+	opt.VisitAll(func(long string, arg Argument) {
+		if !opt.changed[long] {
+			trace("default", "flag", "--"+long, "value", opt.mask(long, arg.Value.String()))
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package flags
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Decode copies the values of the optional arguments into the fields of the
+// struct pointed to by v. See DecodeArguments for how fields are matched.
+func (opt *Optional) Decode(v interface{}) error {
+	return DecodeArguments(opt.Args, v)
+}
+
+// Decode copies the values of the positional arguments into the fields of the
+// struct pointed to by v. See DecodeArguments for how fields are matched.
+func (pos *Positional) Decode(v interface{}) error {
+	return DecodeArguments(pos.Args, v)
+}
+
+// DecodeArguments copies the values of the arguments into the fields of the
+// struct pointed to by v. A field is matched to the argument named in its
+// `flag` tag, or otherwise to the argument whose name equals the field name
+// ignoring case and dashes. Fields tagged with `flag:"-"` and fields without
+// a matching argument are left untouched.
+func DecodeArguments(args Arguments, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("decode target must be a pointer to a struct")
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	names := make(map[string]string)
+	for name := range args {
+		names[normalizeFieldName(name)] = name
+	}
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		tag := field.Tag.Get("flag")
+		if tag == "-" {
+			continue
+		}
+		name, ok := tag, tag != ""
+		if !ok {
+			name, ok = names[normalizeFieldName(field.Name)]
+		}
+		arg, ok := args[name]
+		if !ok {
+			continue
+		}
+		if err := assignValue(rv.Field(i), arg.Value); err != nil {
+			return fmt.Errorf(msg("cannot decode argument `%s` into field `%s`: %v"), name, field.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func normalizeFieldName(name string) string {
+	name = strings.NewReplacer("-", "", "_", "").Replace(name)
+	return strings.ToLower(name)
+}
+
+// assignValue assigns the content of the value to the field.
+func assignValue(field reflect.Value, value Value) error {
+	ft := field.Type()
+	candidates := []reflect.Value{}
+
+	rv := reflect.ValueOf(unwrapValue(value))
+	if m := rv.MethodByName("Get"); m.IsValid() && m.Type().NumIn() == 0 && m.Type().NumOut() == 1 {
+		candidates = append(candidates, m.Call(nil)[0])
+	}
+	candidates = append(candidates, rv)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+		candidates = append(candidates, rv)
+	}
+	if rv.Kind() == reflect.Struct {
+		for i := 0; i < rv.NumField(); i++ {
+			if rv.Type().Field(i).Anonymous {
+				candidates = append(candidates, rv.Field(i))
+			}
+		}
+	}
+
+	for _, candidate := range candidates {
+		if candidate.Kind() == reflect.Interface {
+			candidate = candidate.Elem()
+		}
+		if !candidate.IsValid() || !candidate.CanInterface() {
+			continue
+		}
+		ct := candidate.Type()
+		switch {
+		case ct.AssignableTo(ft):
+			field.Set(candidate)
+			return nil
+		case ct.ConvertibleTo(ft) && ct.Kind() == ft.Kind():
+			field.Set(candidate.Convert(ft))
+			return nil
+		}
+	}
+
+	return fmt.Errorf("value of type %T is not compatible with %s", value, ft)
+}
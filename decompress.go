@@ -0,0 +1,171 @@
+package flags
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression represents a compression format.
+type Compression int
+
+const (
+	// Uncompressed represents data without compression.
+	Uncompressed Compression = iota
+
+	// Gzip represents the gzip format.
+	Gzip
+
+	// Bzip2 represents the bzip2 format.
+	Bzip2
+
+	// Zstd represents the Zstandard format.
+	Zstd
+)
+
+var compressionMagic = []struct {
+	magic []byte
+	c     Compression
+}{
+	{[]byte{0x1f, 0x8b}, Gzip},
+	{[]byte("BZh"), Bzip2},
+	{[]byte{0x28, 0xb5, 0x2f, 0xfd}, Zstd},
+}
+
+var compressionExt = map[string]Compression{
+	".gz":  Gzip,
+	".bz2": Bzip2,
+	".zst": Zstd,
+}
+
+// Decompress wraps the reader in a decompressor for the given compression.
+func Decompress(r io.Reader, c Compression) (io.ReadCloser, error) {
+	switch c {
+	case Gzip:
+		return gzip.NewReader(r)
+	case Bzip2:
+		return ioutil.NopCloser(bzip2.NewReader(r)), nil
+	case Zstd:
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	default:
+		return ioutil.NopCloser(r), nil
+	}
+}
+
+// DecompressValue represents a file argument value for opening which is
+// transparently decompressed. The compression is determined by the file
+// extension (.gz, .bz2, or .zst) or else by the leading magic bytes. The
+// value is a reader of the decompressed content.
+type DecompressValue struct {
+	// File is the file read from, whose fields such as Lazy may be set
+	// before parsing.
+	File *OpenValue
+
+	rc io.ReadCloser
+}
+
+// NewDecompressValue creates a new DecompressValue.
+func NewDecompressValue(init *os.File) *DecompressValue {
+	return &DecompressValue{NewOpenValue(init), nil}
+}
+
+// Set will set attempt to convert the given string to a value.
+func (v *DecompressValue) Set(s string) error {
+	if v.rc != nil {
+		v.rc.Close()
+		v.rc = nil
+	}
+	return v.File.Set(s)
+}
+
+// String satisfies the fmt.Stringer interface.
+func (v *DecompressValue) String() string {
+	return v.File.String()
+}
+
+// Fd returns the file descriptor of the file.
+func (v *DecompressValue) Fd() uintptr {
+	return v.File.Fd()
+}
+
+// Reader returns the decompressed content of the file, opening it if it is
+// pending.
+func (v *DecompressValue) Reader() (io.ReadCloser, error) {
+	if v.rc != nil {
+		return v.rc, nil
+	}
+	name := v.String()
+	f, err := v.File.Reader()
+	if err != nil {
+		return nil, err
+	}
+	c, ok := compressionExt[filepath.Ext(name)]
+	r := bufio.NewReader(f)
+	if !ok {
+		for _, m := range compressionMagic {
+			if p, _ := r.Peek(len(m.magic)); bytes.Equal(p, m.magic) {
+				c = m.c
+				break
+			}
+		}
+	}
+	if v.rc, err = Decompress(r, c); err != nil {
+		return nil, err
+	}
+	return v.rc, nil
+}
+
+// Read reads the decompressed content of the file.
+func (v *DecompressValue) Read(p []byte) (int, error) {
+	r, err := v.Reader()
+	if err != nil {
+		return 0, err
+	}
+	return r.Read(p)
+}
+
+// Close the decompressor and the file if it was opened by the value. The
+// initial file is left open.
+func (v *DecompressValue) Close() error {
+	if v.rc != nil {
+		v.rc.Close()
+		v.rc = nil
+	}
+	return v.File.Close()
+}
+
+// Reset closes the file if it was opened by the value and restores the
+// initial file.
+func (v *DecompressValue) Reset() {
+	v.Close()
+	v.File.Reset()
+}
+
+// Decompress adds a transparently decompressed file for reading to the
+// optional argument list. The file will be closed after the command returns
+// if parsed with Context.Parse.
+func (opt *Optional) Decompress(short rune, long string, init *os.File, usage string) *DecompressValue {
+	value := NewDecompressValue(init)
+	opt.Register(short, long, value, usage)
+	return value
+}
+
+// Decompress adds a transparently decompressed file for reading to the
+// positional argument list. The file will be closed after the command
+// returns if parsed with Context.Parse.
+func (pos *Positional) Decompress(name, usage string) *DecompressValue {
+	value := NewDecompressValue(nil)
+	pos.Register(name, value, usage)
+	return value
+}
@@ -0,0 +1,71 @@
+package flags
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DefaultFunc computes the default value of a flag at parse time, returning
+// its string representation.
+type DefaultFunc func() (string, error)
+
+// ComputedDefault is a default value computed at parse time.
+type ComputedDefault struct {
+	// Desc describes the default in place of its value in the help, e.g.
+	// `derived from INPUT`.
+	Desc string
+
+	// Func computes the default.
+	Func DefaultFunc
+}
+
+// DefaultFrom sets a function computing the default value of the flag with
+// the given long name, for defaults which cannot be known when the flag is
+// defined, such as an output path derived from an input path or the name of
+// the current user. If the flag is not given, the value is set from the
+// string returned by the function after all other arguments are parsed
+// without errors, so the function may read their values. Functions of
+// several flags are called in the order of their long names and should not
+// depend on each other. The help shows desc in place of the default value.
+//
+//	opt.DefaultFrom("output", "derived from INPUT", func() (string, error) {
+//		return strings.TrimSuffix(*input, ".md") + ".html", nil
+//	})
+func (opt *Optional) DefaultFrom(long, desc string, f DefaultFunc) {
+	if !opt.Args.Has(long) {
+		panic(fmt.Errorf("optional argument with long name `%s` does not exist", long))
+	}
+	if opt.ComputedDefaults == nil {
+		opt.ComputedDefaults = make(map[string]ComputedDefault)
+	}
+	opt.ComputedDefaults[long] = ComputedDefault{desc, f}
+}
+
+// computeDefaults sets the flags with computed defaults which were not given
+// in the last parse.
+func (parser Parser) computeDefaults() []error {
+	opt := parser.Opt
+	if len(opt.ComputedDefaults) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(opt.ComputedDefaults))
+	for long := range opt.ComputedDefaults {
+		if opt.Args.Has(long) && !opt.changed[long] {
+			names = append(names, long)
+		}
+	}
+	sort.Strings(names)
+	errs := []error{}
+	for _, long := range names {
+		s, err := opt.ComputedDefaults[long].Func()
+		if err != nil {
+			value := opt.Args[long].Value
+			errs = append(errs, &ParseError{"--" + long, "", TypeName(value), nil, err})
+			continue
+		}
+		if err := parser.setFlag(long, s); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
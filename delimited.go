@@ -0,0 +1,107 @@
+package flags
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Wrapper is implemented by values which wrap another value.
+type Wrapper interface {
+	Unwrap() Value
+}
+
+// unwrapValue returns the innermost value of wrapped values.
+func unwrapValue(v Value) Value {
+	for {
+		w, ok := v.(Wrapper)
+		if !ok {
+			return v
+		}
+		v = w.Unwrap()
+	}
+}
+
+// DelimitedValue wraps a slice value so that each argument is split on a
+// delimiter before being appended. A delimiter or backslash preceded by a
+// backslash is taken literally.
+type DelimitedValue struct {
+	SliceValue
+	Sep rune
+}
+
+// NewDelimitedValue creates a new DelimitedValue.
+func NewDelimitedValue(value SliceValue, sep rune) *DelimitedValue {
+	return &DelimitedValue{value, sep}
+}
+
+// Set will split the given string and append each of the elements.
+func (v *DelimitedValue) Set(s string) error {
+	for _, elem := range splitEscaped(s, v.Sep) {
+		if err := v.SliceValue.Set(elem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unwrap returns the wrapped value.
+func (v *DelimitedValue) Unwrap() Value { return v.SliceValue }
+
+// Type returns the name of the type of the wrapped value.
+func (v *DelimitedValue) Type() string { return TypeName(v.SliceValue) }
+
+func splitEscaped(s string, sep rune) []string {
+	elems := []string{}
+	elem := strings.Builder{}
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			if r != sep && r != '\\' {
+				elem.WriteRune('\\')
+			}
+			elem.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == sep:
+			elems = append(elems, elem.String())
+			elem.Reset()
+		default:
+			elem.WriteRune(r)
+		}
+	}
+	if escaped {
+		elem.WriteRune('\\')
+	}
+	return append(elems, elem.String())
+}
+
+// Delimit makes the slice flag with the given long name split each of its
+// arguments on the delimiter, so that `--tag a,b` is equivalent to
+// `--tag a --tag b`.
+func (opt *Optional) Delimit(long string, sep rune) {
+	arg, ok := opt.Args[long]
+	if !ok {
+		panic(fmt.Errorf("optional argument with long name `%s` does not exist", long))
+	}
+	value, ok := arg.Value.(SliceValue)
+	if !ok {
+		panic(fmt.Errorf("optional argument with long name `%s` is not a slice", long))
+	}
+	opt.Args[long] = Argument{NewDelimitedValue(value, sep), arg.Usage}
+}
+
+// IntSlice adds an integer slice flag to the optional argument list.
+func (opt *Optional) IntSlice(short rune, long string, init []int, usage string) *[]int {
+	value := Slice(init...)
+	opt.Register(short, long, value, usage)
+	return value.Ptr()
+}
+
+// FloatSlice adds a float slice flag to the optional argument list.
+func (opt *Optional) FloatSlice(short rune, long string, init []float64, usage string) *[]float64 {
+	value := Slice(init...)
+	opt.Register(short, long, value, usage)
+	return value.Ptr()
+}
@@ -0,0 +1,55 @@
+package flags
+
+import "fmt"
+
+// DryRunFlag is the long name of the flag added by DryRun.
+const DryRunFlag = "dry-run"
+
+// DryRun returns a middleware which adds a `--dry-run` switch to the
+// arguments parsed by the command and any subcommands it dispatches to.
+// Commands check the switch with Context.DryRun and perform destructive
+// actions through Context.Do so that they are skipped and reported instead
+// when the switch is given.
+//
+//	prog.Use(flags.DryRun())
+func DryRun() Middleware {
+	return func(cmd Command) Command {
+		return func(ctx *Context) error {
+			if ctx.dryRun == nil {
+				ctx.dryRun = new(bool)
+			}
+			return cmd(ctx)
+		}
+	}
+}
+
+// addDryRun registers the dry run switch unless the command defines a flag
+// with the same name itself.
+func (ctx *Context) addDryRun(opt *Optional) *Var[bool] {
+	if arg, ok := opt.Args[DryRunFlag]; ok {
+		value, _ := arg.Value.(*Var[bool])
+		return value
+	}
+	value := New(*ctx.dryRun)
+	opt.Register(0, DryRunFlag, value, "show what would be done without doing it")
+	return value
+}
+
+// DryRun reports whether the command was asked to show what it would do
+// without doing it. It is always false unless the DryRun middleware is used.
+func (ctx *Context) DryRun() bool {
+	return ctx.dryRun != nil && *ctx.dryRun
+}
+
+// Do calls f unless dry run is active, in which case the action describing
+// what f does is written to the standard error stream instead.
+//
+//	err := ctx.Do("remove "+path, func() error { return os.Remove(path) })
+func (ctx *Context) Do(action string, f func() error) error {
+	if ctx.DryRun() {
+		ctx.setDefaults()
+		fmt.Fprintf(ctx.Stderr, msg("dry run: would %s")+"\n", action)
+		return nil
+	}
+	return f()
+}
@@ -0,0 +1,136 @@
+package flags
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Masked is the placeholder shown in place of the values of secret flags.
+const Masked = "********"
+
+// Secret marks the flag with the given long name as secret so that its value
+// is masked in dumps and help messages.
+func (opt *Optional) Secret(long string) {
+	if !opt.Args.Has(long) {
+		panic(fmt.Errorf("optional argument with long name `%s` does not exist", long))
+	}
+	opt.Secrets[long] = true
+}
+
+// Config returns the resolved values of the optional arguments keyed by their
+// long names. Values of secret flags are masked.
+func (opt *Optional) Config() map[string]interface{} {
+	config := make(map[string]interface{})
+	for name, arg := range opt.Args {
+		if opt.Secrets[name] {
+			config[name] = Masked
+			continue
+		}
+		config[name] = plainValue(arg.Value)
+	}
+	return config
+}
+
+// Dump writes the resolved values of the optional arguments in the given
+// format, which may be either `json` or `yaml`.
+func (opt *Optional) Dump(w io.Writer, format string) error {
+	config := opt.Config()
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(config)
+	case "yaml":
+		_, err := io.WriteString(w, formatYAML(config))
+		return err
+	default:
+		return fmt.Errorf(msg("unknown dump format `%s`"), format)
+	}
+}
+
+var packagePath = reflect.TypeOf(Argument{}).PkgPath()
+
+// plainValue converts the value to a plain boolean, number, string, or slice
+// thereof if possible and to its string representation otherwise.
+func plainValue(value Value) interface{} {
+	rv := reflect.ValueOf(unwrapValue(value))
+	if m := rv.MethodByName("Get"); m.IsValid() && m.Type().NumIn() == 0 && m.Type().NumOut() == 1 {
+		rv = m.Call(nil)[0]
+	}
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if v, ok := plainOf(rv); ok {
+		return v
+	}
+	return value.String()
+}
+
+func plainOf(rv reflect.Value) (interface{}, bool) {
+	if path := rv.Type().PkgPath(); path != "" && path != packagePath {
+		return nil, false
+	}
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint(), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	case reflect.String:
+		return rv.String(), true
+	case reflect.Slice:
+		vv := make([]interface{}, rv.Len())
+		for i := range vv {
+			v, ok := plainOf(rv.Index(i))
+			if !ok {
+				return nil, false
+			}
+			vv[i] = v
+		}
+		return vv, true
+	default:
+		return nil, false
+	}
+}
+
+func formatYAML(config map[string]interface{}) string {
+	names := make([]string, 0, len(config))
+	for name := range config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	builder := strings.Builder{}
+	for _, name := range names {
+		builder.WriteString(name + ":")
+		switch v := config[name].(type) {
+		case []interface{}:
+			if len(v) == 0 {
+				builder.WriteString(" []\n")
+				continue
+			}
+			builder.WriteString("\n")
+			for _, x := range v {
+				builder.WriteString("  - " + formatYAMLScalar(x) + "\n")
+			}
+		default:
+			builder.WriteString(" " + formatYAMLScalar(v) + "\n")
+		}
+	}
+	return builder.String()
+}
+
+func formatYAMLScalar(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprint(v)
+}
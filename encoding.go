@@ -0,0 +1,230 @@
+package flags
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+)
+
+// readIndirect returns the contents of the file if the string is of the form
+// `@path` and the string itself otherwise.
+func readIndirect(s string) ([]byte, error) {
+	if strings.HasPrefix(s, "@") {
+		return ioutil.ReadFile(s[1:])
+	}
+	return []byte(s), nil
+}
+
+// JSONValue represents an argument value which is decoded as JSON into a
+// target. An argument of the form `@path` is decoded from the contents of the
+// file at path. Note that response file expansion, if enabled, takes
+// precedence over this syntax.
+type JSONValue struct {
+	target interface{}
+}
+
+// NewJSONValue creates a new JSONValue decoding into the target, which must
+// be a pointer.
+func NewJSONValue(target interface{}) *JSONValue {
+	return &JSONValue{target}
+}
+
+func (v *JSONValue) state() interface{} { return v.target }
+
+// Set will set attempt to decode the given string into the target.
+func (v *JSONValue) Set(s string) error {
+	p, err := readIndirect(s)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(p, v.target); err != nil {
+		return fmt.Errorf(msg("`%s` is not valid JSON: %v"), s, err)
+	}
+	return nil
+}
+
+// Get returns the target.
+func (v *JSONValue) Get() interface{} { return v.target }
+
+// String satisfies the fmt.Stringer interface.
+func (v *JSONValue) String() string {
+	p, err := json.Marshal(v.target)
+	if err != nil {
+		return ""
+	}
+	return string(p)
+}
+
+// JSON adds a flag decoded as JSON into the target to the optional argument
+// list. The current content of the target serves as the default.
+func (opt *Optional) JSON(short rune, long string, target interface{}, usage string) {
+	opt.Register(short, long, NewJSONValue(target), usage)
+}
+
+func checkSize(s string, p []byte, size int) error {
+	if size > 0 && len(p) != size {
+		return fmt.Errorf(msg("`%s` decodes to %d bytes, expected %d"), s, len(p), size)
+	}
+	return nil
+}
+
+// Base64Value represents a byte string argument value given in base64. Both
+// the standard and URL-safe alphabets are accepted with or without padding.
+type Base64Value struct {
+	p *[]byte
+
+	// Size is the exact number of bytes required. Any length is accepted if
+	// Size is zero.
+	Size int
+}
+
+// NewBase64Value creates a new Base64Value.
+func NewBase64Value(init []byte) *Base64Value {
+	p := new([]byte)
+	*p = init
+	return &Base64Value{p, 0}
+}
+
+func (v *Base64Value) state() interface{} { return v.p }
+
+var base64Encodings = []*base64.Encoding{
+	base64.StdEncoding,
+	base64.RawStdEncoding,
+	base64.URLEncoding,
+	base64.RawURLEncoding,
+}
+
+// Set will set attempt to convert the given string to a value.
+func (v *Base64Value) Set(s string) error {
+	for _, enc := range base64Encodings {
+		if p, err := enc.DecodeString(s); err == nil {
+			if err := checkSize(s, p, v.Size); err != nil {
+				return err
+			}
+			*v.p = p
+			return nil
+		}
+	}
+	return fmt.Errorf(msg("`%s` is not valid base64"), s)
+}
+
+// String satisfies the fmt.Stringer interface.
+func (v *Base64Value) String() string {
+	return base64.StdEncoding.EncodeToString(*v.p)
+}
+
+// HexValue represents a byte string argument value given in hexadecimal.
+type HexValue struct {
+	p *[]byte
+
+	// Size is the exact number of bytes required. Any length is accepted if
+	// Size is zero.
+	Size int
+}
+
+// NewHexValue creates a new HexValue.
+func NewHexValue(init []byte) *HexValue {
+	p := new([]byte)
+	*p = init
+	return &HexValue{p, 0}
+}
+
+func (v *HexValue) state() interface{} { return v.p }
+
+// Set will set attempt to convert the given string to a value. An optional
+// `0x` prefix is ignored.
+func (v *HexValue) Set(s string) error {
+	t := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	p, err := hex.DecodeString(t)
+	if err != nil {
+		return fmt.Errorf(msg("`%s` is not valid hexadecimal"), s)
+	}
+	if err := checkSize(s, p, v.Size); err != nil {
+		return err
+	}
+	*v.p = p
+	return nil
+}
+
+// String satisfies the fmt.Stringer interface.
+func (v *HexValue) String() string {
+	return hex.EncodeToString(*v.p)
+}
+
+// Base64 adds a base64 encoded byte string flag to the optional argument
+// list. A size of zero accepts any length.
+func (opt *Optional) Base64(short rune, long string, size int, usage string) *[]byte {
+	value := NewBase64Value(nil)
+	value.Size = size
+	opt.Register(short, long, value, usage)
+	return value.p
+}
+
+// Hex adds a hexadecimal byte string flag to the optional argument list. A
+// size of zero accepts any length.
+func (opt *Optional) Hex(short rune, long string, size int, usage string) *[]byte {
+	value := NewHexValue(nil)
+	value.Size = size
+	opt.Register(short, long, value, usage)
+	return value.p
+}
+
+// TextValue adapts any type implementing encoding.TextUnmarshaler, such as
+// netip.Addr or time.Time, into an argument value. The value is rendered
+// with MarshalText if the type also implements encoding.TextMarshaler.
+type TextValue struct {
+	u encoding.TextUnmarshaler
+}
+
+// NewTextValue creates a new TextValue decoding into the target. The current
+// content of the target serves as the default.
+func NewTextValue(target encoding.TextUnmarshaler) *TextValue {
+	return &TextValue{target}
+}
+
+func (v *TextValue) state() interface{} { return v.u }
+
+// Set will set attempt to convert the given string to a value.
+func (v *TextValue) Set(s string) error {
+	if err := v.u.UnmarshalText([]byte(s)); err != nil {
+		return fmt.Errorf(msg("`%s` cannot be interpreted as %T: %v"), s, v.u, err)
+	}
+	return nil
+}
+
+// Get returns the target.
+func (v *TextValue) Get() interface{} { return v.u }
+
+// Type returns the name of the target type.
+func (v *TextValue) Type() string {
+	return strings.ToLower(reflect.Indirect(reflect.ValueOf(v.u)).Type().Name())
+}
+
+// String satisfies the fmt.Stringer interface.
+func (v *TextValue) String() string {
+	if m, ok := v.u.(encoding.TextMarshaler); ok {
+		p, err := m.MarshalText()
+		if err != nil {
+			return ""
+		}
+		return string(p)
+	}
+	return fmt.Sprint(v.u)
+}
+
+// Text adds a flag decoded with the UnmarshalText method of the target to
+// the optional argument list.
+func (opt *Optional) Text(short rune, long string, target encoding.TextUnmarshaler, usage string) {
+	opt.Register(short, long, NewTextValue(target), usage)
+}
+
+// Text adds an argument decoded with the UnmarshalText method of the target
+// to the positional argument list.
+func (pos *Positional) Text(name string, target encoding.TextUnmarshaler, usage string) {
+	pos.Register(name, NewTextValue(target), usage)
+}
@@ -0,0 +1,107 @@
+package flags
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Errors describing the cause of a ParseError.
+var (
+	// ErrUnknownFlag indicates that the flag is not defined.
+	ErrUnknownFlag = errors.New("unknown flag")
+
+	// ErrAmbiguousFlag indicates that the abbreviated flag matches more
+	// than one definition.
+	ErrAmbiguousFlag = errors.New("ambiguous flag")
+
+	// ErrMissingValue indicates that a flag requiring a value was not given
+	// one.
+	ErrMissingValue = errors.New("value not given")
+
+	// ErrNotBoolean indicates that a non-boolean shorthand flag was combined
+	// with other shorthand flags in a non-final position.
+	ErrNotBoolean = errors.New("flag is not boolean")
+
+	// ErrMissingArgument indicates that a positional argument was not given.
+	ErrMissingArgument = errors.New("missing positional argument")
+
+	// ErrExtraneousArgument indicates that an argument was not consumed by
+	// any definition.
+	ErrExtraneousArgument = errors.New("extraneous argument")
+)
+
+// ParseError represents an error encountered while parsing an argument.
+type ParseError struct {
+	// Name of the flag (e.g. `--ratio` or `-r`) or positional argument
+	// (e.g. `<count>`). Empty for extraneous arguments.
+	Name string
+
+	// Input is the raw input which caused the error.
+	Input string
+
+	// Type is the expected type of the value.
+	Type string
+
+	// Suggestions are the names the input may have been intended as.
+	Suggestions []string
+
+	// Err is the cause of the error.
+	Err error
+}
+
+// IsFlag reports whether the error concerns a flag.
+func (e *ParseError) IsFlag() bool {
+	return strings.HasPrefix(e.Name, "-")
+}
+
+func (e *ParseError) suggest() string {
+	return strings.Join(e.Suggestions, "`, `")
+}
+
+// Error satisfies the error interface.
+func (e *ParseError) Error() string {
+	switch e.Err {
+	case ErrUnknownFlag:
+		if len(e.Suggestions) > 0 {
+			return fmt.Sprintf(msg("unknown flag `%s`, did you mean `%s`?"), e.Name, e.suggest())
+		}
+		return fmt.Sprintf(msg("unknown flag `%s`"), e.Name)
+	case ErrAmbiguousFlag:
+		return fmt.Sprintf(msg("ambiguous flag `%s` could be `%s`"), e.Name, e.suggest())
+	case ErrMissingValue:
+		return fmt.Sprintf(msg("value not given for flag `%s`"), e.Name)
+	case ErrNotBoolean:
+		return fmt.Sprintf(msg("flag `%s` in `%s` is not boolean"), e.Name, e.Input)
+	case ErrMissingArgument:
+		return fmt.Sprintf(msg("missing positional argument `%s`"), e.Name)
+	case ErrExtraneousArgument:
+		return fmt.Sprintf(msg("extraneous argument `%s`"), e.Input)
+	}
+	if e.IsFlag() {
+		return fmt.Sprintf(msg("in flag `%s`: %v"), e.Name, e.Err)
+	}
+	return fmt.Sprintf(msg("in positional argument `%s`: %v"), e.Name, e.Err)
+}
+
+// Unwrap returns the cause of the error.
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// Typed is implemented by values which can describe their type.
+type Typed interface {
+	Type() string
+}
+
+// TypeName returns the name of the type expected by the value.
+func TypeName(v Value) string {
+	if t, ok := v.(Typed); ok {
+		return t.Type()
+	}
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	name := strings.TrimSuffix(t.Name(), "Value")
+	return strings.ToLower(name)
+}
@@ -0,0 +1,64 @@
+package flags
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Conventional exit status codes.
+const (
+	// ExitSuccess indicates that the command completed successfully.
+	ExitSuccess = 0
+
+	// ExitFailure indicates that the command failed at runtime.
+	ExitFailure = 1
+
+	// ExitUsage indicates that the command was invoked incorrectly.
+	ExitUsage = 2
+
+	// ExitPanic indicates that the command panicked. It is the value of
+	// EX_SOFTWARE in sysexits.h.
+	ExitPanic = 70
+)
+
+// ExitError is an error which carries the status code to exit with.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+// Exit creates an error which will make Run exit with the given code. The
+// error may be nil in which case nothing is reported.
+func Exit(code int, err error) error {
+	return &ExitError{code, err}
+}
+
+// usageError wraps the error so that Run will exit with ExitUsage.
+func usageError(err error) error {
+	return &ExitError{ExitUsage, err}
+}
+
+// Error satisfies the error interface.
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *ExitError) Unwrap() error { return e.Err }
+
+// ExitCode returns the status code a program should exit with for the error.
+// A nil error or ErrHelp yields ExitSuccess, an ExitError yields its Code,
+// and any other error yields ExitFailure.
+func ExitCode(err error) int {
+	if err == nil || errors.Is(err, ErrHelp) {
+		return ExitSuccess
+	}
+	var e *ExitError
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ExitFailure
+}
@@ -0,0 +1,182 @@
+package flags
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// FetchValue represents a resource argument value which is either a local
+// file path or an http(s) URL. The resource is opened when Reader is called.
+type FetchValue struct {
+	init *os.File
+	src  string
+	rc   io.ReadCloser
+
+	// Timeout limits the time taken to fetch a remote resource including
+	// reading its body. No limit is imposed if Timeout is zero.
+	Timeout time.Duration
+
+	// MaxSize limits the number of bytes read from the resource. Reading
+	// past the limit results in an error. No limit is imposed if MaxSize is
+	// zero.
+	MaxSize int64
+
+	// Client is used to fetch remote resources. A client with Timeout is
+	// used if Client is nil.
+	Client *http.Client
+}
+
+// NewFetchValue creates a new FetchValue. The initial file is read if no
+// resource is given.
+func NewFetchValue(init *os.File) *FetchValue {
+	return &FetchValue{init: init}
+}
+
+func isRemote(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// Set will set attempt to convert the given string to a value.
+func (v *FetchValue) Set(s string) error {
+	if isRemote(s) {
+		if u, err := url.Parse(s); err != nil || u.Host == "" {
+			return fmt.Errorf(msg("`%s` is not a valid URL"), s)
+		}
+	} else {
+		info, err := os.Stat(s)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return fmt.Errorf(msg("`%s` is a directory"), s)
+		}
+	}
+	v.Close()
+	v.src = s
+	return nil
+}
+
+func (v *FetchValue) fetch() (io.ReadCloser, error) {
+	client := v.Client
+	if client == nil {
+		client = &http.Client{Timeout: v.Timeout}
+	}
+	res, err := client.Get(v.src)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || 299 < res.StatusCode {
+		res.Body.Close()
+		return nil, fmt.Errorf(msg("fetching `%s`: %s"), v.src, res.Status)
+	}
+	if v.MaxSize > 0 && res.ContentLength > v.MaxSize {
+		res.Body.Close()
+		return nil, fmt.Errorf(msg("fetching `%s`: size of %d bytes exceeds the limit of %d bytes"), v.src, res.ContentLength, v.MaxSize)
+	}
+	return res.Body, nil
+}
+
+// Reader returns the resource for reading, opening it if it is not open.
+func (v *FetchValue) Reader() (io.ReadCloser, error) {
+	if v.rc != nil {
+		return v.rc, nil
+	}
+	var rc io.ReadCloser
+	switch {
+	case isRemote(v.src):
+		var err error
+		if rc, err = v.fetch(); err != nil {
+			return nil, err
+		}
+	case v.src != "":
+		f, err := os.Open(v.src)
+		if err != nil {
+			return nil, err
+		}
+		rc = f
+	case v.init != nil:
+		return v.init, nil
+	default:
+		return nil, errors.New(msg("no resource to read from"))
+	}
+	if v.MaxSize > 0 {
+		rc = &limitedReadCloser{rc, v.MaxSize, v.src}
+	}
+	v.rc = rc
+	return rc, nil
+}
+
+// String satisfies the fmt.Stringer interface.
+func (v *FetchValue) String() string {
+	switch {
+	case v.src != "":
+		return v.src
+	case v.init != nil:
+		return v.init.Name()
+	default:
+		return ""
+	}
+}
+
+// Close the resource if it was opened by the value. The initial file is left
+// open.
+func (v *FetchValue) Close() error {
+	if v.rc == nil {
+		return nil
+	}
+	rc := v.rc
+	v.rc = nil
+	return rc.Close()
+}
+
+// Reset closes the resource if it was opened by the value and restores the
+// initial file.
+func (v *FetchValue) Reset() {
+	v.Close()
+	v.src = ""
+}
+
+type limitedReadCloser struct {
+	io.ReadCloser
+	n   int64
+	src string
+}
+
+func (r *limitedReadCloser) Read(p []byte) (int, error) {
+	if r.n < 0 {
+		return 0, fmt.Errorf(msg("reading `%s`: size exceeds the limit"), r.src)
+	}
+	if int64(len(p)) > r.n+1 {
+		p = p[:r.n+1]
+	}
+	n, err := r.ReadCloser.Read(p)
+	r.n -= int64(n)
+	if r.n < 0 {
+		return n + int(r.n), fmt.Errorf(msg("reading `%s`: size exceeds the limit"), r.src)
+	}
+	return n, err
+}
+
+// Fetch adds a local file or http(s) URL flag to the optional argument list.
+// The resource will be closed after the command returns if parsed with
+// Context.Parse.
+func (opt *Optional) Fetch(short rune, long string, init *os.File, usage string) *FetchValue {
+	value := NewFetchValue(init)
+	opt.Register(short, long, value, usage)
+	return value
+}
+
+// Fetch adds a local file or http(s) URL to the positional argument list.
+// The resource will be closed after the command returns if parsed with
+// Context.Parse.
+func (pos *Positional) Fetch(name, usage string) *FetchValue {
+	value := NewFetchValue(nil)
+	pos.Register(name, value, usage)
+	return value
+}
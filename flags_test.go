@@ -1,9 +1,33 @@
 package flags
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	goflag "flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
 )
 
 func same(a, b interface{}) bool {
@@ -79,3 +103,4013 @@ func TestPositional(t *testing.T) {
 		return
 	}
 }
+
+func TestContextDefaults(t *testing.T) {
+	ctx := &Context{Name: "test"}
+	Exec(ctx, func(ctx *Context) error { return nil })
+	equals(t, ctx.Stdin, io.Reader(os.Stdin))
+	equals(t, ctx.Stdout, io.Writer(os.Stdout))
+	equals(t, ctx.Stderr, io.Writer(os.Stderr))
+
+	buf := &bytes.Buffer{}
+	prog := NewProgram()
+	prog.Add("echo", "echo arguments", func(ctx *Context) error {
+		_, err := fmt.Fprintln(ctx.Stdout, strings.Join(ctx.Args, " "))
+		return err
+	})
+	ctx = &Context{Name: "test", Args: []string{"echo", "foo", "bar"}, Stdout: buf}
+	equals(t, Exec(ctx, prog.Compile()), 0)
+	equals(t, buf.String(), "foo bar\n")
+}
+
+func TestExitCode(t *testing.T) {
+	equals(t, ExitCode(nil), ExitSuccess)
+	equals(t, ExitCode(errors.New("failure")), ExitFailure)
+	equals(t, ExitCode(Exit(3, nil)), 3)
+	equals(t, ExitCode(fmt.Errorf("wrapped: %w", Exit(4, errors.New("inner")))), 4)
+
+	buf := &bytes.Buffer{}
+	ctx := &Context{Name: "test", Args: []string{"--unknown"}, Stderr: buf}
+	code := Exec(ctx, func(ctx *Context) error {
+		return ctx.Parse(Args())
+	})
+	equals(t, code, ExitUsage)
+
+	buf.Reset()
+	code = Exec(ctx, func(ctx *Context) error { return Exit(5, nil) })
+	equals(t, code, 5)
+	equals(t, buf.String(), "")
+}
+
+func TestHelp(t *testing.T) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	ctx := &Context{Name: "test", Args: []string{"--help"}, Stdout: stdout, Stderr: stderr}
+	code := Exec(ctx, func(ctx *Context) error {
+		pos, opt := Args()
+		pos.String("name", "name of the thing")
+		return ctx.Parse(pos, opt)
+	})
+	equals(t, code, ExitSuccess)
+	equals(t, strings.HasPrefix(stdout.String(), "usage: test"), true)
+	equals(t, stderr.String(), "")
+
+	parser := NewParser(nil, nil)
+	equals(t, parser.Parse([]string{"-h"}), ErrHelp)
+	equals(t, parser.Parse([]string{"-vh"}), ErrHelp)
+	equals(t, parser.Parse([]string{"--help=x"}), ErrHelp)
+
+	pos, opt := Args()
+	host := opt.String('h', "host", "", "host to connect to")
+	topic := opt.String(0, "help", "", "help topic")
+	parser = NewParser(pos, opt)
+	equals(t, parser.Parse([]string{"-h", "example", "--help", "flags"}), nil)
+	equals(t, *host, "example")
+	equals(t, *topic, "flags")
+}
+
+func TestRegexpValue(t *testing.T) {
+	pos, opt := Args()
+	pattern := pos.Regexp("pattern", "pattern to match")
+	exclude := opt.Regexp('x', "exclude", nil, "pattern to exclude")
+	parser := NewParser(pos, opt)
+
+	equals(t, exclude.MatchString("anything"), true)
+
+	if err := parser.Parse([]string{"-x", "^_", "fo+"}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, pattern.MatchString("foo"), true)
+	equals(t, pattern.MatchString("bar"), false)
+	equals(t, exclude.MatchString("_foo"), true)
+
+	if err := parser.Parse([]string{"fo(o"}); err == nil {
+		t.Error("parser.Parse([]string{\"fo(o\"}) = nil, want error")
+	}
+}
+
+func TestGeneric(t *testing.T) {
+	pos, opt := Args()
+	count := New(0)
+	verbose := New(false)
+	timeout := New(time.Second)
+	names := Slice[string]()
+	pos.Register("count", count, "number of things")
+	opt.Register('v', "verbose", verbose, "be verbose")
+	opt.Register('t', "timeout", timeout, "timeout duration")
+	opt.Register('n', "name", names, "names of things")
+	parser := NewParser(pos, opt)
+
+	args := []string{"-v", "-n", "foo", "-n", "bar", "--timeout", "1m", "42"}
+	if err := parser.Parse(args); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+
+	equals(t, count.Get(), 42)
+	equals(t, verbose.Get(), true)
+	equals(t, timeout.Get(), time.Minute)
+	equals(t, names.Get(), []string{"foo", "bar"})
+	equals(t, *count.Ptr(), 42)
+
+	if err := parser.Parse([]string{"foo"}); err == nil {
+		t.Error("parser.Parse([]string{\"foo\"}) = nil, want error")
+	}
+
+	var value *Var[int] = NewIntValue(7)
+	equals(t, value.Get(), 7)
+
+	// Zero values are usable as they were before the types became aliases.
+	var quiet BoolValue
+	var level IntValue
+	var tags StringSliceValue
+	var env StringToStringValue
+	equals(t, quiet.String(), "false")
+	equals(t, quiet.IsBoolFlag(), true)
+	pos, opt = Args()
+	opt.Register('q', "quiet", &quiet, "be quiet")
+	opt.Register('l', "level", &level, "verbosity level")
+	opt.Register('t', "tag", &tags, "tags")
+	opt.Register('e', "env", &env, "environment")
+	if err := NewParser(pos, opt).Parse([]string{"-q", "-l", "2", "-t", "a", "-e", "k=v"}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, quiet.Get(), true)
+	equals(t, level.Get(), 2)
+	equals(t, tags.Get(), []string{"a"})
+	equals(t, env.Get(), map[string]string{"k": "v"})
+	equals(t, TypeName(NewFloatValue(0)), "float")
+	equals(t, TypeName(timeout), "duration")
+	equals(t, TypeName(NewStringSliceValue(nil)), "stringslice")
+}
+
+func TestDirValue(t *testing.T) {
+	root, err := ioutil.TempDir("", "flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	value := NewDirValue("")
+	if err := value.Set(root); err != nil {
+		t.Errorf("value.Set(%q): %v", root, err)
+	}
+	equals(t, value.String(), root)
+
+	missing := filepath.Join(root, "missing", "dir")
+	if err := value.Set(missing); err == nil {
+		t.Errorf("value.Set(%q) = nil, want error", missing)
+	}
+
+	value.Create, value.Writable = true, true
+	if err := value.Set(missing); err != nil {
+		t.Errorf("value.Set(%q): %v", missing, err)
+	}
+	if info, err := os.Stat(missing); err != nil || !info.IsDir() {
+		t.Errorf("expected %q to be created", missing)
+	}
+
+	file := filepath.Join(root, "file")
+	if err := ioutil.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := value.Set(file); err == nil {
+		t.Errorf("value.Set(%q) = nil, want error", file)
+	}
+}
+
+func TestGlobSliceValue(t *testing.T) {
+	root, err := ioutil.TempDir("", "flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	for _, name := range []string{"a.txt", "b.txt", "c.csv"} {
+		if err := ioutil.WriteFile(filepath.Join(root, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pos, _ := Args()
+	files := pos.Glob("files", "files to process")
+	dest := pos.String("dest", "destination")
+	parser := NewParser(pos, nil)
+
+	args := []string{filepath.Join(root, "*.txt"), "literal", "out"}
+	if err := parser.Parse(args); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, *files, []string{
+		filepath.Join(root, "a.txt"),
+		filepath.Join(root, "b.txt"),
+		"literal",
+	})
+	equals(t, *dest, "out")
+
+	pattern := filepath.Join(root, "*.json")
+	if err := parser.Parse([]string{pattern, "out"}); err == nil {
+		t.Errorf("parser.Parse([]string{%q, \"out\"}) = nil, want error", pattern)
+	}
+}
+
+func TestCloseFiles(t *testing.T) {
+	f, err := ioutil.TempFile("", "flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	var in *OpenValue
+	ctx := &Context{Name: "test", Args: []string{f.Name()}}
+	code := Exec(ctx, func(ctx *Context) error {
+		pos, opt := Args()
+		in = pos.Open("file", "file to read")
+		if err := ctx.Parse(pos, opt); err != nil {
+			return err
+		}
+		_, err := ioutil.ReadAll(in)
+		return err
+	})
+	equals(t, code, ExitSuccess)
+	if _, err := in.Read(make([]byte, 1)); err == nil {
+		t.Error("expected file to be closed")
+	}
+}
+
+func TestCreateValue(t *testing.T) {
+	root, err := ioutil.TempDir("", "flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	name := filepath.Join(root, "out.txt")
+	if err := ioutil.WriteFile(name, []byte("foo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	value := NewCreateValue(nil)
+	value.Exclusive = true
+	if err := value.Set(name); err == nil {
+		t.Errorf("value.Set(%q) = nil, want error", name)
+	}
+
+	value = NewCreateValue(nil)
+	value.Append = true
+	if err := value.Set(name); err != nil {
+		t.Errorf("value.Set(%q): %v", name, err)
+		return
+	}
+	value.WriteString("bar\n")
+	value.Close()
+
+	p, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, string(p), "foo\nbar\n")
+
+	value = NewCreateValue(nil)
+	value.Perm = 0600
+	other := filepath.Join(root, "other.txt")
+	if err := value.Set(other); err != nil {
+		t.Errorf("value.Set(%q): %v", other, err)
+		return
+	}
+	value.Close()
+	if info, err := os.Stat(other); err != nil || info.Mode().Perm()&0077 != 0 {
+		t.Errorf("expected %q to be private", other)
+	}
+}
+
+func TestLazyFiles(t *testing.T) {
+	root, err := ioutil.TempDir("", "flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	name := filepath.Join(root, "log.txt")
+	out := NewCreateValue(nil)
+	out.Lazy = true
+	if err := out.Set(name); err != nil {
+		t.Errorf("out.Set(%q): %v", name, err)
+		return
+	}
+	equals(t, out.String(), name)
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("expected %q to not be created yet", name)
+	}
+
+	w, err := out.Writer()
+	if err != nil {
+		t.Errorf("out.Writer(): %v", err)
+		return
+	}
+	io.WriteString(w, "foo")
+	out.Close()
+
+	in := NewOpenValue(nil)
+	in.Lazy = true
+	missing := filepath.Join(root, "missing.txt")
+	if err := in.Set(missing); err == nil {
+		t.Errorf("in.Set(%q) = nil, want error", missing)
+	}
+	if err := in.Set(name); err != nil {
+		t.Errorf("in.Set(%q): %v", name, err)
+		return
+	}
+	r, err := in.Reader()
+	if err != nil {
+		t.Errorf("in.Reader(): %v", err)
+		return
+	}
+	p, _ := ioutil.ReadAll(r)
+	equals(t, string(p), "foo")
+	in.Close()
+}
+
+func TestArity(t *testing.T) {
+	pos, _ := Args()
+	src := pos.Glob("src", "source files")
+	pos.String("dest", "destination")
+	pos.Arity("src", 1, 3)
+	parser := NewParser(pos, nil)
+
+	if err := parser.Parse([]string{"a", "b", "out"}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, *src, []string{"a", "b"})
+
+	if err := parser.Parse([]string{"out"}); err == nil {
+		t.Error("parser.Parse([]string{\"out\"}) = nil, want error")
+	}
+	if err := parser.Parse([]string{"a", "b", "c", "d", "out"}); err == nil {
+		t.Error("parser.Parse([]string{\"a\", \"b\", \"c\", \"d\", \"out\"}) = nil, want error")
+	}
+
+	equals(t, Usage(pos, nil), "[-h | --help] <src> ... <dest>")
+	panics(t, func() { pos.Arity("dest", 1, 1) })
+	panics(t, func() { pos.Arity("missing", 1, 1) })
+	panics(t, func() { pos.Arity("src", 2, 1) })
+}
+
+func TestInterspersed(t *testing.T) {
+	pos, opt := Args()
+	src := pos.String("src", "source")
+	dst := pos.String("dst", "destination")
+	verbose := opt.Switch('v', "verbose", "be verbose")
+	mode := opt.String('m', "mode", "copy", "copy mode")
+	parser := NewParser(pos, opt)
+
+	if err := parser.Parse([]string{"foo", "--verbose", "bar", "--mode=move"}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, *src, "foo")
+	equals(t, *dst, "bar")
+	equals(t, *verbose, true)
+	equals(t, *mode, "move")
+
+	*verbose = false
+	if err := parser.Parse([]string{"foo", "--", "-v"}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, *dst, "-v")
+	equals(t, *verbose, false)
+
+	opt.NoInterspersed = true
+	if err := parser.Parse([]string{"-v", "foo", "bar", "-v"}); err == nil {
+		t.Error("parser.Parse([]string{\"-v\", \"foo\", \"bar\", \"-v\"}) = nil, want error")
+	}
+
+	if err := parser.Parse([]string{"foo", "bar", "--mode"}); err == nil {
+		t.Error("parser.Parse([]string{\"foo\", \"bar\", \"--mode\"}) = nil, want error")
+	}
+}
+
+func TestAttachedValues(t *testing.T) {
+	pos, opt := Args()
+	verbose := opt.Switch('v', "verbose", "be verbose")
+	count := opt.Int('n', "count", 1, "number of items")
+	output := opt.String('o', "output", "", "output name")
+	parser := NewParser(pos, opt)
+
+	if err := parser.Parse([]string{"-n5"}); err == nil {
+		t.Error("parser.Parse([]string{\"-n5\"}) = nil, want error")
+	}
+
+	opt.AttachedValues = true
+	if err := parser.Parse([]string{"-n5", "-vofile.txt"}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, *count, 5)
+	equals(t, *verbose, true)
+	equals(t, *output, "file.txt")
+
+	if err := parser.Parse([]string{"-ov", "-n", "3"}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, *output, "v")
+	equals(t, *verbose, false)
+	equals(t, *count, 3)
+
+	if err := parser.Parse([]string{"-nx"}); err == nil {
+		t.Error("parser.Parse([]string{\"-nx\"}) = nil, want error")
+	}
+}
+
+func TestAbbrev(t *testing.T) {
+	pos, opt := Args()
+	verbose := opt.Switch('v', "verbose", "be verbose")
+	opt.Switch(0, "version", "show version")
+	output := opt.String('o', "output", "", "output name")
+	parser := NewParser(pos, opt)
+
+	if err := parser.Parse([]string{"--verb"}); err == nil {
+		t.Error("parser.Parse([]string{\"--verb\"}) = nil, want error")
+	}
+
+	opt.Abbrev = true
+	if err := parser.Parse([]string{"--verb", "--out=foo"}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, *verbose, true)
+	equals(t, *output, "foo")
+
+	err := parser.Parse([]string{"--ver"})
+	if err == nil {
+		t.Error("parser.Parse([]string{\"--ver\"}) = nil, want error")
+		return
+	}
+	equals(t, err.Error(), "ambiguous flag `--ver` could be `--verbose`, `--version`")
+}
+
+func TestParseErrors(t *testing.T) {
+	root, err := ioutil.TempDir("", "flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	pos, opt := Args()
+	pos.Int("count", "number of things")
+	pos.Open("file", "file to read")
+	opt.Float('r', "ratio", 0.5, "ratio of things")
+	parser := NewParser(pos, opt)
+
+	missing := filepath.Join(root, "missing")
+	args := []string{"--unknown", "--ratio", "half", "many", missing, "extra"}
+	err = parser.Parse(args)
+	if err == nil {
+		t.Errorf("parser.Parse(%q) = nil, want error", args)
+		return
+	}
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Errorf("expected joined errors, got %T", err)
+		return
+	}
+	equals(t, len(joined.Unwrap()), 5)
+
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected %v to wrap os.ErrNotExist", err)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	pos, opt := Args()
+	pos.Int("count", "number of things")
+	opt.Float('r', "ratio", 0.5, "ratio of things")
+	parser := NewParser(pos, opt)
+
+	err := parser.Parse([]string{"-r", "half", "-x", "42", "extra"})
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Errorf("expected %v to be a ParseError", err)
+		return
+	}
+	equals(t, *perr, ParseError{"--ratio", "half", "float", nil, perr.Err})
+	equals(t, perr.Error(), "in flag `--ratio`: `half` cannot be interpreted as float64")
+
+	if !errors.Is(err, ErrUnknownFlag) {
+		t.Errorf("expected %v to wrap ErrUnknownFlag", err)
+	}
+	if !errors.Is(err, ErrExtraneousArgument) {
+		t.Errorf("expected %v to wrap ErrExtraneousArgument", err)
+	}
+
+	err = parser.Parse(nil)
+	if !errors.As(err, &perr) {
+		t.Errorf("expected %v to be a ParseError", err)
+		return
+	}
+	equals(t, *perr, ParseError{"<count>", "", "int", nil, ErrMissingArgument})
+}
+
+func TestComplete(t *testing.T) {
+	profiles := func(prefix string) []string {
+		return []string{"default", "dev", "prod"}
+	}
+
+	prog := NewProgram()
+	prog.Add("deploy", "deploy a profile", func(ctx *Context) error {
+		pos, opt := Args()
+		pos.String("profile", "profile to deploy")
+		pos.Complete("profile", profiles)
+		opt.String('r', "region", "", "region to deploy to")
+		opt.Complete("region", func(prefix string) []string {
+			return []string{"us-east", "us-west", "eu-north"}
+		})
+		opt.Switch('f', "force", "force deployment")
+		if err := ctx.Parse(pos, opt); err != nil {
+			return err
+		}
+		t.Error("command should not run while completing")
+		return nil
+	})
+	prog.Add("destroy", "destroy a profile", func(ctx *Context) error { return nil })
+
+	complete := func(args ...string) string {
+		buf := &bytes.Buffer{}
+		args = append([]string{CompleteCommand}, args...)
+		ctx := &Context{Name: "test", Args: args, Stdout: buf}
+		equals(t, Exec(ctx, prog.Compile()), ExitSuccess)
+		return buf.String()
+	}
+
+	equals(t, complete("de"), "deploy\ndestroy\n")
+	equals(t, complete("deploy", "d"), "default\ndev\n")
+	equals(t, complete("deploy", "-f", "p"), "prod\n")
+	equals(t, complete("deploy", "--region", "us"), "us-east\nus-west\n")
+	equals(t, complete("deploy", "--region=eu"), "--region=eu-north\n")
+	equals(t, complete("deploy", "--f"), "--force\n")
+	equals(t, complete("deploy", "dev", ""), "")
+	equals(t, complete("deploy", "-3", ""), "")
+	equals(t, complete("deploy", "-fr", "us-w"), "us-west\n")
+
+	script, err := CompletionScript("bash", "test")
+	if err != nil {
+		t.Errorf("CompletionScript: %v", err)
+	}
+	equals(t, strings.Contains(script, "test __complete"), true)
+	script, err = CompletionScript("zsh", "test")
+	if err != nil {
+		t.Errorf("CompletionScript: %v", err)
+	}
+	equals(t, strings.Contains(script, "compadd -S '' -a dirs"), true)
+	equals(t, strings.Contains(script, "compadd -a others"), true)
+	if _, err := CompletionScript("tcsh", "test"); err == nil {
+		t.Error("CompletionScript(\"tcsh\", \"test\") = nil, want error")
+	}
+}
+
+func TestCompleteFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.json", "b.YAML", "c.txt", ".hidden.json"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "conf"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	prog := NewProgram()
+	prog.Add("load", "load a config", func(ctx *Context) error {
+		pos, opt := Args()
+		pos.Open("config", "config file")
+		pos.Extensions("config", ".json", ".yaml")
+		opt.Create('o', "out", nil, "output file")
+		opt.Extensions("out", ".txt")
+		return ctx.Parse(pos, opt)
+	})
+
+	complete := func(args ...string) string {
+		buf := &bytes.Buffer{}
+		args = append([]string{CompleteCommand}, args...)
+		ctx := &Context{Name: "test", Args: args, Stdout: buf}
+		equals(t, Exec(ctx, prog.Compile()), ExitSuccess)
+		return strings.ReplaceAll(buf.String(), dir, "$DIR")
+	}
+
+	equals(t, complete("load", dir+"/"), "$DIR/a.json\n$DIR/b.YAML\n$DIR/conf/\n")
+	equals(t, complete("load", dir+"/."), "$DIR/.hidden.json\n")
+	equals(t, complete("load", "-o", dir+"/"), "$DIR/c.txt\n$DIR/conf/\n")
+	equals(t, complete("load", "-o", dir+"/missing/"), "")
+	equals(t, len(CompleteFiles()(dir+"/")), 4)
+}
+
+func TestCloneProgram(t *testing.T) {
+	echo := func(ctx *Context) error {
+		fmt.Fprintln(ctx.Stdout, ctx.Name)
+		return nil
+	}
+	sub := NewProgram()
+	sub.Add("add", "add a remote", echo)
+
+	prog := NewProgram()
+	prog.Add("status", "show status", echo)
+	prog.AddProgram("remote", "manage remotes", sub)
+	prog.AddAlias("st", "status")
+
+	run := func(cmd Command, args ...string) string {
+		stdout := &bytes.Buffer{}
+		ctx := &Context{Name: "git", Args: args, Stdout: stdout, Stderr: ioutil.Discard}
+		Exec(ctx, cmd)
+		return stdout.String()
+	}
+
+	clone := prog.Clone()
+	clone.Add("commit", "record changes", echo)
+	clone.Map["remote"].Prog.Add("remove", "remove a remote", echo)
+	equals(t, run(clone.Compile(), "commit"), "git commit\n")
+	equals(t, run(clone.Compile(), "remote", "remove"), "git remote remove\n")
+	equals(t, run(clone.Compile(), "st"), "git status\n")
+	equals(t, run(prog.Compile(), "commit"), "")
+	equals(t, run(prog.Compile(), "remote", "remove"), "")
+	equals(t, prog.Map["remote"].Prog == sub, true)
+
+	cmd := prog.CompileClone()
+	prog.Add("log", "show history", echo)
+	equals(t, run(prog.Compile(), "log"), "git log\n")
+	equals(t, run(cmd, "log"), "")
+
+	prog.Reset()
+	equals(t, len(prog.Map), 0)
+	equals(t, len(prog.Aliases), 0)
+	equals(t, run(prog.Compile(), "status"), "")
+	prog.Add("status", "show status", echo)
+	equals(t, run(prog.Compile(), "status"), "git status\n")
+	equals(t, run(clone.Compile(), "status"), "git status\n")
+}
+
+func TestCloneProgramConcurrently(t *testing.T) {
+	prog := NewProgram()
+	prog.Add("status", "show status", func(ctx *Context) error { return nil })
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			prog.Clone().Add("commit", "record changes", func(ctx *Context) error { return nil })
+		}()
+		go func() {
+			defer wg.Done()
+			prog.Reset()
+			prog.Add("status", "show status", func(ctx *Context) error { return nil })
+		}()
+	}
+	wg.Wait()
+	equals(t, prog.Map["status"].Desc, "show status")
+
+	cmd := Program.Compile(*prog)
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			prog.Add(fmt.Sprintf("cmd%d", i), "run a command", func(ctx *Context) error { return nil })
+		}(i)
+		go func() {
+			defer wg.Done()
+			ctx := &Context{Name: "git", Args: []string{"status"}, Stdout: ioutil.Discard}
+			equals(t, Exec(ctx, cmd), ExitSuccess)
+		}()
+	}
+	wg.Wait()
+	ctx := &Context{Name: "git", Args: []string{"cmd3"}, Stdout: ioutil.Discard}
+	equals(t, Exec(ctx, cmd), ExitSuccess)
+}
+
+func TestAddProgram(t *testing.T) {
+	sub := NewProgram()
+	sub.Add("add", "add a remote", func(ctx *Context) error {
+		fmt.Fprintln(ctx.Stdout, ctx.Name, ctx.Args)
+		return nil
+	})
+	sub.Add("remove", "remove a remote", func(ctx *Context) error { return nil })
+
+	prog := NewProgram()
+	prog.Add("status", "show status", func(ctx *Context) error { return nil })
+	prog.AddProgram("remote", "manage remotes", sub)
+
+	equals(t, ListCommands(*prog), strings.Join([]string{
+		"available commands:",
+		"  remote         manage remotes",
+		"  remote add     add a remote",
+		"  remote remove  remove a remote",
+		"  status         show status",
+	}, "\n"))
+
+	buf := &bytes.Buffer{}
+	ctx := &Context{Name: "git", Args: []string{"remote", "add", "origin"}, Stdout: buf}
+	equals(t, Exec(ctx, prog.Compile()), ExitSuccess)
+	equals(t, buf.String(), "git remote add [origin]\n")
+
+	buf.Reset()
+	ctx = &Context{Name: "git", Args: []string{"remote", "--help"}, Stdout: buf}
+	equals(t, Exec(ctx, prog.Compile()), ExitSuccess)
+	equals(t, strings.HasPrefix(buf.String(), "git remote: manage remotes\n"), true)
+}
+
+func TestExternal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not executable on windows")
+	}
+
+	root, err := ioutil.TempDir("", "flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	script := "#!/bin/sh\necho \"plugin $@\"\nexit 3\n"
+	name := filepath.Join(root, "tool-plugin")
+	if err := ioutil.WriteFile(name, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", root+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	prog := NewProgram()
+	buf := &bytes.Buffer{}
+	ctx := &Context{Name: "tool", Args: []string{"plugin", "foo", "bar"}, Stdout: buf}
+	equals(t, Exec(ctx, prog.Compile()), ExitUsage)
+
+	prog.External = true
+	ctx = &Context{Name: "tool", Args: []string{"plugin", "foo", "bar"}, Stdout: buf}
+	equals(t, Exec(ctx, prog.Compile()), 3)
+	equals(t, buf.String(), "plugin foo bar\n")
+}
+
+func TestSplit(t *testing.T) {
+	words, err := Split(`prog -a 'b c' "d \"e\"" f\ g  `)
+	if err != nil {
+		t.Errorf("Split: %v", err)
+		return
+	}
+	equals(t, words, []string{"prog", "-a", "b c", `d "e"`, "f g"})
+
+	for _, s := range []string{`'foo`, `"foo`, `foo\`} {
+		if _, err := Split(s); err == nil {
+			t.Errorf("Split(%q) = nil, want error", s)
+		}
+	}
+}
+
+func TestCommandLineValue(t *testing.T) {
+	pos, opt := Args()
+	exec := opt.CommandLine('e', "exec", []string{"true"}, "command to execute")
+	parser := NewParser(pos, opt)
+
+	if err := parser.Parse([]string{"--exec", `prog -a 'b c' "it's"`}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, *exec, []string{"prog", "-a", "b c", "it's"})
+
+	value := opt.Args["exec"].Value
+	equals(t, value.String(), `prog -a 'b c' 'it'\''s'`)
+	words, err := Split(value.String())
+	equals(t, err, nil)
+	equals(t, words, *exec)
+
+	if err := parser.Parse([]string{"--exec", "prog 'a"}); err == nil {
+		t.Error("parser.Parse([]string{\"--exec\", \"prog 'a\"}) = nil, want error")
+	}
+	equals(t, Join([]string{"", "a"}), "'' a")
+}
+
+func TestInteractive(t *testing.T) {
+	prog := NewProgram()
+	prog.Add("echo", "echo arguments", func(ctx *Context) error {
+		_, err := fmt.Fprintln(ctx.Stdout, strings.Join(ctx.Args, ","))
+		return err
+	})
+
+	stdin := strings.NewReader("echo 'foo bar' baz\n\nunknown\nexit\necho unreachable\n")
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	ctx := &Context{Name: "test", Stdin: stdin, Stdout: stdout, Stderr: stderr}
+	equals(t, Exec(ctx, Interactive(prog)), ExitSuccess)
+	equals(t, stdout.String(), "test> foo bar,baz\ntest> test> test> ")
+	equals(t, stderr.String(), "unknown command name `unknown`\n")
+}
+
+func TestDecode(t *testing.T) {
+	pos, opt := Args()
+	pos.String("name", "name of the thing")
+	opt.Int('n', "max-count", 1, "maximum count")
+	opt.Switch('v', "verbose", "be verbose")
+	opt.StringSlice('t', "tag", nil, "tags")
+	opt.Register('d', "delay", New(time.Second), "delay")
+	opt.Open('i', "input", os.Stdin, "input file")
+	opt.Regexp('p', "pattern", regexp.MustCompile("^a"), "pattern")
+	parser := NewParser(pos, opt)
+
+	if err := parser.Parse([]string{"-v", "--max-count", "3", "-t", "a", "-t", "b", "foo"}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+
+	type config struct {
+		Name     string
+		MaxCount int
+		Loud     bool     `flag:"verbose"`
+		Tags     []string `flag:"tag"`
+		Delay    time.Duration
+		Input    *os.File
+		Pattern  *regexp.Regexp
+		Ignored  string `flag:"-"`
+	}
+
+	cfg := config{Ignored: "untouched"}
+	if err := opt.Decode(&cfg); err != nil {
+		t.Errorf("opt.Decode: %v", err)
+		return
+	}
+	if err := pos.Decode(&cfg); err != nil {
+		t.Errorf("pos.Decode: %v", err)
+		return
+	}
+	equals(t, cfg.Name, "foo")
+	equals(t, cfg.MaxCount, 3)
+	equals(t, cfg.Loud, true)
+	equals(t, cfg.Tags, []string{"a", "b"})
+	equals(t, cfg.Delay, time.Second)
+	equals(t, cfg.Input, os.Stdin)
+	equals(t, cfg.Pattern.String(), "^a")
+	equals(t, cfg.Ignored, "untouched")
+
+	var bad struct {
+		MaxCount string
+	}
+	if err := opt.Decode(&bad); err == nil {
+		t.Error("opt.Decode(&bad) = nil, want error")
+	}
+	if err := opt.Decode(cfg); err == nil {
+		t.Error("opt.Decode(cfg) = nil, want error")
+	}
+}
+
+func TestDump(t *testing.T) {
+	pos, opt := Args()
+	opt.Int('p', "port", 8080, "port to listen on")
+	opt.String('t', "token", "", "access token")
+	opt.StringSlice('H', "header", nil, "headers")
+	opt.Register('d', "delay", New(time.Second), "delay")
+	opt.Secret("token")
+	parser := NewParser(pos, opt)
+
+	if err := parser.Parse([]string{"--token", "hunter2", "-H", "a", "-H", "b"}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	if err := opt.Dump(buf, "json"); err != nil {
+		t.Errorf("opt.Dump: %v", err)
+		return
+	}
+	equals(t, buf.String(), `{
+  "delay": "1s",
+  "header": [
+    "a",
+    "b"
+  ],
+  "port": 8080,
+  "token": "********"
+}
+`)
+
+	buf.Reset()
+	if err := opt.Dump(buf, "yaml"); err != nil {
+		t.Errorf("opt.Dump: %v", err)
+		return
+	}
+	equals(t, buf.String(), "delay: \"1s\"\nheader:\n  - \"a\"\n  - \"b\"\nport: 8080\ntoken: \"********\"\n")
+
+	if err := opt.Dump(buf, "toml"); err == nil {
+		t.Error("opt.Dump(buf, \"toml\") = nil, want error")
+	}
+}
+
+func TestChanged(t *testing.T) {
+	pos, opt := Args()
+	opt.Int('p', "port", 8080, "port to listen on")
+	opt.String('H', "host", "localhost", "host to listen on")
+	opt.Switch('v', "verbose", "be verbose")
+	parser := NewParser(pos, opt)
+
+	if err := parser.Parse([]string{"-v", "--port=80"}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, opt.Changed("port"), true)
+	equals(t, opt.Changed("host"), false)
+	equals(t, opt.Changed("verbose"), true)
+
+	visited := []string{}
+	opt.Visit(func(long string, arg Argument) { visited = append(visited, long) })
+	equals(t, visited, []string{"port", "verbose"})
+
+	visited = visited[:0]
+	opt.VisitAll(func(long string, arg Argument) { visited = append(visited, long) })
+	equals(t, visited, []string{"host", "port", "verbose"})
+
+	if err := parser.Parse(nil); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, opt.Changed("port"), false)
+}
+
+func TestGoFlag(t *testing.T) {
+	fs := goflag.NewFlagSet("lib", goflag.ContinueOnError)
+	v := fs.Int("v", 0, "log verbosity")
+	dir := fs.String("log_dir", "", "log directory")
+	alsologtostderr := fs.Bool("alsologtostderr", false, "log to stderr")
+
+	pos, opt := Args()
+	name := opt.String('n', "name", "", "name")
+	opt.AddGoFlagSet(fs)
+	parser := NewParser(pos, opt)
+
+	args := []string{"-v", "2", "--log_dir", "/tmp", "--alsologtostderr", "-n", "foo"}
+	if err := parser.Parse(args); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, *v, 2)
+	equals(t, *dir, "/tmp")
+	equals(t, *alsologtostderr, true)
+	equals(t, *name, "foo")
+
+	exported := opt.GoFlagSet("test")
+	if err := exported.Parse([]string{"-n", "bar", "-name", "baz", "-v", "3"}); err != nil {
+		t.Errorf("exported.Parse: %v", err)
+		return
+	}
+	equals(t, *name, "baz")
+	equals(t, *v, 3)
+}
+
+func TestHostPortValue(t *testing.T) {
+	cases := []struct {
+		in, out, host, port string
+	}{
+		{"localhost:80", "localhost:80", "localhost", "80"},
+		{"example.com", "example.com:8080", "example.com", "8080"},
+		{"[::1]:443", "[::1]:443", "::1", "443"},
+		{"[::1]", "[::1]:8080", "::1", "8080"},
+		{"::1", "[::1]:8080", "::1", "8080"},
+		{":9000", ":9000", "", "9000"},
+	}
+
+	for _, tt := range cases {
+		value := NewHostPortValue("", "8080")
+		if err := value.Set(tt.in); err != nil {
+			t.Errorf("value.Set(%q): %v", tt.in, err)
+			continue
+		}
+		equals(t, value.String(), tt.out)
+		equals(t, value.Host(), tt.host)
+		equals(t, value.Port(), tt.port)
+	}
+
+	for _, in := range []string{"localhost:http", "localhost:70000", "[::1"} {
+		value := NewHostPortValue("", "8080")
+		if err := value.Set(in); err == nil {
+			t.Errorf("value.Set(%q) = nil, want error", in)
+		}
+	}
+
+	value := NewHostPortValue("", "")
+	if err := value.Set("localhost"); err == nil {
+		t.Error("value.Set(\"localhost\") = nil, want error")
+	}
+}
+
+func TestUUIDValue(t *testing.T) {
+	canonical := "123e4567-e89b-12d3-a456-426614174000"
+	for _, in := range []string{
+		canonical,
+		"123E4567-E89B-12D3-A456-426614174000",
+		"{123e4567-e89b-12d3-a456-426614174000}",
+		"123e4567e89b12d3a456426614174000",
+	} {
+		value := NewUUIDValue("")
+		if err := value.Set(in); err != nil {
+			t.Errorf("value.Set(%q): %v", in, err)
+			continue
+		}
+		equals(t, value.String(), canonical)
+	}
+
+	for _, in := range []string{
+		"123e4567-e89b-12d3-a456-42661417400",
+		"123e4567-e89b-12d3-a456-42661417400g",
+		"123e4567e-89b-12d3-a456-426614174000",
+		"{123e4567-e89b-12d3-a456-426614174000",
+	} {
+		value := NewUUIDValue("")
+		if err := value.Set(in); err == nil {
+			t.Errorf("value.Set(%q) = nil, want error", in)
+		}
+	}
+}
+
+func TestSemverValue(t *testing.T) {
+	value := NewSemverValue(Version{})
+	if err := value.Set("v1.2.3-rc.1+build.5"); err != nil {
+		t.Errorf("value.Set: %v", err)
+		return
+	}
+	equals(t, Version(*value), Version{1, 2, 3, "rc.1", "build.5"})
+	equals(t, value.String(), "1.2.3-rc.1+build.5")
+
+	for _, in := range []string{"1.2", "1.2.x", "01.2.3", "1.2.3-", "1.2.3-01", "1.2.3+a..b"} {
+		if err := value.Set(in); err == nil {
+			t.Errorf("value.Set(%q) = nil, want error", in)
+		}
+	}
+
+	order := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta",
+		"1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.1.0", "2.0.0",
+	}
+	for i := 1; i < len(order); i++ {
+		a, _ := ParseVersion(order[i-1])
+		b, _ := ParseVersion(order[i])
+		if !a.Less(b) || b.Less(a) {
+			t.Errorf("expected %s < %s", a, b)
+		}
+	}
+
+	a, _ := ParseVersion("1.0.0+a")
+	b, _ := ParseVersion("1.0.0+b")
+	equals(t, a.Compare(b), 0)
+}
+
+func TestFileModeValue(t *testing.T) {
+	cases := []struct {
+		in  string
+		out os.FileMode
+	}{
+		{"0755", 0755},
+		{"600", 0600},
+		{"u+x", 0744},
+		{"go-r", 0600},
+		{"a=rx,u+w", 0755},
+		{"+x", 0755},
+		{"1777", os.ModeSticky | 0777},
+		{"2755", os.ModeSetgid | 0755},
+		{"4755", os.ModeSetuid | 0755},
+	}
+
+	for _, tt := range cases {
+		value := NewFileModeValue(0644)
+		if err := value.Set(tt.in); err != nil {
+			t.Errorf("value.Set(%q): %v", tt.in, err)
+			continue
+		}
+		equals(t, os.FileMode(*value), tt.out)
+	}
+
+	equals(t, NewFileModeValue(0644).String(), "0644")
+	equals(t, NewFileModeValue(os.ModeSetgid|0755).String(), "2755")
+
+	value := NewFileModeValue(os.ModeSticky | 0777)
+	if err := value.Set("o-w"); err != nil {
+		t.Errorf("value.Set(%q): %v", "o-w", err)
+	}
+	equals(t, value.String(), "1775")
+
+	for _, in := range []string{"17777", "0999", "u+q", "z+r", "rw"} {
+		if err := NewFileModeValue(0644).Set(in); err == nil {
+			t.Errorf("value.Set(%q) = nil, want error", in)
+		}
+	}
+}
+
+func TestLevelValue(t *testing.T) {
+	cases := []struct {
+		in  string
+		out slog.Level
+	}{
+		{"debug", slog.LevelDebug},
+		{"INFO", slog.LevelInfo},
+		{"Warning", slog.LevelWarn},
+		{"warn", slog.LevelWarn},
+		{"error", slog.LevelError},
+		{"info+2", slog.LevelInfo + 2},
+		{"-4", slog.LevelDebug},
+		{"8", slog.LevelError},
+	}
+	for _, tt := range cases {
+		value := NewLevelValue(slog.LevelInfo)
+		if err := value.Set(tt.in); err != nil {
+			t.Errorf("value.Set(%q): %v", tt.in, err)
+			continue
+		}
+		equals(t, slog.Level(*value), tt.out)
+	}
+
+	if err := NewLevelValue(slog.LevelInfo).Set("verbose"); err == nil {
+		t.Error("value.Set(\"verbose\") = nil, want error")
+	}
+	equals(t, NewLevelValue(slog.LevelWarn).String(), "WARN")
+}
+
+func TestDelimit(t *testing.T) {
+	pos, opt := Args()
+	tags := opt.StringSlice('t', "tag", nil, "tags")
+	ports := opt.IntSlice('p', "port", nil, "ports")
+	opt.Delimit("tag", ',')
+	opt.Delimit("port", ':')
+	parser := NewParser(pos, opt)
+
+	args := []string{"--tag", `a,b\,c`, "-t", `d\\`, "--port=80:443", "-p", "8080"}
+	if err := parser.Parse(args); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, *tags, []string{"a", "b,c", `d\`})
+	equals(t, *ports, []int{80, 443, 8080})
+
+	if err := parser.Parse([]string{"--port", "80:http"}); err == nil {
+		t.Error("parser.Parse([]string{\"--port\", \"80:http\"}) = nil, want error")
+	}
+
+	var cfg struct{ Tag []string }
+	if err := opt.Decode(&cfg); err != nil {
+		t.Errorf("opt.Decode: %v", err)
+	}
+
+	panics(t, func() { opt.Delimit("missing", ',') })
+	opt.String('n', "name", "", "name")
+	panics(t, func() { opt.Delimit("name", ',') })
+}
+
+func TestStringSetValue(t *testing.T) {
+	pos, opt := Args()
+	exclude := opt.StringSet('x', "exclude", []string{"vendor"}, "paths to exclude")
+	parser := NewParser(pos, opt)
+
+	args := []string{"-x", "node_modules", "-x", "vendor", "--exclude", "node_modules", "-x", ".git"}
+	if err := parser.Parse(args); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, exclude.Slice(), []string{"vendor", "node_modules", ".git"})
+	equals(t, exclude.Len(), 3)
+	equals(t, exclude.Contains(".git"), true)
+	equals(t, exclude.Contains("src"), false)
+	equals(t, exclude.String(), "[vendor, node_modules, .git]")
+}
+
+func TestMapVar(t *testing.T) {
+	pos, opt := Args()
+	limits := opt.StringToInt('l', "limit", map[string]int{"cpu": 1}, "resource limits")
+	features := opt.StringToBool('f', "feature", nil, "feature toggles")
+	parser := NewParser(pos, opt)
+
+	args := []string{"--limit", "cpu=2,mem=4", "-l", "disk=10", "-f", "beta=true"}
+	if err := parser.Parse(args); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, *limits, map[string]int{"cpu": 2, "mem": 4, "disk": 10})
+	equals(t, *features, map[string]bool{"beta": true})
+	equals(t, opt.Args["limit"].Value.String(), "[cpu=2, disk=10, mem=4]")
+
+	err := parser.Parse([]string{"--limit", "cpu=2,mem=lots"})
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Errorf("expected %v to be a ParseError", err)
+		return
+	}
+	equals(t, perr.Err.Error(), "for key `mem`: `lots` cannot be interpreted as int")
+	equals(t, perr.Type, "stringToInt")
+
+	if err := parser.Parse([]string{"--limit", "cpu"}); err == nil {
+		t.Error("parser.Parse([]string{\"--limit\", \"cpu\"}) = nil, want error")
+	}
+}
+
+func TestResponseFiles(t *testing.T) {
+	root, err := ioutil.TempDir("", "flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	inner := filepath.Join(root, "inner.rsp")
+	outer := filepath.Join(root, "outer.rsp")
+	ioutil.WriteFile(inner, []byte("--name\nfoo bar\n"), 0644)
+	ioutil.WriteFile(outer, []byte("# options\n-v\n\n@"+inner+"\n"), 0644)
+
+	pos, opt := Args()
+	files := pos.Glob("files", "files")
+	verbose := opt.Switch('v', "verbose", "be verbose")
+	name := opt.String('n', "name", "", "name")
+	opt.ResponseFiles = true
+	parser := NewParser(pos, opt)
+
+	if err := parser.Parse([]string{"@" + outer, "a", "--", "@b"}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, *verbose, true)
+	equals(t, *name, "foo bar")
+	equals(t, *files, []string{"a", "@b"})
+
+	if err := parser.Parse([]string{"@" + filepath.Join(root, "missing")}); err == nil {
+		t.Error("expected error for missing response file")
+	}
+
+	loop := filepath.Join(root, "loop.rsp")
+	ioutil.WriteFile(loop, []byte("@"+loop+"\n"), 0644)
+	if _, err := ExpandResponseFiles([]string{"@" + loop}); err == nil {
+		t.Error("expected error for recursive response file")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	pos, opt := Args()
+	count := opt.Int('n', "max-count", 0, "maximum count")
+	opt.Normalize = DashNormalize
+	parser := NewParser(pos, opt)
+
+	if err := parser.Parse([]string{"--max_count", "3"}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, *count, 3)
+
+	if err := parser.Parse([]string{"--MAX-COUNT=4"}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, *count, 4)
+	equals(t, opt.Changed("max-count"), true)
+
+	panics(t, func() { opt.Int(0, "max_count", 0, "duplicate") })
+}
+
+func TestSlashFlags(t *testing.T) {
+	pos, opt := Args()
+	file := pos.String("file", "file")
+	verbose := opt.Switch('v', "verbose", "be verbose")
+	output := opt.String('o', "output", "", "output")
+	opt.SlashFlags = true
+	parser := NewParser(pos, opt)
+
+	if err := parser.Parse([]string{"/v", "/output:out.txt", "/usr/share/file"}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, *verbose, true)
+	equals(t, *output, "out.txt")
+	equals(t, *file, "/usr/share/file")
+
+	*verbose = false
+	if err := parser.Parse([]string{"/verbose", "/o", "a.txt", "--output=b.txt", "in"}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, *verbose, true)
+	equals(t, *output, "b.txt")
+
+	equals(t, parser.Parse([]string{"/?"}), ErrHelp)
+}
+
+func TestJSONValue(t *testing.T) {
+	root, err := ioutil.TempDir("", "flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	type config struct {
+		Name    string   `json:"name"`
+		Retries int      `json:"retries"`
+		Hosts   []string `json:"hosts"`
+	}
+
+	cfg := config{Retries: 3}
+	pos, opt := Args()
+	opt.JSON('c', "config", &cfg, "configuration")
+	parser := NewParser(pos, opt)
+	equals(t, opt.Args["config"].Value.String(), `{"name":"","retries":3,"hosts":null}`)
+
+	if err := parser.Parse([]string{"--config", `{"name": "foo", "hosts": ["a", "b"]}`}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, cfg, config{"foo", 3, []string{"a", "b"}})
+
+	name := filepath.Join(root, "config.json")
+	ioutil.WriteFile(name, []byte(`{"retries": 5}`), 0644)
+	if err := parser.Parse([]string{"-c", "@" + name}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, cfg.Retries, 5)
+
+	if err := parser.Parse([]string{"-c", `{"name": 42}`}); err == nil {
+		t.Error("expected error for mistyped JSON")
+	}
+}
+
+func TestByteValues(t *testing.T) {
+	b64 := NewBase64Value(nil)
+	for _, s := range []string{"AAEC/w==", "AAEC/w", "AAEC_w==", "AAEC_w"} {
+		if err := b64.Set(s); err != nil {
+			t.Errorf("b64.Set(%q): %v", s, err)
+			continue
+		}
+		equals(t, *b64.p, []byte{0, 1, 2, 255})
+	}
+	equals(t, b64.String(), "AAEC/w==")
+	if err := b64.Set("!!"); err == nil {
+		t.Error("expected error for invalid base64")
+	}
+	b64.Size = 3
+	if err := b64.Set("AAEC/w=="); err == nil {
+		t.Error("expected error for wrong length")
+	}
+
+	hx := NewHexValue(nil)
+	for _, s := range []string{"deadbeef", "0xDEADBEEF"} {
+		if err := hx.Set(s); err != nil {
+			t.Errorf("hx.Set(%q): %v", s, err)
+			continue
+		}
+		equals(t, *hx.p, []byte{0xde, 0xad, 0xbe, 0xef})
+	}
+	equals(t, hx.String(), "deadbeef")
+	if err := hx.Set("abc"); err == nil {
+		t.Error("expected error for odd length hex")
+	}
+
+	pos, opt := Args()
+	key := opt.Hex('k', "key", 4, "key")
+	if err := NewParser(pos, opt).Parse([]string{"-k", "00112233"}); err != nil {
+		t.Fatalf("parser.Parse: %v", err)
+	}
+	equals(t, *key, []byte{0x00, 0x11, 0x22, 0x33})
+	if err := NewParser(pos, opt).Parse([]string{"-k", "0011"}); err == nil {
+		t.Error("expected error for short key")
+	}
+}
+
+func TestTextValue(t *testing.T) {
+	addr := netip.MustParseAddr("127.0.0.1")
+	stamp := time.Time{}
+	pos, opt := Args()
+	opt.Text('a', "addr", &addr, "address to bind")
+	pos.Text("since", &stamp, "start time")
+	equals(t, opt.Args["addr"].Value.String(), "127.0.0.1")
+	equals(t, TypeName(opt.Args["addr"].Value), "addr")
+	equals(t, TypeName(pos.Args["since"].Value), "time")
+
+	parser := NewParser(pos, opt)
+	if err := parser.Parse([]string{"-a", "::1", "2024-01-02T03:04:05Z"}); err != nil {
+		t.Fatalf("parser.Parse: %v", err)
+	}
+	equals(t, addr, netip.IPv6Loopback())
+	equals(t, stamp, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	if err := parser.Parse([]string{"-a", "localhost", "2024-01-02T03:04:05Z"}); err == nil {
+		t.Error("expected error for invalid address")
+	}
+}
+
+func TestBigValues(t *testing.T) {
+	pos, opt := Args()
+	amount := opt.BigInt('a', "amount", big.NewInt(1), "token amount")
+	scale := pos.BigFloat("scale", "scale factor")
+	equals(t, opt.Args["amount"].Value.String(), "1")
+	equals(t, TypeName(opt.Args["amount"].Value), "bigint")
+
+	parser := NewParser(pos, opt)
+	if err := parser.Parse([]string{"-a", "123456789012345678901234567890", "0.1"}); err != nil {
+		t.Fatalf("parser.Parse: %v", err)
+	}
+	equals(t, amount.String(), "123456789012345678901234567890")
+	equals(t, scale.Prec(), uint(BigFloatPrec))
+	equals(t, pos.Args["scale"].Value.String(), "0.1")
+
+	if err := parser.Parse([]string{"-a", "0xff_ff", "1e400"}); err != nil {
+		t.Fatalf("parser.Parse: %v", err)
+	}
+	equals(t, amount.Int64(), int64(0xffff))
+	equals(t, pos.Args["scale"].Value.String(), "1e+400")
+
+	if err := parser.Parse([]string{"-a", "1.5", "x"}); err == nil {
+		t.Error("expected error for invalid numbers")
+	}
+
+	value := NewBigIntValue(big.NewInt(42))
+	equals(t, value.Set("4x").Error(), "`4x` cannot be interpreted as *big.Int")
+	equals(t, value.String(), "42")
+	float := NewBigFloatValue(big.NewFloat(0.5))
+	equals(t, float.Set("x").Error(), "`x` cannot be interpreted as *big.Float")
+	equals(t, float.String(), "0.5")
+}
+
+func TestTimezoneValue(t *testing.T) {
+	pos, opt := Args()
+	tz := opt.Timezone('z', "timezone", nil, "report time zone")
+	equals(t, opt.Args["timezone"].Value.String(), "UTC")
+
+	parser := NewParser(pos, opt)
+	if err := parser.Parse([]string{"-z", "utc"}); err != nil {
+		t.Fatalf("parser.Parse: %v", err)
+	}
+	equals(t, tz.Location, time.UTC)
+
+	if _, err := time.LoadLocation("Asia/Tokyo"); err == nil {
+		if err := parser.Parse([]string{"-z", "Asia/Tokyo"}); err != nil {
+			t.Fatalf("parser.Parse: %v", err)
+		}
+		equals(t, tz.String(), "Asia/Tokyo")
+		_, offset := time.Date(2024, 1, 1, 0, 0, 0, 0, tz.Location).Zone()
+		equals(t, offset, 9*60*60)
+	}
+
+	err := parser.Parse([]string{"-z", "Mars/Olympus"})
+	if err == nil || !strings.Contains(err.Error(), "IANA") {
+		t.Errorf("expected IANA hint in error, got %v", err)
+	}
+}
+
+func TestDateValue(t *testing.T) {
+	value := NewDateValue(Date{})
+	equals(t, value.String(), "")
+	if err := value.Set("2024-02-29"); err != nil {
+		t.Fatalf("value.Set: %v", err)
+	}
+	equals(t, Date(*value), Date{2024, time.February, 29})
+	equals(t, value.String(), "2024-02-29")
+	equals(t, Date(*value).In(time.UTC), time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC))
+	for _, in := range []string{"2023-02-29", "2024-1-1", "2024-01-01T00:00:00Z", ""} {
+		if err := value.Set(in); err == nil {
+			t.Errorf("value.Set(%q) = nil, want error", in)
+		}
+	}
+
+	pos, opt := Args()
+	period := opt.DateRange('p', "period", DateRange{}, "query period")
+	parser := NewParser(pos, opt)
+	if err := parser.Parse([]string{"-p", "2024-01-01..2024-02-01"}); err != nil {
+		t.Fatalf("parser.Parse: %v", err)
+	}
+	equals(t, *period, DateRange{Date{2024, 1, 1}, Date{2024, 2, 1}})
+	equals(t, period.Contains(Date{2024, 1, 31}), true)
+	equals(t, period.Contains(Date{2024, 2, 2}), false)
+	equals(t, opt.Args["period"].Value.String(), "2024-01-01..2024-02-01")
+
+	for _, in := range []string{"2024-02-01..2024-01-01", "2024-01-01", "2024-01-01..", "..2024-01-01"} {
+		if err := parser.Parse([]string{"-p", in}); err == nil {
+			t.Errorf("parser.Parse(%q) = nil, want error", in)
+		}
+	}
+}
+
+func TestPortValue(t *testing.T) {
+	pos, opt := Args()
+	port := opt.Port('p', "port", 8080, "port to listen on")
+	equals(t, opt.Args["port"].Value.String(), "8080")
+	parser := NewParser(pos, opt)
+	if err := parser.Parse([]string{"-p", "443"}); err != nil {
+		t.Fatalf("parser.Parse: %v", err)
+	}
+	equals(t, *port, uint16(443))
+
+	for _, in := range []string{"0", "65536", "-1", "http"} {
+		err := parser.Parse([]string{"--port=" + in})
+		if err == nil || !strings.Contains(err.Error(), "from 1 to 65535") {
+			t.Errorf("parser.Parse(%q) = %v, want range error", in, err)
+		}
+	}
+
+	value := NewPortValue(0)
+	value.Unprivileged = true
+	if err := value.Set("80"); err == nil || !strings.Contains(err.Error(), "from 1024 to 65535") {
+		t.Errorf("value.Set(\"80\") = %v, want range error", err)
+	}
+	if err := value.Set("1024"); err != nil {
+		t.Errorf("value.Set(\"1024\"): %v", err)
+	}
+}
+
+func TestRatioValue(t *testing.T) {
+	pos, opt := Args()
+	sample := opt.Ratio('s', "sample", 1, "sampling ratio")
+	throttle := opt.Percentage('t', "throttle", 0.5, "throttling percentage")
+	equals(t, opt.Args["sample"].Value.String(), "1")
+	equals(t, opt.Args["throttle"].Value.String(), "50%")
+	equals(t, TypeName(opt.Args["throttle"].Value), "percentage")
+
+	parser := NewParser(pos, opt)
+	if err := parser.Parse([]string{"-s", "75%", "-t", "25"}); err != nil {
+		t.Fatalf("parser.Parse: %v", err)
+	}
+	equals(t, *sample, 0.75)
+	equals(t, *throttle, 0.25)
+
+	if err := parser.Parse([]string{"-s", "0.1", "-t", "10%"}); err != nil {
+		t.Fatalf("parser.Parse: %v", err)
+	}
+	equals(t, *sample, 0.1)
+	equals(t, *throttle, 0.1)
+
+	for _, args := range [][]string{{"-s", "75"}, {"-s", "101%"}, {"-t", "150"}, {"-s", "half"}} {
+		if err := parser.Parse(args); err == nil {
+			t.Errorf("parser.Parse(%q) = nil, want error", args)
+		}
+	}
+}
+
+func TestTemplateValue(t *testing.T) {
+	root, err := ioutil.TempDir("", "flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	data := struct {
+		Name string
+		Size int
+	}{"foo", 42}
+
+	pos, opt := Args()
+	format := opt.Template('f', "format", "{{.Name}}", "output format")
+	equals(t, opt.Args["format"].Value.String(), "{{.Name}}")
+	parser := NewParser(pos, opt)
+	if err := parser.Parse([]string{"-f", "{{.Name}}\t{{.Size}}"}); err != nil {
+		t.Fatalf("parser.Parse: %v", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := format.Execute(buf, data); err != nil {
+		t.Fatalf("format.Execute: %v", err)
+	}
+	equals(t, buf.String(), "foo\t42")
+
+	name := filepath.Join(root, "format.tmpl")
+	ioutil.WriteFile(name, []byte("{{.Size}} {{.Name}}"), 0644)
+	if err := parser.Parse([]string{"-f", "@" + name}); err != nil {
+		t.Fatalf("parser.Parse: %v", err)
+	}
+	buf.Reset()
+	format.Execute(buf, data)
+	equals(t, buf.String(), "42 foo")
+
+	if err := parser.Parse([]string{"-f", "{{.Name"}); err == nil {
+		t.Error("expected error for invalid template")
+	}
+	panics(t, func() { NewTemplateValue("{{") })
+}
+
+func TestFetchValue(t *testing.T) {
+	root, err := ioutil.TempDir("", "flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/manifest":
+			io.WriteString(w, "remote manifest")
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	local := filepath.Join(root, "manifest")
+	ioutil.WriteFile(local, []byte("local manifest"), 0644)
+
+	read := func(v *FetchValue) (string, error) {
+		defer v.Close()
+		r, err := v.Reader()
+		if err != nil {
+			return "", err
+		}
+		p, err := ioutil.ReadAll(r)
+		return string(p), err
+	}
+
+	value := NewFetchValue(nil)
+	for in, out := range map[string]string{local: "local manifest", server.URL + "/manifest": "remote manifest"} {
+		if err := value.Set(in); err != nil {
+			t.Errorf("value.Set(%q): %v", in, err)
+			continue
+		}
+		equals(t, value.String(), in)
+		s, err := read(value)
+		if err != nil {
+			t.Errorf("reading %q: %v", in, err)
+		}
+		equals(t, s, out)
+	}
+
+	for _, in := range []string{filepath.Join(root, "missing"), root, "http://"} {
+		if err := value.Set(in); err == nil {
+			t.Errorf("value.Set(%q) = nil, want error", in)
+		}
+	}
+
+	value.Set(server.URL + "/missing")
+	if _, err := read(value); err == nil {
+		t.Error("expected error for missing resource")
+	}
+
+	value.MaxSize = 4
+	value.Set(server.URL + "/manifest")
+	if _, err := read(value); err == nil {
+		t.Error("expected error for oversized resource")
+	}
+	value.Set(local)
+	if _, err := read(value); err == nil {
+		t.Error("expected error for oversized file")
+	}
+
+	value.MaxSize, value.Timeout = 0, 50*time.Millisecond
+	value.Set(server.URL + "/slow")
+	if _, err := read(value); err == nil {
+		t.Error("expected error for timeout")
+	}
+}
+
+func TestDecompressValue(t *testing.T) {
+	root, err := ioutil.TempDir("", "flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	gz := &bytes.Buffer{}
+	w := gzip.NewWriter(gz)
+	io.WriteString(w, "gzip content")
+	w.Close()
+
+	zst := &bytes.Buffer{}
+	e, _ := zstd.NewWriter(zst)
+	io.WriteString(e, "zstd content")
+	e.Close()
+
+	emptyBzip2 := []byte{'B', 'Z', 'h', '9', 0x17, 0x72, 0x45, 0x38, 0x50, 0x90, 0, 0, 0, 0}
+
+	files := map[string][]byte{
+		"plain.txt":   []byte("plain content"),
+		"data.gz":     gz.Bytes(),
+		"data.gzip":   gz.Bytes(),
+		"data.zst":    zst.Bytes(),
+		"data":        zst.Bytes(),
+		"data.bz2":    emptyBzip2,
+		"invalid.zst": []byte("plain content"),
+	}
+	for name, p := range files {
+		ioutil.WriteFile(filepath.Join(root, name), p, 0644)
+	}
+
+	cases := map[string]string{
+		"plain.txt": "plain content",
+		"data.gz":   "gzip content",
+		"data.gzip": "gzip content",
+		"data.zst":  "zstd content",
+		"data":      "zstd content",
+		"data.bz2":  "",
+	}
+	value := NewDecompressValue(nil)
+	for name, out := range cases {
+		if err := value.Set(filepath.Join(root, name)); err != nil {
+			t.Errorf("value.Set(%q): %v", name, err)
+			continue
+		}
+		r, err := value.Reader()
+		if err != nil {
+			t.Errorf("value.Reader() for %q: %v", name, err)
+			continue
+		}
+		p, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Errorf("reading %q: %v", name, err)
+		}
+		equals(t, string(p), out)
+		value.Close()
+	}
+
+	value.Set(filepath.Join(root, "data.gz"))
+	p, err := ioutil.ReadAll(value)
+	if err != nil {
+		t.Errorf("reading the value: %v", err)
+	}
+	equals(t, string(p), "gzip content")
+	value.Close()
+
+	value.Set(filepath.Join(root, "invalid.zst"))
+	if r, err := value.Reader(); err == nil {
+		if _, err := ioutil.ReadAll(r); err == nil {
+			t.Error("expected error for corrupt zstd file")
+		}
+	}
+	value.Close()
+}
+
+func TestTempValues(t *testing.T) {
+	root, err := ioutil.TempDir("", "flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	dir, file := "", ""
+	cmd := func(ctx *Context) error {
+		pos, opt := Args()
+		work := opt.TempDir('w', "workdir", "work-", "parent of the working directory")
+		stage := opt.TempFile('s', "stage", "stage-*.txt", "parent of the staging file")
+		if err := ctx.Parse(pos, opt); err != nil {
+			return err
+		}
+		path, err := work.Path()
+		if err != nil {
+			return err
+		}
+		f, err := stage.Writer()
+		if err != nil {
+			return err
+		}
+		dir, file = path, f.Name()
+		io.WriteString(f, "staged")
+		return ioutil.WriteFile(filepath.Join(path, "out"), nil, 0644)
+	}
+
+	ctx := &Context{Name: "test", Args: []string{"-w", root, "-s", root}, Stderr: ioutil.Discard}
+	equals(t, Exec(ctx, cmd), ExitSuccess)
+	equals(t, filepath.Dir(dir), root)
+	equals(t, filepath.Dir(file), root)
+	equals(t, strings.HasPrefix(filepath.Base(dir), "work-"), true)
+	equals(t, strings.HasSuffix(file, ".txt"), true)
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed", dir)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed", file)
+	}
+
+	ctx = &Context{Name: "test", Stderr: ioutil.Discard}
+	equals(t, Exec(ctx, cmd), ExitSuccess)
+	equals(t, filepath.Dir(dir), filepath.Clean(os.TempDir()))
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed", dir)
+	}
+
+	value := NewTempDirValue("")
+	value.Lazy = true
+	if err := value.Set(root); err != nil {
+		t.Fatalf("value.Set: %v", err)
+	}
+	equals(t, value.String(), root)
+	if err := value.Set(filepath.Join(root, "missing")); err == nil {
+		t.Error("expected error for missing parent directory")
+	}
+}
+
+func TestInputs(t *testing.T) {
+	root, err := ioutil.TempDir("", "flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	stdin, err := os.Create(filepath.Join(root, "stdin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	name := filepath.Join(root, "a.txt")
+	ioutil.WriteFile(name, []byte("a"), 0644)
+
+	pos := newPositional()
+	files := pos.Inputs("files", "files to filter")
+	value := pos.Args["files"].Value.(*InputSliceValue)
+	value.Stdin = stdin
+	parser := NewParser(pos, nil)
+
+	if err := parser.Parse([]string{name}); err != nil {
+		t.Fatalf("parser.Parse: %v", err)
+	}
+	equals(t, len(*files), 1)
+	equals(t, (*files)[0].Name(), name)
+	value.Close()
+
+	*files = nil
+	if err := parser.Parse(nil); err != nil {
+		t.Fatalf("parser.Parse: %v", err)
+	}
+	equals(t, *files, []*os.File{stdin})
+	value.Close()
+	if _, err := stdin.Stat(); err != nil {
+		t.Errorf("expected stdin to be left open: %v", err)
+	}
+
+	equals(t, (&Context{Stdin: strings.NewReader("")}).Piped(), true)
+	equals(t, (&Context{Stdin: stdin}).Piped(), true)
+}
+
+func TestConfirm(t *testing.T) {
+	root, err := ioutil.TempDir("", "flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	deleted := false
+	cmd := func(ctx *Context) error {
+		pos, opt := Args()
+		opt.Yes()
+		opt.Confirm('f', "force", "do not ask before deleting")
+		if err := ctx.Parse(pos, opt); err != nil {
+			return err
+		}
+		ok, err := ctx.Confirm("Delete 42 items?")
+		if err != nil {
+			return err
+		}
+		deleted = ok
+		return nil
+	}
+
+	cases := []struct {
+		args    []string
+		stdin   string
+		deleted bool
+		prompt  string
+	}{
+		{nil, "y\n", true, "Delete 42 items? [y/N]: "},
+		{nil, "Yes\n", true, "Delete 42 items? [y/N]: "},
+		{nil, "n\n", false, "Delete 42 items? [y/N]: "},
+		{nil, "", false, "Delete 42 items? [y/N]: "},
+		{[]string{"--yes"}, "", true, ""},
+		{[]string{"-f"}, "", true, ""},
+	}
+	for _, tt := range cases {
+		deleted = false
+		stderr := &bytes.Buffer{}
+		ctx := &Context{Name: "rm", Args: tt.args, Stdin: strings.NewReader(tt.stdin), Stderr: stderr}
+		equals(t, Exec(ctx, cmd), ExitSuccess)
+		equals(t, deleted, tt.deleted)
+		equals(t, stderr.String(), tt.prompt)
+	}
+
+	stdin, err := os.Create(filepath.Join(root, "stdin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	stderr := &bytes.Buffer{}
+	ctx := &Context{Name: "rm", Stdin: stdin, Stderr: stderr}
+	equals(t, Exec(ctx, cmd), ExitFailure)
+	equals(t, stderr.String(), fmt.Sprintf("%v: give --force to proceed\n", ErrNotConfirmed))
+}
+
+func TestListCommands(t *testing.T) {
+	defer os.Setenv("COLUMNS", os.Getenv("COLUMNS"))
+	os.Setenv("COLUMNS", "40")
+
+	noop := func(ctx *Context) error { return nil }
+	prog := NewProgram()
+	prog.Add("push", "update remote refs along with associated objects", noop)
+	prog.Add("commit", "record changes", noop)
+	prog.Add("a-very-long-command-name-indeed", "too long", noop)
+	prog.Add("commit", "record changes to the repository", noop)
+
+	equals(t, prog.Order, []string{"push", "commit", "a-very-long-command-name-indeed"})
+	equals(t, ListCommands(*prog), strings.Join([]string{
+		"available commands:",
+		"  a-very-long-command-name-indeed",
+		"          too long",
+		"  commit  record changes to the",
+		"          repository",
+		"  push    update remote refs along with",
+		"          associated objects",
+	}, "\n"))
+
+	prog = NewProgram()
+	prog.KeepOrder = true
+	prog.Add("push", "update", noop)
+	prog.Add("commit", "record", noop)
+	prog.Map["add"] = CommandDescription{"stage", noop, nil, Doc{}}
+	equals(t, ListCommands(*prog), strings.Join([]string{
+		"available commands:",
+		"  push    update",
+		"  commit  record",
+		"  add     stage",
+	}, "\n"))
+}
+
+func TestDoc(t *testing.T) {
+	noop := func(ctx *Context) error {
+		pos, opt := Args()
+		pos.String("pattern", "pattern to search for")
+		return ctx.Parse(pos, opt)
+	}
+	prog := NewProgram()
+	prog.Doc = Doc{
+		Long:     "Tool manages things.",
+		Synopsis: "<command> [<args>]",
+	}
+	prog.Add("grep", "search for a pattern", noop)
+	prog.Describe("grep", Doc{
+		Long: "Grep searches the files for lines matching the pattern.",
+		Examples: []Example{
+			{"search for TODO comments", "tool grep TODO"},
+			{"", "tool grep -- -v"},
+		},
+	})
+	panics(t, func() { prog.Describe("missing", Doc{}) })
+
+	buf := &bytes.Buffer{}
+	ctx := &Context{Name: "tool", Desc: "a tool", Args: []string{"--help"}, Stdout: buf}
+	equals(t, Exec(ctx, prog.Compile()), ExitSuccess)
+	equals(t, buf.String(), strings.Join([]string{
+		"tool: a tool",
+		"usage: tool <command> [<args>]",
+		"",
+		"Tool manages things.",
+		"",
+		ListCommands(*prog),
+		"",
+	}, "\n"))
+
+	buf.Reset()
+	ctx = &Context{Name: "tool", Args: []string{"grep", "--help"}, Stdout: buf}
+	equals(t, Exec(ctx, prog.Compile()), ExitSuccess)
+	pos := newPositional()
+	pos.String("pattern", "pattern to search for")
+	equals(t, buf.String(), strings.Join([]string{
+		"usage: tool grep " + Usage(pos, newOptional()),
+		"",
+		"Grep searches the files for lines matching the pattern.",
+		Help(pos, newOptional()),
+		"",
+		"examples:",
+		"  # search for TODO comments",
+		"  $ tool grep TODO",
+		"",
+		"  $ tool grep -- -v",
+		"",
+	}, "\n"))
+}
+
+func TestMessages(t *testing.T) {
+	defer func() { Messages = map[string]string{} }()
+	Messages["unknown command name `%s`"] = "不明なコマンド `%s`"
+	Messages["available commands:"] = "利用可能なコマンド:"
+	Messages["optional arguments:"] = "オプション引数:"
+	Messages["unknown flag `%s`"] = "不明なフラグ `%s`"
+
+	prog := NewProgram()
+	prog.Add("run", "run it", func(ctx *Context) error {
+		pos, opt := Args()
+		opt.Switch('v', "verbose", "verbose output")
+		return ctx.Parse(pos, opt)
+	})
+
+	stderr := &bytes.Buffer{}
+	ctx := &Context{Name: "tool", Args: []string{"walk"}, Stderr: stderr}
+	equals(t, Exec(ctx, prog.Compile()), ExitUsage)
+	equals(t, stderr.String(), "不明なコマンド `walk`\n")
+	equals(t, strings.HasPrefix(ListCommands(*prog), "利用可能なコマンド:"), true)
+
+	stderr.Reset()
+	ctx = &Context{Name: "tool", Args: []string{"run", "--quiet"}, Stderr: stderr}
+	equals(t, Exec(ctx, prog.Compile()), ExitUsage)
+	equals(t, strings.HasPrefix(stderr.String(), "不明なフラグ `--quiet`\n"), true)
+	equals(t, strings.Contains(Help(nil, newOptional()), "オプション引数:"), true)
+
+	Messages["`%s` cannot be interpreted as %T"] = "`%s` は %T として解釈できません"
+	err := NewIntValue(0).Set("x")
+	equals(t, err.Error(), "`x` は int として解釈できません")
+
+	Messages = map[string]string{}
+	listed := map[string]bool{}
+	for _, format := range MessageFormats {
+		equals(t, msg(format), format)
+		listed[format] = true
+	}
+
+	// Every format given to msg must be listed.
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	re := regexp.MustCompile(`msg\(("(?:[^"\\]|\\.)*")\)`)
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		p, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range re.FindAllSubmatch(p, -1) {
+			format, err := strconv.Unquote(string(m[1]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !listed[format] {
+				t.Errorf("%s: format %q is not listed in MessageFormats", file, format)
+			}
+		}
+	}
+}
+
+func TestSuggestFlags(t *testing.T) {
+	pos, opt := Args()
+	opt.Int('p', "port", 80, "port")
+	opt.String('P', "path", "", "path")
+	opt.Switch('v', "verbose", "verbose output")
+	parser := NewParser(pos, opt)
+
+	cases := []struct {
+		in          string
+		suggestions []string
+		message     string
+	}{
+		{"--prot", []string{"--port"}, "unknown flag `--prot`, did you mean `--port`?"},
+		{"--pat", []string{"--path"}, "unknown flag `--pat`, did you mean `--path`?"},
+		{"--verbos", []string{"--verbose"}, "unknown flag `--verbos`, did you mean `--verbose`?"},
+		{"--pot", []string{"--port"}, "unknown flag `--pot`, did you mean `--port`?"},
+		{"--x", []string{}, "unknown flag `--x`"},
+		{"--quiet", []string{}, "unknown flag `--quiet`"},
+	}
+	for _, tt := range cases {
+		err := parser.Parse([]string{tt.in})
+		var e *ParseError
+		if !errors.As(err, &e) {
+			t.Errorf("parser.Parse(%q) = %v, want ParseError", tt.in, err)
+			continue
+		}
+		equals(t, e.Suggestions, tt.suggestions)
+		equals(t, e.Error(), tt.message)
+	}
+
+	equals(t, distance("", "abc"), 3)
+	equals(t, distance("port", "prot"), 1)
+	equals(t, distance("kitten", "sitting"), 3)
+}
+
+func TestRunArgs(t *testing.T) {
+	cmd := func(ctx *Context) error {
+		pos, opt := Args()
+		count := pos.Int("count", "number of items")
+		if err := ctx.Parse(pos, opt); err != nil {
+			return err
+		}
+		fmt.Fprintln(ctx.Stderr, "discarded")
+		if *count < 0 {
+			return Exit(3, errors.New("negative count"))
+		}
+		return nil
+	}
+
+	code, err := RunArgs("test", "", []string{"1"}, cmd)
+	equals(t, code, ExitSuccess)
+	equals(t, err, nil)
+
+	code, err = RunArgs("test", "", []string{"--", "-1"}, cmd)
+	equals(t, code, 3)
+	equals(t, err.Error(), "negative count")
+
+	code, err = RunArgs("test", "", []string{"one"}, cmd)
+	equals(t, code, ExitUsage)
+	var e *ParseError
+	equals(t, errors.As(err, &e), true)
+
+	stdout := &bytes.Buffer{}
+	ctx := &Context{Name: "test", Args: []string{"--help"}, Stdout: stdout}
+	code, err = ExecArgs(ctx, cmd)
+	equals(t, code, ExitSuccess)
+	equals(t, errors.Is(err, ErrHelp), true)
+	equals(t, strings.HasPrefix(stdout.String(), "usage: test"), true)
+}
+
+func TestContextValues(t *testing.T) {
+	type key string
+
+	ctx := &Context{}
+	equals(t, ctx.Value(key("db")), nil)
+	ctx.SetValue(key("db"), "postgres")
+	equals(t, ctx.Value(key("db")), "postgres")
+
+	order := []string{}
+	logger := func(name string) Middleware {
+		return func(cmd Command) Command {
+			return func(ctx *Context) error {
+				order = append(order, name)
+				ctx.SetValue(key("logger"), name)
+				return cmd(ctx)
+			}
+		}
+	}
+
+	sub := NewProgram()
+	sub.Add("show", "show it", func(ctx *Context) error {
+		fmt.Fprintln(ctx.Stdout, ctx.Value(key("db")), ctx.Value(key("logger")))
+		return nil
+	})
+	prog := NewProgram()
+	prog.Use(logger("outer"), logger("inner"))
+	prog.AddProgram("config", "manage config", sub)
+
+	buf := &bytes.Buffer{}
+	ctx = &Context{Name: "tool", Args: []string{"config", "show"}, Stdout: buf}
+	ctx.SetValue(key("db"), "sqlite")
+	equals(t, Exec(ctx, prog.Compile()), ExitSuccess)
+	equals(t, buf.String(), "sqlite inner\n")
+	equals(t, order, []string{"outer", "inner"})
+	equals(t, ctx.Value(key("logger")), nil)
+}
+
+func TestProgramConcurrency(t *testing.T) {
+	prog := NewProgram()
+	cmd := prog.Compile()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("cmd%d", i)
+			prog.Add(name, "a command", func(ctx *Context) error { return nil })
+			ctx := &Context{Name: "test", Args: []string{name}, Stderr: ioutil.Discard}
+			equals(t, Exec(ctx, cmd), ExitSuccess)
+			ListCommands(prog.snapshot())
+		}(i)
+	}
+	wg.Wait()
+	equals(t, len(prog.Map), 8)
+	equals(t, len(prog.Order), 8)
+}
+
+func TestInspect(t *testing.T) {
+	sub := NewProgram()
+	sub.Add("add", "add a remote", func(ctx *Context) error {
+		pos, opt := Args()
+		pos.String("name", "name of the remote")
+		pos.Register("urls", NewStringSliceValue(nil), "urls of the remote")
+		opt.Switch('f', "fetch", "fetch after adding")
+		opt.String(0, "token", "abc", "access token")
+		opt.Secret("token")
+		if err := ctx.Parse(pos, opt); err != nil {
+			return err
+		}
+		panic("command ran while inspecting")
+	})
+	prog := NewProgram()
+	prog.Doc.Long = "Tool manages things."
+	prog.AddProgram("remote", "manage remotes", sub)
+	prog.Add("status", "show status", func(ctx *Context) error {
+		return ctx.Parse(nil, nil)
+	})
+
+	info := prog.Inspect("git", "version control")
+	equals(t, info.Name, "git")
+	equals(t, info.Doc.Long, "Tool manages things.")
+	equals(t, len(info.Commands), 2)
+	remote := info.Commands[0]
+	equals(t, remote.Name, "git remote")
+	equals(t, remote.Desc, "manage remotes")
+	equals(t, len(remote.Commands), 1)
+	equals(t, remote.Commands[0], CommandInfo{
+		Name: "git remote add",
+		Desc: "add a remote",
+		Positionals: []ArgumentInfo{
+			{"name", "", "string", "", "name of the remote", false},
+			{"urls", "", "stringslice", "[]", "urls of the remote", true},
+		},
+		Optionals: []ArgumentInfo{
+			{"fetch", "f", "bool", "false", "fetch after adding", false},
+			{"token", "", "string", Masked, "access token", false},
+		},
+	})
+	status := info.Commands[1]
+	equals(t, status.Name, "git status")
+	equals(t, status.Positionals, []ArgumentInfo{})
+	equals(t, status.Commands, []CommandInfo(nil))
+
+	info = Inspect("push", "push changes", func(ctx *Context) error {
+		if ctx.Context().Err() == nil {
+			t.Error("context should be canceled while inspecting")
+		}
+		opt := newOptional()
+		opt.Switch('f', "force", "force the push")
+		ctx.Parse(nil, opt)
+		panic("command ran past Parse while inspecting")
+	})
+	equals(t, len(info.Optionals), 1)
+}
+
+func TestDescribeJSON(t *testing.T) {
+	prog := NewProgram()
+	prog.Add("serve", "start the server", func(ctx *Context) error {
+		pos, opt := Args()
+		opt.Int('p', "port", 8080, "port to listen on")
+		return ctx.Parse(pos, opt)
+	})
+	prog.Describe("serve", Doc{Examples: []Example{{"", "tool serve -p 80"}}})
+
+	p, err := DescribeJSON("tool", prog)
+	if err != nil {
+		t.Fatalf("DescribeJSON: %v", err)
+	}
+	equals(t, string(p), `{
+  "schema": 1,
+  "name": "tool",
+  "desc": "",
+  "doc": {},
+  "commands": [
+    {
+      "name": "tool serve",
+      "desc": "start the server",
+      "doc": {
+        "examples": [
+          {
+            "command": "tool serve -p 80"
+          }
+        ]
+      },
+      "optionals": [
+        {
+          "name": "port",
+          "short": "p",
+          "type": "int",
+          "default": "8080",
+          "usage": "port to listen on"
+        }
+      ]
+    }
+  ]
+}
+`)
+	q, _ := DescribeJSON("tool", prog)
+	equals(t, q, p)
+}
+
+func TestHelpDefaults(t *testing.T) {
+	pos, opt := Args()
+	opt.Int('p', "port", 8080, "port to listen on")
+	opt.String(0, "token", "abc", "access token")
+	opt.String(0, "name", "", "name of the server")
+	opt.String(0, "seed", "42", "random seed")
+	opt.Switch('v', "verbose", "verbose output")
+	opt.StringSlice(0, "tag", nil, "tags")
+	opt.Secret("token")
+	opt.HideDefault("seed")
+
+	if err := NewParser(pos, opt).Parse([]string{"--port", "9000", "--name", "foo"}); err != nil {
+		t.Fatalf("parser.Parse: %v", err)
+	}
+	help := Help(pos, opt)
+	equals(t, strings.Contains(help, "port to listen on (default: 8080)"), true)
+	equals(t, strings.Contains(help, "name of the server (default"), false)
+	equals(t, strings.Contains(help, "verbose output (default"), false)
+	equals(t, strings.Contains(help, "tags (default"), false)
+	equals(t, strings.Contains(help, "access token (default"), false)
+	equals(t, strings.Contains(help, "random seed (default"), false)
+	equals(t, strings.Contains(help, "abc"), false)
+
+	opt.ShowZeroDefaults = true
+	help = Help(pos, opt)
+	equals(t, strings.Contains(help, "name of the server (default: )"), true)
+	equals(t, strings.Contains(help, "verbose output (default: false)"), true)
+	equals(t, strings.Contains(help, "port to listen on (default: 8080)"), true)
+}
+
+func TestReset(t *testing.T) {
+	pos, opt := Args()
+	count := opt.Int('n', "count", 3, "count")
+	tags := opt.StringSlice('t', "tag", []string{"a"}, "tags")
+	ints := opt.IntSlice('i', "int", []int{1}, "ints")
+	set := NewStringSetValue([]string{"x"})
+	opt.Register('s', "set", set, "set")
+	opt.Delimit("tag", ',')
+	pos.Register("files", NewStringSliceValue(nil), "files")
+	parser := NewParser(pos, opt)
+
+	for i := 0; i < 2; i++ {
+		equals(t, parser.Parse([]string{"-n", "5", "--tag=b,c", "--int=2", "--set=y", "f"}), nil)
+		equals(t, *count, 5)
+		equals(t, *tags, []string{"a", "b", "c"})
+		equals(t, *ints, []int{1, 2})
+		equals(t, set.Slice(), []string{"x", "y"})
+		equals(t, pos.Args["files"].Value.String(), "[f]")
+	}
+
+	equals(t, parser.Parse(nil), nil)
+	equals(t, *count, 3)
+	equals(t, *tags, []string{"a"})
+	equals(t, *ints, []int{1})
+	equals(t, set.Slice(), []string{"x"})
+	equals(t, pos.Args["files"].Value.String(), "[]")
+
+	equals(t, DefaultString(NewIntValue(1)), "1")
+	m := Map(map[string]int{"a": 1})
+	equals(t, m.Set("b=2"), nil)
+	equals(t, DefaultString(m), "[a=1]")
+	m.Reset()
+	equals(t, m.Get(), map[string]int{"a": 1})
+}
+
+func TestOpenSliceValue(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	init, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer init.Close()
+
+	value := NewOpenSliceValue([]*os.File{init})
+	equals(t, value.Set(path), nil)
+	opened := value.Get()[1]
+	equals(t, value.Close(), nil)
+	_, err = init.Stat()
+	equals(t, err, nil)
+	_, err = opened.Stat()
+	equals(t, errors.Is(err, os.ErrClosed), true)
+	equals(t, value.Close(), nil)
+
+	// Parsing again closes the files opened by the previous parse.
+	pos, opt := Args()
+	opt.Register('f', "file", value, "files")
+	parser := NewParser(pos, opt)
+	equals(t, parser.Parse([]string{"-f", path}), nil)
+	opened = value.Get()[1]
+	equals(t, parser.Parse(nil), nil)
+	_, err = opened.Stat()
+	equals(t, errors.Is(err, os.ErrClosed), true)
+	equals(t, value.Get(), []*os.File{init})
+	_, err = init.Stat()
+	equals(t, err, nil)
+
+	inputs := NewInputSliceValue()
+	equals(t, inputs.Set(path), nil)
+	opened = inputs.Get()[0]
+	inputs.Reset()
+	equals(t, inputs.Len(), 0)
+	_, err = opened.Stat()
+	equals(t, errors.Is(err, os.ErrClosed), true)
+}
+
+func TestResetFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "default.txt")
+	if err := os.WriteFile(path, []byte("precious"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	init, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer init.Close()
+
+	pos, opt := Args()
+	out := opt.Create('o', "output", init, "output file")
+	in := opt.Open('i', "input", init, "input file")
+	port := NewPortValue(8080)
+	opt.Register('p', "port", port, "port")
+	parser := NewParser(pos, opt)
+
+	other := filepath.Join(dir, "other.txt")
+	equals(t, parser.Parse([]string{"-o", other, "-i", other, "-p", "80"}), nil)
+	equals(t, out.File != init, true)
+	equals(t, parser.Parse(nil), nil)
+	equals(t, out.File == init, true)
+	equals(t, in.File == init, true)
+	equals(t, port.String(), "8080")
+
+	p, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, string(p), "precious")
+}
+
+func TestTimeout(t *testing.T) {
+	prog := NewProgram()
+	prog.Use(Timeout(time.Hour))
+	deadline := time.Duration(0)
+	prog.Add("wait", "wait for the timeout", func(ctx *Context) error {
+		if err := ctx.Parse(nil, nil); err != nil {
+			return err
+		}
+		d, ok := ctx.Context().Deadline()
+		deadline = 0
+		if ok {
+			deadline = time.Until(d)
+		}
+		return nil
+	})
+
+	code, err := RunArgs("prog", "", []string{"wait"}, prog.Compile())
+	equals(t, code, ExitSuccess)
+	equals(t, err, nil)
+	equals(t, deadline > 59*time.Minute, true)
+
+	code, err = RunArgs("prog", "", []string{"wait", "--timeout", "1s"}, prog.Compile())
+	equals(t, code, ExitSuccess)
+	equals(t, err, nil)
+	equals(t, deadline > 0 && deadline <= time.Second, true)
+
+	_, err = RunArgs("prog", "", []string{"wait", "--timeout=0"}, prog.Compile())
+	equals(t, err, nil)
+	equals(t, deadline, time.Duration(0))
+
+	code, _ = RunArgs("prog", "", []string{"wait", "--timeout", "soon"}, prog.Compile())
+	equals(t, code, ExitUsage)
+}
+
+type testObserver struct {
+	events []string
+}
+
+func (o *testObserver) OnDispatch(name string, args []string) {
+	o.events = append(o.events, fmt.Sprintf("dispatch %s %v", name, args))
+}
+
+func (o *testObserver) OnComplete(name string, err error, dur time.Duration) {
+	o.events = append(o.events, fmt.Sprintf("complete %s %v", name, err))
+}
+
+func TestObserver(t *testing.T) {
+	obs := &testObserver{}
+	prog := NewProgram()
+	prog.Observe(obs)
+	fail := errors.New("fail")
+	prog.Add("ok", "succeed", func(ctx *Context) error { return nil })
+	prog.Add("fail", "fail", func(ctx *Context) error { return fail })
+
+	RunArgs("prog", "", []string{"ok", "a"}, prog.Compile())
+	RunArgs("prog", "", []string{"fail"}, prog.Compile())
+	RunArgs("prog", "", []string{"unknown"}, prog.Compile())
+	RunArgs("prog", "", []string{CompleteCommand, "ok", ""}, prog.Compile())
+	equals(t, obs.events, []string{
+		"dispatch prog ok [a]",
+		"complete prog ok <nil>",
+		"dispatch prog fail []",
+		"complete prog fail fail",
+	})
+}
+
+func TestJSONOutput(t *testing.T) {
+	JSONOutput = true
+	defer func() { JSONOutput = false }()
+
+	prog := NewProgram()
+	prog.Add("add", "add numbers", func(ctx *Context) error {
+		pos, opt := Args()
+		pos.Int("count", "number of items")
+		opt.Switch('v', "verbose", "be verbose")
+		return ctx.Parse(pos, opt)
+	})
+	cmd := prog.Compile()
+
+	stderr := &bytes.Buffer{}
+	ctx := &Context{Name: "prog", Args: []string{"add", "--verbos", "x"}, Stderr: stderr}
+	equals(t, Exec(ctx, cmd), ExitUsage)
+	info := ErrorInfo{}
+	equals(t, json.Unmarshal(stderr.Bytes(), &info), nil)
+	equals(t, info.Code, ExitUsage)
+	equals(t, info.Usage, "usage: prog add [-h | --help] [<args>] <count>")
+	equals(t, len(info.Errors), 2)
+	equals(t, info.Errors[0].Kind, "unknown_flag")
+	equals(t, info.Errors[0].Name, "--verbos")
+	equals(t, info.Errors[0].Suggestions, []string{"--verbose"})
+	equals(t, info.Errors[1].Kind, "invalid_value")
+	equals(t, info.Errors[1].Name, "<count>")
+	equals(t, info.Message, info.Errors[0].Message+"\n"+info.Errors[1].Message)
+
+	stdout := &bytes.Buffer{}
+	ctx = &Context{Name: "prog", Args: []string{"add", "--help"}, Stdout: stdout}
+	equals(t, Exec(ctx, cmd), ExitSuccess)
+	help := CommandInfo{}
+	equals(t, json.Unmarshal(stdout.Bytes(), &help), nil)
+	equals(t, help.Name, "prog add")
+	equals(t, help.Positionals[0].Name, "count")
+	equals(t, help.Optionals[0].Name, "verbose")
+
+	stdout.Reset()
+	ctx = &Context{Name: "prog", Desc: "test program", Args: []string{"--help"}, Stdout: stdout}
+	equals(t, Exec(ctx, cmd), ExitSuccess)
+	help = CommandInfo{}
+	equals(t, json.Unmarshal(stdout.Bytes(), &help), nil)
+	equals(t, help.Desc, "test program")
+	equals(t, help.Commands[0].Name, "prog add")
+}
+
+func TestChain(t *testing.T) {
+	ran := []string{}
+	prog := NewProgram()
+	prog.Chain = ","
+	for _, name := range []string{"build", "test", "deploy"} {
+		name := name
+		prog.Add(name, name, func(ctx *Context) error {
+			pos, opt := Args()
+			fail := opt.Switch(0, "fail", "fail the command")
+			if err := ctx.Parse(pos, opt); err != nil {
+				return err
+			}
+			ran = append(ran, name)
+			if *fail {
+				return errors.New(name + " failed")
+			}
+			return nil
+		})
+	}
+
+	_, err := RunArgs("prog", "", []string{"build", ",", "test", ",", "deploy"}, prog.Compile())
+	equals(t, err, nil)
+	equals(t, ran, []string{"build", "test", "deploy"})
+
+	ran = nil
+	_, err = RunArgs("prog", "", []string{"build", ",", "test", "--fail", ",", "deploy"}, prog.Compile())
+	equals(t, err.Error(), "test failed")
+	equals(t, ran, []string{"build", "test"})
+
+	ran = nil
+	code, _ := RunArgs("prog", "", []string{"build", ",", ",", "deploy"}, prog.Compile())
+	equals(t, code, ExitUsage)
+	equals(t, ran, []string{"build"})
+}
+
+func TestPositionalNames(t *testing.T) {
+	cmd := func(ctx *Context) error {
+		pos, opt := Args()
+		pos.String("source", "source file")
+		pos.String("dest", "destination file")
+		pos.Register("extra", NewStringSliceValue(nil), "extra files")
+		pos.UpperNames = true
+		if err := ctx.Parse(pos, opt); err != nil {
+			return err
+		}
+		equals(t, pos.Get("source").String(), "a")
+		equals(t, ctx.Arg("dest"), "b")
+		equals(t, ctx.Arg("extra"), "[c, d]")
+		equals(t, ctx.Arg("missing"), "")
+		panics(t, func() { pos.Get("missing") })
+		return nil
+	}
+	_, err := RunArgs("cp", "", []string{"a", "b", "c", "d"}, cmd)
+	equals(t, err, nil)
+
+	stdout := &bytes.Buffer{}
+	ctx := &Context{Name: "cp", Args: []string{"--help"}, Stdout: stdout}
+	Exec(ctx, cmd)
+	equals(t, strings.HasPrefix(stdout.String(), "usage: cp [-h | --help] SOURCE DEST [EXTRA...]\n"), true)
+	equals(t, strings.Contains(stdout.String(), "\n  SOURCE "), true)
+}
+
+func TestTopics(t *testing.T) {
+	prog := NewProgram()
+	prog.Add("login", "log in", func(ctx *Context) error { return nil })
+	prog.AddTopic("authentication", "How to authenticate.\n\nSet the token with login.\n")
+	prog.AddTopic("config", "Configuration files.")
+
+	stdout := &bytes.Buffer{}
+	ctx := &Context{Name: "prog", Args: []string{"help", "authentication"}, Stdout: stdout}
+	equals(t, Exec(ctx, prog.Compile()), ExitSuccess)
+	equals(t, stdout.String(), "How to authenticate.\n\nSet the token with login.\n")
+
+	stdout.Reset()
+	ctx = &Context{Name: "prog", Args: []string{"--help"}, Stdout: stdout}
+	equals(t, Exec(ctx, prog.Compile()), ExitSuccess)
+	equals(t, strings.HasSuffix(stdout.String(), "help topics:\n  authentication  How to authenticate.\n  config          Configuration files.\n"), true)
+
+	code, _ := RunArgs("prog", "", []string{"help", "unknown"}, prog.Compile())
+	equals(t, code, ExitUsage)
+}
+
+func TestHelpCommand(t *testing.T) {
+	sub := NewProgram()
+	sub.Add("add", "add a remote", func(ctx *Context) error {
+		pos, opt := Args()
+		pos.String("name", "name of the remote")
+		opt.Switch('f', "fetch", "fetch the remote")
+		return ctx.Parse(pos, opt)
+	})
+	prog := NewProgram()
+	prog.AddProgram("remote", "manage remotes", sub)
+	prog.Add("status", "show status", func(ctx *Context) error {
+		return ctx.Parse(Args())
+	})
+	prog.Describe("status", Doc{Examples: []Example{{Command: "git status"}}})
+
+	run := func(args ...string) (string, int) {
+		stdout := &bytes.Buffer{}
+		ctx := &Context{Name: "git", Args: args, Stdout: stdout, Stderr: ioutil.Discard}
+		code := Exec(ctx, prog.Compile())
+		return stdout.String(), code
+	}
+
+	out, code := run("help", "status")
+	equals(t, code, ExitSuccess)
+	equals(t, strings.HasPrefix(out, "usage: git status [-h | --help]\n"), true)
+	equals(t, strings.HasSuffix(out, "examples:\n  $ git status\n"), true)
+
+	out, code = run("help", "remote", "add")
+	equals(t, code, ExitSuccess)
+	equals(t, strings.HasPrefix(out, "usage: git remote add [-h | --help] [<args>] <name>\n"), true)
+	equals(t, strings.Contains(out, "--fetch"), true)
+
+	out, _ = run("help")
+	help, _ := run("--help")
+	equals(t, out, help)
+
+	out, _ = run("help", "remote")
+	help, _ = run("remote", "--help")
+	equals(t, out, help)
+
+	_, code = run("help", "unknown")
+	equals(t, code, ExitUsage)
+
+	out, _ = run(CompleteCommand, "help", "st")
+	equals(t, out, "status\n")
+
+	prog.Add("help", "custom help", func(ctx *Context) error {
+		fmt.Fprintln(ctx.Stdout, "custom")
+		return nil
+	})
+	out, _ = run("help", "status")
+	equals(t, out, "custom\n")
+}
+
+func TestConstraints(t *testing.T) {
+	pos, opt := Args()
+	opt.String(0, "tls-cert", "", "certificate file")
+	opt.String(0, "tls-key", "", "key file")
+	opt.String(0, "input", "", "input file")
+	opt.Switch(0, "stdin", "read the standard input")
+	opt.Switch(0, "json", "output JSON")
+	opt.Switch(0, "yaml", "output YAML")
+	opt.Requires("tls-cert", "tls-key")
+	opt.RequireOne("input", "stdin")
+	opt.Exclusive("json", "yaml")
+	panics(t, func() { opt.Requires("tls-cert", "missing") })
+	parser := NewParser(pos, opt)
+
+	equals(t, parser.Parse([]string{"--stdin", "--tls-cert", "a", "--tls-key", "b", "--json"}), nil)
+
+	err := parser.Parse([]string{"--tls-cert", "a", "--json", "--yaml"})
+	equals(t, errors.Is(err, ErrRequiredFlag), true)
+	equals(t, errors.Is(err, ErrExclusiveFlags), true)
+	equals(t, err.Error(), strings.Join([]string{
+		"flag `--tls-cert` requires `--tls-key`",
+		"one of `--input`, `--stdin` is required",
+		"flags `--json`, `--yaml` cannot be given together",
+	}, "\n"))
+	var e *ConstraintError
+	equals(t, errors.As(err, &e), true)
+	equals(t, e.Flags, []string{"--tls-key"})
+}
+
+func TestOptionalArity(t *testing.T) {
+	pos, opt := Args()
+	headers := opt.StringSlice('H', "header", []string{"Accept: */*"}, "request headers")
+	opt.Arity("header", 0, 2)
+	opt.IntSlice(0, "port", nil, "ports")
+	opt.Arity("port", 1, Unbounded)
+	opt.Int(0, "count", 0, "count")
+	panics(t, func() { opt.Arity("count", 0, 1) })
+	panics(t, func() { opt.Arity("port", 2, 1) })
+	parser := NewParser(pos, opt)
+
+	equals(t, parser.Parse([]string{"--port=80", "--header=A: 1", "--header=B: 2"}), nil)
+	equals(t, *headers, []string{"Accept: */*", "A: 1", "B: 2"})
+
+	err := parser.Parse([]string{"--header=A: 1", "--header=B: 2", "--header=C: 3"})
+	equals(t, err.Error(), strings.Join([]string{
+		"in flag `--header`: expected at most 2 value(s), got 3",
+		"in flag `--port`: expected at least 1 value(s), got 0",
+	}, "\n"))
+	equals(t, strings.Contains(Help(nil, opt), "request headers (at most 2 values)"), true)
+}
+
+func TestPassthrough(t *testing.T) {
+	rest := []string{}
+	cmd := func(ctx *Context) error {
+		pos, opt := Args()
+		pos.String("container", "container name")
+		pos.Passthrough = true
+		opt.Switch('v', "verbose", "be verbose")
+		if err := ctx.Parse(pos, opt); err != nil {
+			return err
+		}
+		rest = ctx.Rest
+		return nil
+	}
+
+	_, err := RunArgs("exec", "", []string{"-v", "c1", "--", "prog", "--its-flag"}, cmd)
+	equals(t, err, nil)
+	equals(t, rest, []string{"prog", "--its-flag"})
+
+	_, err = RunArgs("exec", "", []string{"c1", "prog", "-v", "--", "x"}, cmd)
+	equals(t, err, nil)
+	equals(t, rest, []string{"prog", "-v", "--", "x"})
+
+	_, err = RunArgs("exec", "", []string{"c1"}, cmd)
+	equals(t, err, nil)
+	equals(t, len(rest), 0)
+
+	pos, opt := Args()
+	pos.Passthrough = true
+	equals(t, Usage(pos, opt), "[-h | --help] [--] [<args> ...]")
+}
+
+func TestAutocorrect(t *testing.T) {
+	ran := ""
+	prog := NewProgram()
+	prog.Autocorrect = true
+	for _, name := range []string{"status", "commit", "pull", "push"} {
+		name := name
+		prog.Add(name, name, func(ctx *Context) error {
+			ran = name
+			return nil
+		})
+	}
+
+	f, err := ioutil.TempFile("", "stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	stderr := &bytes.Buffer{}
+	ctx := &Context{Name: "git", Args: []string{"stauts"}, Stdin: f, Stderr: stderr}
+	equals(t, Exec(ctx, prog.Compile()), ExitSuccess)
+	equals(t, ran, "status")
+	equals(t, stderr.String(), "assuming you meant `status` instead of `stauts`\n")
+
+	ran = ""
+	stderr.Reset()
+	ctx = &Context{Name: "git", Args: []string{"comit"}, Stdin: strings.NewReader("y\n"), Stderr: stderr}
+	equals(t, Exec(ctx, prog.Compile()), ExitSuccess)
+	equals(t, ran, "commit")
+	equals(t, stderr.String(), "unknown command name `comit`, did you mean `commit`? [y/N]: ")
+
+	ran = ""
+	ctx = &Context{Name: "git", Args: []string{"comit"}, Stdin: strings.NewReader("n\n"), Stderr: ioutil.Discard}
+	equals(t, Exec(ctx, prog.Compile()), ExitUsage)
+	equals(t, ran, "")
+
+	// Ambiguous corrections are not made.
+	ctx = &Context{Name: "git", Args: []string{"pulh"}, Stdin: f, Stderr: ioutil.Discard}
+	equals(t, Exec(ctx, prog.Compile()), ExitUsage)
+	equals(t, ran, "")
+}
+
+func TestIntRangeSetValue(t *testing.T) {
+	value := NewIntRangeSetValue([]int{3, 1, 1})
+	equals(t, value.String(), "1,3")
+
+	equals(t, value.Set("5-9,1,3,7-10, 12"), nil)
+	equals(t, value.Get(), []int{1, 3, 5, 6, 7, 8, 9, 10, 12})
+	equals(t, value.String(), "1,3,5-10,12")
+	equals(t, value.Contains(6), true)
+	equals(t, value.Contains(11), false)
+	equals(t, TypeName(value), "ranges")
+
+	for _, s := range []string{"", "a", "1,", "9-5", "-1", "1-", "0-2000000"} {
+		differs(t, value.Set(s), nil)
+	}
+	equals(t, value.String(), "1,3,5-10,12")
+
+	pos, opt := Args()
+	cpus := opt.IntRangeSet('c', "cpus", []int{0}, "cpu list")
+	pages := pos.IntRangeSet("pages", "page selection")
+	equals(t, NewParser(pos, opt).Parse([]string{"-c", "0-3", "2,4"}), nil)
+	equals(t, *cpus, []int{0, 1, 2, 3})
+	equals(t, *pages, []int{2, 4})
+}
+
+func TestSeedValue(t *testing.T) {
+	value := NewSeedValue("42")
+	equals(t, value.Get(), int64(42))
+	equals(t, value.Generated(), false)
+	equals(t, value.Set(RandomSeed), nil)
+	equals(t, value.Generated(), true)
+	equals(t, value.String(), strconv.FormatInt(value.Get(), 10))
+	differs(t, value.Set("soon"), nil)
+	panics(t, func() { NewSeedValue("soon") })
+
+	seed := int64(0)
+	cmd := func(ctx *Context) error {
+		pos, opt := Args()
+		p := opt.Seed('s', "seed", RandomSeed, "random seed")
+		if err := ctx.Parse(pos, opt); err != nil {
+			return err
+		}
+		seed = *p
+		return nil
+	}
+	stderr := &bytes.Buffer{}
+	ctx := &Context{Name: "sim", Args: []string{}, Stderr: stderr}
+	equals(t, Exec(ctx, cmd), ExitSuccess)
+	equals(t, stderr.String(), fmt.Sprintf("using random seed %d for --seed\n", seed))
+
+	stderr.Reset()
+	ctx = &Context{Name: "sim", Args: []string{"--seed", "7"}, Stderr: stderr}
+	equals(t, Exec(ctx, cmd), ExitSuccess)
+	equals(t, seed, int64(7))
+	equals(t, stderr.String(), "")
+}
+
+type testDBFlags struct {
+	Host     *string
+	Port     *int
+	Password *string
+}
+
+var testDatabase Mixin[testDBFlags] = func(opt *Optional) *testDBFlags {
+	db := &testDBFlags{
+		Host:     opt.String('H', "host", "localhost", "database host"),
+		Port:     opt.Int(0, "port", 5432, "database port"),
+		Password: opt.String(0, "password", "", "database password"),
+	}
+	opt.Secret("password")
+	opt.Requires("password", "host")
+	return db
+}
+
+func TestMixin(t *testing.T) {
+	pos, opt := Args()
+	db := Attach(opt, testDatabase)
+	equals(t, NewParser(pos, opt).Parse([]string{"-H", "db.local", "--port", "1234"}), nil)
+	equals(t, *db.Host, "db.local")
+	equals(t, *db.Port, 1234)
+	equals(t, opt.Secrets["password"], true)
+	panics(t, func() { Attach(opt, testDatabase) })
+
+	pos, opt = Args()
+	src := AttachPrefix(opt, "src-", testDatabase)
+	dst := AttachPrefix(opt, "dst-", testDatabase)
+	equals(t, NewParser(pos, opt).Parse([]string{"--src-host", "a", "--dst-port", "1"}), nil)
+	equals(t, *src.Host, "a")
+	equals(t, *src.Port, 5432)
+	equals(t, *dst.Host, "localhost")
+	equals(t, *dst.Port, 1)
+	equals(t, opt.Alias, map[rune]string{})
+	equals(t, opt.Secrets["dst-password"], true)
+	err := NewParser(pos, opt).Parse([]string{"--dst-password", "x"})
+	equals(t, err.Error(), "flag `--dst-password` requires `--dst-host`")
+}
+
+type testRelease struct {
+	Name    string `json:"name" yaml:"name"`
+	Version string `json:"version" yaml:"version"`
+	secret  string
+}
+
+func TestOutput(t *testing.T) {
+	cmd := Produce(func(ctx *Context) (interface{}, error) {
+		pos, opt := Args()
+		opt.Output("table")
+		if err := ctx.Parse(pos, opt); err != nil {
+			return nil, err
+		}
+		return []testRelease{{"flags", "v1.0.0", ""}, {"wrap", "v1.10.2", ""}}, nil
+	})
+
+	cases := []struct {
+		format string
+		out    string
+	}{
+		{"table", "NAME   VERSION\nflags  v1.0.0\nwrap   v1.10.2\n"},
+		{"json", "[\n  {\n    \"name\": \"flags\",\n    \"version\": \"v1.0.0\"\n  },\n  {\n    \"name\": \"wrap\",\n    \"version\": \"v1.10.2\"\n  }\n]\n"},
+		{"yaml", "- name: flags\n  version: v1.0.0\n- name: wrap\n  version: v1.10.2\n"},
+	}
+	for _, tt := range cases {
+		stdout := &bytes.Buffer{}
+		ctx := &Context{Name: "releases", Args: []string{"-o", tt.format}, Stdout: stdout}
+		equals(t, Exec(ctx, cmd), ExitSuccess)
+		equals(t, stdout.String(), tt.out)
+	}
+
+	ctx := &Context{Name: "releases", Args: []string{"-o", "xml"}, Stderr: ioutil.Discard}
+	equals(t, Exec(ctx, cmd), ExitUsage)
+
+	Encoders["csv"] = func(w io.Writer, v interface{}) error {
+		_, err := fmt.Fprintln(w, "csv")
+		return err
+	}
+	defer delete(Encoders, "csv")
+	stdout := &bytes.Buffer{}
+	ctx = &Context{Name: "releases", Args: []string{"--output=csv"}, Stdout: stdout}
+	equals(t, Exec(ctx, cmd), ExitSuccess)
+	equals(t, stdout.String(), "csv\n")
+
+	stdout.Reset()
+	equals(t, EncodeTable(stdout, map[string]int{"b": 2, "a": 1}), nil)
+	equals(t, stdout.String(), "A  1\nB  2\n")
+}
+
+func TestDebug(t *testing.T) {
+	equals(t, debugLogger(""), (*slog.Logger)(nil))
+	equals(t, debugLogger("0"), (*slog.Logger)(nil))
+	differs(t, debugLogger("1"), (*slog.Logger)(nil))
+
+	buf := &bytes.Buffer{}
+	Debug = slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == slog.LevelKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	defer func() { Debug = nil }()
+
+	pos, opt := Args()
+	pos.String("name", "name")
+	opt.Int('n', "count", 1, "count")
+	opt.String(0, "token", "", "token")
+	opt.Switch('v', "verbose", "be verbose")
+	opt.Secret("token")
+	equals(t, NewParser(pos, opt).Parse([]string{"--token=abc", "-n", "2", "x"}), nil)
+	equals(t, buf.String(), strings.Join([]string{
+		`msg=token arg="--token=********"`,
+		`msg=match arg="--token=********" flag=--token`,
+		"msg=set flag=--token value=********",
+		"msg=token arg=-n",
+		"msg=match arg=-n flag=--count",
+		"msg=set flag=--count value=2",
+		"msg=token arg=x",
+		"msg=set positional=<name> value=x",
+		"msg=default flag=--verbose value=false",
+		"",
+	}, "\n"))
+
+	buf.Reset()
+	opt.SlashFlags = true
+	equals(t, NewParser(pos, opt).Parse([]string{"--token", "hunter1", "/token:hunter2", "x"}), nil)
+	equals(t, strings.Contains(buf.String(), "hunter"), false)
+	equals(t, strings.Contains(buf.String(), `msg=token arg="--token=********"`), true)
+}
+
+func TestLargeFlagSet(t *testing.T) {
+	opt := newOptional()
+	opt.Abbrev = true
+	for i := 0; i < 10000; i++ {
+		opt.Int(0, fmt.Sprintf("option-%05d", i), 0, "")
+	}
+	opt.Int(0, "unique", 0, "")
+	name, err := opt.Lookup("option-01234")
+	equals(t, err, nil)
+	equals(t, name, "option-01234")
+	name, err = opt.Lookup("uni")
+	equals(t, err, nil)
+	equals(t, name, "unique")
+	equals(t, errors.Is(mustError(opt.Lookup("option-099")), ErrAmbiguousFlag), true)
+	equals(t, errors.Is(mustError(opt.Lookup("other")), ErrUnknownFlag), true)
+	panics(t, func() { opt.Int(0, "option-00000", 0, "") })
+
+	// Flags added to Args directly and normalization changes are picked up.
+	opt.Args["extra"] = Argument{NewIntValue(0), ""}
+	name, err = opt.Lookup("ex")
+	equals(t, err, nil)
+	equals(t, name, "extra")
+	opt.Normalize = strings.ToLower
+	name, err = opt.Lookup("EXTRA")
+	equals(t, err, nil)
+	equals(t, name, "extra")
+}
+
+func mustError(_ string, err error) error {
+	return err
+}
+
+func benchmarkArgs() (*Positional, *Optional) {
+	pos, opt := Args()
+	pos.String("src", "source")
+	pos.String("dst", "destination")
+	opt.Switch('v', "verbose", "verbose output")
+	opt.Switch('q', "quiet", "quiet output")
+	opt.Int('n', "count", 0, "number of things")
+	opt.String('o', "output", "", "output name")
+	opt.Float('r', "ratio", 0, "ratio of things")
+	opt.Register('t', "tag", NewStringSliceValue(nil), "tags")
+	for i := 0; i < 50; i++ {
+		opt.Int(0, fmt.Sprintf("option-%02d", i), 0, "generated option")
+	}
+	return pos, opt
+}
+
+func BenchmarkParse(b *testing.B) {
+	pos, opt := benchmarkArgs()
+	parser := NewParser(pos, opt)
+	args := []string{
+		"-vq", "--count", "42", "--output=out.txt", "-r", "0.5",
+		"--option-10", "1", "--option-20=2", "-t", "a", "-t", "b", "src", "dst",
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		*opt.Args["tag"].Value.(*StringSliceValue).Ptr() = nil
+		if err := parser.Parse(args); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseSlice(b *testing.B) {
+	pos, opt := benchmarkArgs()
+	parser := NewParser(pos, opt)
+	args := []string{"-t"}
+	for i := 0; i < 100; i++ {
+		args = append(args, fmt.Sprintf("tag%d", i))
+	}
+	args = append(args, "src", "dst")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		*opt.Args["tag"].Value.(*StringSliceValue).Ptr() = nil
+		if err := parser.Parse(args); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLookup(b *testing.B) {
+	opt := newOptional()
+	opt.Abbrev = true
+	for i := 0; i < 10000; i++ {
+		opt.Int(0, fmt.Sprintf("option-%05d", i), 0, "")
+	}
+	opt.Int(0, "unique", 0, "")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := opt.Lookup("uni"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestCrash(t *testing.T) {
+	var reports []CrashReport
+	CrashHook = func(report CrashReport) { reports = append(reports, report) }
+	defer func() { CrashHook = nil }()
+
+	closed := false
+	prog := NewProgram()
+	prog.Add("login", "log in", func(ctx *Context) error {
+		ctx.Defer(func() error { closed = true; return nil })
+		pos, opt := Args()
+		opt.String('u', "user", "", "user name")
+		opt.String('p', "password", "", "password")
+		opt.Secret("password")
+		if err := ctx.Parse(pos, opt); err != nil {
+			return err
+		}
+		var m map[string]int
+		m["boom"]++
+		return nil
+	})
+
+	stderr := &bytes.Buffer{}
+	args := []string{"login", "-u", "alice", "--password=hunter2", "--password", "letmein", "-p", "secret"}
+	ctx := &Context{Name: "prog", Args: args, Stderr: stderr}
+	equals(t, Exec(ctx, prog.Compile()), ExitPanic)
+	equals(t, closed, true)
+	equals(t, len(reports), 1)
+	equals(t, reports[0].Command, "prog login")
+	equals(t, reports[0].Args, []string{"-u", "alice", "--password=" + Masked, "--password", Masked, "-p", Masked})
+	equals(t, strings.Contains(reports[0].Panic, "nil map"), true)
+	equals(t, strings.Contains(reports[0].Stack, "TestCrash"), true)
+	equals(t, strings.Contains(stderr.String(), "panic: "+reports[0].Panic), true)
+	equals(t, strings.Contains(stderr.String(), "hunter2"), false)
+}
+
+func TestDryRun(t *testing.T) {
+	prog := NewProgram()
+	prog.Use(DryRun())
+	removed := []string{}
+	prog.Add("rm", "remove files", func(ctx *Context) error {
+		pos, opt := Args()
+		files := Slice[string]()
+		pos.Register("files", files, "files to remove")
+		if err := ctx.Parse(pos, opt); err != nil {
+			return err
+		}
+		for _, file := range files.Get() {
+			if err := ctx.Do("remove "+file, func() error {
+				removed = append(removed, file)
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	stderr := &bytes.Buffer{}
+	ctx := &Context{Name: "prog", Args: []string{"rm", "--dry-run", "a", "b"}, Stderr: stderr}
+	equals(t, Exec(ctx, prog.Compile()), ExitSuccess)
+	equals(t, len(removed), 0)
+	equals(t, stderr.String(), "dry run: would remove a\ndry run: would remove b\n")
+
+	code, err := RunArgs("prog", "", []string{"rm", "a", "b"}, prog.Compile())
+	equals(t, code, ExitSuccess)
+	equals(t, err, nil)
+	equals(t, removed, []string{"a", "b"})
+
+	ctx = &Context{}
+	equals(t, ctx.DryRun(), false)
+}
+
+func TestIndirect(t *testing.T) {
+	root, err := ioutil.TempDir("", "flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	file := filepath.Join(root, "token")
+	if err := ioutil.WriteFile(file, []byte("s3cr3t\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv("FLAGS_TEST_TOKEN")
+	os.Setenv("FLAGS_TEST_TOKEN", "from-env")
+
+	pos, opt := Args()
+	token := opt.String('t', "token", "", "access token")
+	name := opt.String('n', "name", "", "name")
+	opt.Indirect("token")
+	parser := NewParser(pos, opt)
+
+	for _, tt := range []struct {
+		arg, want string
+	}{
+		{"file:" + file, "s3cr3t"},
+		{"env:FLAGS_TEST_TOKEN", "from-env"},
+		{"literal:file:x", "file:x"},
+		{"plain", "plain"},
+	} {
+		if err := parser.Parse([]string{"--token", tt.arg, "--name", tt.arg}); err != nil {
+			t.Errorf("parser.Parse: %v", err)
+			continue
+		}
+		equals(t, *token, tt.want)
+		equals(t, *name, tt.arg)
+	}
+
+	for _, arg := range []string{"file:" + filepath.Join(root, "missing"), "env:FLAGS_TEST_UNSET"} {
+		if err := parser.Parse([]string{"--token=" + arg}); err == nil {
+			t.Errorf("parser.Parse([]string{%q}) = nil, want error", "--token="+arg)
+		}
+	}
+
+	opt.IndirectAll = true
+	if err := parser.Parse([]string{"-n", "env:FLAGS_TEST_TOKEN"}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+	}
+	equals(t, *name, "from-env")
+}
+
+func TestChecksumValue(t *testing.T) {
+	root, err := ioutil.TempDir("", "flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	file := filepath.Join(root, "archive#1.tar")
+	if err := ioutil.WriteFile(file, []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+
+	value := NewChecksumValue(nil)
+	if err := value.Set(file + "#sha256=" + sum); err != nil {
+		t.Errorf("value.Set: %v", err)
+		return
+	}
+	defer value.Close()
+	p, err := ioutil.ReadAll(value)
+	equals(t, err, nil)
+	equals(t, string(p), "hello\n")
+	algorithm, digest := value.Digest()
+	equals(t, algorithm, "sha256")
+	equals(t, digest, sum)
+	equals(t, value.String(), file+"#sha256="+sum)
+
+	bad := strings.Repeat("0", 64)
+	for _, s := range []string{
+		file,
+		file + "#md5=" + sum,
+		file + "#sha256=xyz",
+		file + "#sha256=" + bad,
+		filepath.Join(root, "missing") + "#sha256=" + sum,
+	} {
+		if err := value.Set(s); err == nil {
+			t.Errorf("value.Set(%q) = nil, want error", s)
+		}
+	}
+	equals(t, value.String(), file+"#sha256="+sum)
+}
+
+func TestLimitedValue(t *testing.T) {
+	root, err := ioutil.TempDir("", "flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	file := filepath.Join(root, "input")
+	if err := ioutil.WriteFile(file, []byte("a\nb\nc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		maxBytes int64
+		maxLines int
+		want     string
+		fail     bool
+	}{
+		{0, 0, "a\nb\nc\n", false},
+		{6, 3, "a\nb\nc\n", false},
+		{5, 0, "a\nb\nc", true},
+		{0, 2, "a\nb\n", true},
+	} {
+		value := NewLimitedValue(nil, tt.maxBytes, tt.maxLines)
+		if err := value.Set(file); err != nil {
+			t.Errorf("value.Set(%q): %v", file, err)
+			continue
+		}
+		r, err := value.Reader()
+		if err != nil {
+			t.Errorf("value.Reader: %v", err)
+			continue
+		}
+		p, err := ioutil.ReadAll(r)
+		equals(t, string(p), tt.want)
+		equals(t, errors.Is(err, ErrInputTooLarge), tt.fail)
+		value.Close()
+
+		if err := value.Set(file); err != nil {
+			t.Errorf("value.Set(%q): %v", file, err)
+			continue
+		}
+		p, err = ioutil.ReadAll(value)
+		equals(t, string(p), tt.want)
+		equals(t, errors.Is(err, ErrInputTooLarge), tt.fail)
+		value.Close()
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	value := NewLocaleFloatValue(0, nil)
+	if err := value.Set("1.234,56"); err == nil {
+		t.Error("value.Set(\"1.234,56\") = nil, want error")
+	}
+	if err := NewFloatValue(0).Set("1.234,56"); err == nil {
+		t.Error("NewFloatValue(0).Set(\"1.234,56\") = nil, want error")
+	}
+
+	value.Format = EuropeanNumbers
+	for s, want := range map[string]float64{
+		"1.234,56":    1234.56,
+		"-1.234.567":  -1234567,
+		"0,5":         0.5,
+		"42":          42,
+		"1e3":         1000,
+		"123.456.789": 123456789,
+		"1.234,0":     1234,
+	} {
+		if err := value.Set(s); err != nil {
+			t.Errorf("value.Set(%q): %v", s, err)
+			continue
+		}
+		equals(t, *value.p, want)
+	}
+	for _, s := range []string{"1.23,4", "1234.567", "1,", ",5", "1.234.56", "1.234", "0.5"} {
+		if err := value.Set(s); err == nil {
+			t.Errorf("value.Set(%q) = nil, want error", s)
+		}
+	}
+	err := value.Set("0.5")
+	equals(t, err.Error(), "`0.5` cannot be interpreted as float64 written like `1.234,56`")
+
+	n := NewLocaleIntValue(0, EnglishNumbers)
+	if err := n.Set("1,000,000"); err != nil {
+		t.Errorf("n.Set(\"1,000,000\"): %v", err)
+	}
+	equals(t, *n.p, 1000000)
+	if err := n.Set("1,000.5"); err == nil {
+		t.Error("n.Set(\"1,000.5\") = nil, want error")
+	}
+
+	pos, opt := Args()
+	amount := pos.LocaleFloat("amount", FrenchNumbers, "amount to pay")
+	count := opt.LocaleInt('n', "count", 1, SwissNumbers, "number of payments")
+	if err := NewParser(pos, opt).Parse([]string{"-n", "1'200", "1 234,5"}); err != nil {
+		t.Fatal(err)
+	}
+	equals(t, *amount, 1234.5)
+	equals(t, *count, 1200)
+}
+
+func TestColorValue(t *testing.T) {
+	value := NewColorValue(RGBA{})
+	for s, want := range map[string]RGBA{
+		"#ff8000":              {0xff, 0x80, 0x00, 0xff},
+		"#F80":                 {0xff, 0x88, 0x00, 0xff},
+		"#ff800080":            {0xff, 0x80, 0x00, 0x80},
+		"rgb(255, 99, 71)":     {255, 99, 71, 0xff},
+		"rgba(0, 0, 255, 0.5)": {0, 0, 255, 128},
+		"rgb(100%, 0%, 50%)":   {255, 0, 128, 0xff},
+		"Tomato":               {255, 99, 71, 0xff},
+		"transparent":          {0, 0, 0, 0},
+		" rgba(1,2,3,100%) ":   {1, 2, 3, 0xff},
+	} {
+		if err := value.Set(s); err != nil {
+			t.Errorf("value.Set(%q): %v", s, err)
+			continue
+		}
+		equals(t, RGBA(*value), want)
+	}
+	equals(t, NewColorValue(RGBA{255, 99, 71, 0xff}).String(), "#ff6347")
+	equals(t, NewColorValue(RGBA{255, 99, 71, 0x80}).String(), "#ff634780")
+
+	for _, s := range []string{"#ff800", "#gggggg", "rgb(256, 0, 0)", "rgb(1, 2)", "rgba(0, 0, 0, 2)", "blurple"} {
+		if err := value.Set(s); err == nil {
+			t.Errorf("value.Set(%q) = nil, want error", s)
+		}
+	}
+}
+
+func TestCronValue(t *testing.T) {
+	base := time.Date(2024, time.January, 31, 10, 17, 30, 0, time.UTC)
+	for expr, want := range map[string]time.Time{
+		"*/15 * * * *":        time.Date(2024, time.January, 31, 10, 30, 0, 0, time.UTC),
+		"0 9 * * MON-FRI":     time.Date(2024, time.February, 1, 9, 0, 0, 0, time.UTC),
+		"30 0 0 29 feb *":     time.Date(2024, time.February, 29, 0, 0, 30, 0, time.UTC),
+		"0 0 1,15 * 7":        time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC),
+		"@hourly":             time.Date(2024, time.January, 31, 11, 0, 0, 0, time.UTC),
+		"0 12 31 4 *":         {}, // April has no 31st day.
+		"5/20 10-11 31 jan *": time.Date(2024, time.January, 31, 10, 25, 0, 0, time.UTC),
+	} {
+		sched, err := ParseCron(expr)
+		if err != nil {
+			t.Errorf("ParseCron(%q): %v", expr, err)
+			continue
+		}
+		equals(t, sched.Next(base), want)
+	}
+
+	value := NewCronValue("@daily")
+	equals(t, value.String(), "@daily")
+	equals(t, value.Next().After(time.Now()), true)
+	for _, s := range []string{"* * * *", "60 * * * *", "* * * 13 *", "*/0 * * * *", "5-1 * * * *", "* * * * funday"} {
+		if err := value.Set(s); err == nil {
+			t.Errorf("value.Set(%q) = nil, want error", s)
+		}
+	}
+}
+
+func TestCSVValue(t *testing.T) {
+	root, err := ioutil.TempDir("", "flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	file := filepath.Join(root, "data.tsv")
+	if err := ioutil.WriteFile(file, []byte("name\tage\n# comment\nalice\t30\nbob\t25\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pos, opt := Args()
+	data := pos.CSV("data", "data to read")
+	data.Comma, data.Comment, data.HasHeader = '\t', '#', true
+	if err := NewParser(pos, opt).Parse([]string{file}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	defer data.Close()
+
+	records, err := data.Records()
+	equals(t, err, nil)
+	equals(t, data.Header(), []string{"name", "age"})
+	equals(t, records, [][]string{{"alice", "30"}, {"bob", "25"}})
+	records, err = data.Records()
+	equals(t, err, nil)
+	equals(t, len(records), 2)
+
+	if err := data.Set(file); err != nil {
+		t.Errorf("data.Set(%q): %v", file, err)
+		return
+	}
+	data.HasHeader = false
+	names := []string{}
+	for record := range data.Stream() {
+		if record.Err != nil {
+			t.Errorf("data.Stream: %v", record.Err)
+			break
+		}
+		names = append(names, record.Fields[0])
+	}
+	equals(t, names, []string{"name", "alice", "bob"})
+}
+
+func TestProfile(t *testing.T) {
+	root, err := ioutil.TempDir("", "flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	prog := NewProgram()
+	prog.Use(Profile())
+	prog.Add("work", "do some work", func(ctx *Context) error {
+		return ctx.Parse(nil, nil)
+	})
+
+	dir := filepath.Join(root, "profiles")
+	stderr := &bytes.Buffer{}
+	ctx := &Context{Name: "prog", Args: []string{"work", "--profile", dir}, Stderr: stderr}
+	equals(t, Exec(ctx, prog.Compile()), ExitSuccess)
+	for _, name := range []string{"cpu.pprof", "heap.pprof"} {
+		if info, err := os.Stat(filepath.Join(dir, name)); err != nil || info.Size() == 0 {
+			t.Errorf("expected %s to be written", name)
+		}
+	}
+	equals(t, strings.HasPrefix(stderr.String(), "wrote profiles to `"+dir+"` after "), true)
+
+	code, err := RunArgs("prog", "", []string{"work"}, prog.Compile())
+	equals(t, code, ExitSuccess)
+	equals(t, err, nil)
+}
+
+func TestAliases(t *testing.T) {
+	root, err := ioutil.TempDir("", "flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	file := filepath.Join(root, "aliases")
+	content := "# shorthands\nst = status --short\n\nlg = log --format '%h %s'\nstatus = log\n"
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	prog := NewProgram()
+	for _, name := range []string{"status", "log"} {
+		prog.Add(name, name, func(ctx *Context) error {
+			fmt.Fprintln(ctx.Stdout, ctx.Name, ctx.Args)
+			return nil
+		})
+	}
+	equals(t, prog.LoadAliases(file), nil)
+	equals(t, prog.LoadAliases(filepath.Join(root, "missing")), nil)
+	prog.AddAlias("s", "st")
+
+	run := func(args ...string) string {
+		stdout := &bytes.Buffer{}
+		ctx := &Context{Name: "prog", Args: args, Stdout: stdout}
+		Exec(ctx, prog.Compile())
+		return stdout.String()
+	}
+	equals(t, run("st", "-b"), "prog status [--short -b]\n")
+	equals(t, run("lg"), "prog log [--format %h %s]\n")
+	equals(t, run("status"), "prog status []\n")
+	equals(t, run("s"), "")
+	equals(t, run(CompleteCommand, "l"), "lg\nlog\n")
+
+	if err := ioutil.WriteFile(file, []byte("st status\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := prog.LoadAliases(file); err == nil {
+		t.Error("prog.LoadAliases = nil, want error")
+	}
+}
+
+func TestRename(t *testing.T) {
+	prog := NewProgram()
+	prog.Add("remove", "remove an item", func(ctx *Context) error {
+		fmt.Fprintln(ctx.Stdout, ctx.Name, ctx.Args)
+		return nil
+	})
+	prog.Rename("rm", "remove")
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	ctx := &Context{Name: "prog", Args: []string{"rm", "a"}, Stdout: stdout, Stderr: stderr}
+	equals(t, Exec(ctx, prog.Compile()), ExitSuccess)
+	equals(t, stdout.String(), "prog remove [a]\n")
+	equals(t, stderr.String(), "`rm` is deprecated, use `remove` instead\n")
+
+	equals(t, ListCommands(prog.snapshot()), strings.Join([]string{
+		"available commands:",
+		"  remove  remove an item",
+		"  rm      deprecated, use `remove` instead",
+	}, "\n"))
+}
+
+func TestPath(t *testing.T) {
+	var path []string
+	var root string
+	sub := NewProgram()
+	sub.Add("add", "add a remote", func(ctx *Context) error {
+		path, root = ctx.Path(), ctx.Root()
+		return nil
+	})
+	prog := NewProgram()
+	prog.AddProgram("remote", "manage remotes", sub)
+
+	ctx := &Context{Name: "my prog", Args: []string{"remote", "add"}}
+	equals(t, Exec(ctx, prog.Compile()), ExitSuccess)
+	equals(t, path, []string{"my prog", "remote", "add"})
+	equals(t, root, "my prog")
+	equals(t, ctx.Path(), []string{"my prog"})
+}
+
+func TestShortUsage(t *testing.T) {
+	pos, opt := Args()
+	pos.Register("sources", NewStringSliceValue(nil), "source files")
+	pos.Arity("sources", 1, Unbounded)
+	pos.String("dest", "destination")
+	opt.Switch('f', "force", "overwrite files")
+	equals(t, ShortUsage(pos, opt), "[flags] SOURCES... DEST")
+	equals(t, ShortUsage(nil, nil), "[flags]")
+
+	CompactUsage = true
+	defer func() { CompactUsage = false }()
+	cmd := func(ctx *Context) error {
+		pos, opt := Args()
+		pos.String("source", "source file")
+		pos.String("dest", "destination file")
+		return ctx.Parse(pos, opt)
+	}
+	stderr := &bytes.Buffer{}
+	ctx := &Context{Name: "mytool copy", Args: []string{"a"}, Stderr: stderr}
+	equals(t, Exec(ctx, cmd), ExitUsage)
+	equals(t, strings.HasSuffix(stderr.String(), "usage: mytool copy [flags] SOURCE DEST\n"), true)
+}
+
+type testIPNetValue struct{ n *net.IPNet }
+
+func (v *testIPNetValue) Set(s string) error {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		return err
+	}
+	v.n = n
+	return nil
+}
+
+func (v *testIPNetValue) Get() *net.IPNet { return v.n }
+
+func (v *testIPNetValue) String() string {
+	if v.n == nil {
+		return ""
+	}
+	return v.n.String()
+}
+
+func TestValueRegistry(t *testing.T) {
+	RegisterValueType("ipnet", func() Value { return &testIPNetValue{} })
+	equals(t, strings.Contains(strings.Join(ValueTypes(), " "), "int ipnet"), true)
+
+	pos, opt := Args()
+	network := opt.OfType('n', "network", "ipnet", "10.0.0.0/8", "network to scan")
+	count := pos.OfType("count", "int", "number of hosts")
+	if err := NewParser(pos, opt).Parse([]string{"-n", "192.168.0.1/24", "3"}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, network.String(), "192.168.0.0/24")
+	equals(t, count.String(), "3")
+
+	if _, err := NewValue("unknown"); err == nil {
+		t.Error("NewValue(\"unknown\") = nil, want error")
+	}
+	panics(t, func() { opt.OfType(0, "mask", "ipnet", "bad", "mask") })
+
+	pos, opt = Args()
+	tags := opt.OfType('t', "tag", "strings", "a", "tags")
+	parser := NewParser(pos, opt)
+	if err := parser.Parse([]string{"-t", "b"}); err != nil {
+		t.Fatalf("parser.Parse: %v", err)
+	}
+	equals(t, tags.String(), "[a, b]")
+	equals(t, strings.Contains(Help(pos, opt), "tags (default: [a])"), true)
+	if err := parser.Parse(nil); err != nil {
+		t.Fatalf("parser.Parse: %v", err)
+	}
+	equals(t, tags.String(), "[a]")
+
+	var target struct {
+		Network *net.IPNet `type:"ipnet"`
+		Pages   []int      `type:"ranges"`
+		Mask    string     `type:"unknown"`
+	}
+	pos, opt = Args()
+	opt.Bind(0, "set", &target, "set a value")
+	parser = NewParser(pos, opt)
+	if err := parser.Parse([]string{"--set", "network=10.1.2.3/16", "--set", "pages=1-3,5"}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, target.Network.String(), "10.1.0.0/16")
+	equals(t, target.Pages, []int{1, 2, 3, 5})
+	for _, arg := range []string{"network=bad", "mask=x"} {
+		if err := parser.Parse([]string{"--set", arg}); err == nil {
+			t.Errorf("parser.Parse([]string{\"--set\", %q}) = nil, want error", arg)
+		}
+	}
+}
+
+func TestSignals(t *testing.T) {
+	defer func(d time.Duration) { GracePeriod = d }(GracePeriod)
+	GracePeriod = 10 * time.Millisecond
+
+	signals := make(chan os.Signal, 1)
+	started := make(chan struct{})
+	cmd := func(ctx *Context) error {
+		close(started)
+		<-ctx.Context().Done()
+		return ctx.Context().Err()
+	}
+	ctx := &Context{Name: "prog", Stderr: ioutil.Discard}
+	go func() {
+		<-started
+		signals <- os.Interrupt
+	}()
+	equals(t, execSignals(ctx, cmd, signals), 130)
+
+	stderr := &bytes.Buffer{}
+	block := make(chan struct{})
+	defer close(block)
+	ctx = &Context{Name: "prog", Stderr: stderr}
+	signals <- syscall.SIGTERM
+	equals(t, execSignals(ctx, func(ctx *Context) error { <-block; return nil }, signals), 143)
+	equals(t, strings.HasPrefix(stderr.String(), "command did not stop within 10ms"), true)
+
+	equals(t, execSignals(&Context{}, func(ctx *Context) error { return nil }, nil), ExitSuccess)
+}
+
+func TestBindValue(t *testing.T) {
+	type image struct {
+		Repository string
+		Tag        string
+	}
+	type values struct {
+		Replicas int
+		Image    image
+		Sidecar  *image
+		Timeout  time.Duration
+		Debug    bool `json:"debug_mode"`
+		Ports    []uint16
+		Labels   map[string]string
+		Limits   map[string]image
+		Address  netip.Addr
+		hidden   string
+	}
+
+	target := values{Replicas: 1, Image: image{"nginx", "latest"}}
+	pos, opt := Args()
+	opt.Bind(0, "set", &target, "set a value")
+	parser := NewParser(pos, opt)
+	if err := parser.Parse([]string{
+		"--set", "replicas=3",
+		"--set", "image.tag=v2",
+		"--set", "sidecar.repository=envoy",
+		"--set", "timeout=30s",
+		"--set", "debug_mode=true",
+		"--set", "ports=80,443",
+		"--set", "labels.app=web",
+		"--set", "limits.cpu.tag=x",
+		"--set", "address=10.0.0.1",
+	}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, target.Replicas, 3)
+	equals(t, target.Image, image{"nginx", "v2"})
+	equals(t, *target.Sidecar, image{"envoy", ""})
+	equals(t, target.Timeout, 30*time.Second)
+	equals(t, target.Debug, true)
+	equals(t, target.Ports, []uint16{80, 443})
+	equals(t, target.Labels, map[string]string{"app": "web"})
+	equals(t, target.Limits, map[string]image{"cpu": {"", "x"}})
+	equals(t, target.Address, netip.MustParseAddr("10.0.0.1"))
+
+	if err := parser.Parse(nil); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+	}
+	equals(t, target.Replicas, 1)
+	equals(t, target.Image.Tag, "latest")
+
+	for _, arg := range []string{"replicas", "replicas=many", "missing=1", "hidden=x", "replicas.x=1", "ports=80,http"} {
+		if err := parser.Parse([]string{"--set", arg}); err == nil {
+			t.Errorf("parser.Parse([]string{\"--set\", %q}) = nil, want error", arg)
+		}
+	}
+	panics(t, func() { NewBindValue(target) })
+}
+
+func TestTokenize(t *testing.T) {
+	args := []string{"--name", "--name=a=b", "-abc", "-3", "-2.5e3", "-", "", "--=x", "--", "-v", "--name"}
+	kinds := []TokenKind{}
+	for _, tok := range Tokenize(args) {
+		kinds = append(kinds, tok.Kind)
+	}
+	equals(t, kinds, []TokenKind{
+		LongToken, LongToken, ShortToken, NumberToken, NumberToken,
+		ValueToken, ValueToken, LongToken, TerminatorToken, ValueToken, ValueToken,
+	})
+	tok := Tokenize([]string{"--name=a=b"})[0]
+	equals(t, tok.Name, "name")
+	equals(t, tok.Value, "a=b")
+	equals(t, tok.HasValue, true)
+	equals(t, Tokenize([]string{"-abc"})[0].Name, "abc")
+	equals(t, Tokenize([]string{"-1x"})[0].Kind, ShortToken)
+	equals(t, Tokenize([]string{"-0x10"})[0].Kind, NumberToken)
+	equals(t, Tokenize([]string{"-0b101"})[0].Kind, NumberToken)
+}
+
+func FuzzTokenize(f *testing.F) {
+	for _, arg := range []string{"--name", "--name=value", "-abc", "-3", "-.5", "-", "--", "--=", "-1e", "value"} {
+		f.Add(arg, "-v")
+	}
+	f.Fuzz(func(t *testing.T, a, b string) {
+		args := []string{a, b}
+		tokens := Tokenize(args)
+		if len(tokens) != len(args) {
+			t.Fatalf("Tokenize(%q) yielded %d tokens", args, len(tokens))
+		}
+		for i, tok := range tokens {
+			if tok.Raw != args[i] || tok.String() != args[i] {
+				t.Errorf("token %d of %q reads back as %q", i, args, tok.String())
+			}
+			switch tok.Kind {
+			case LongToken:
+				if strings.Contains(tok.Name, "=") {
+					t.Errorf("long token %q has a name containing `=`", tok.Raw)
+				}
+			case ShortToken, NumberToken:
+				if tok.Name == "" {
+					t.Errorf("short token %q has no name", tok.Raw)
+				}
+			case TerminatorToken:
+				if i == 1 && tokens[0].Kind == TerminatorToken {
+					t.Errorf("terminator %q follows a terminator", tok.Raw)
+				}
+			}
+		}
+	})
+}
+
+func TestNegativeNumbers(t *testing.T) {
+	pos, opt := Args()
+	threshold := opt.Float('t', "threshold", 0, "threshold")
+	name := opt.String('n', "name", "", "name")
+	offsets := opt.IntSlice('o', "offset", nil, "offsets")
+	one := opt.Switch('1', "one", "single column")
+	x := pos.Int("x", "x coordinate")
+	parser := NewParser(pos, opt)
+
+	if err := parser.Parse([]string{"-t", "-2.5", "--name", "-3", "-5"}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, *threshold, -2.5)
+	equals(t, *name, "-3")
+	equals(t, *x, -5)
+
+	if err := parser.Parse([]string{"-o", "-1", "-2", "--threshold=-1e3", "7"}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, *offsets, []int{-1, -2})
+	equals(t, *threshold, -1000.0)
+	equals(t, *x, 7)
+
+	// A number naming a short flag is the flag unless a number is expected.
+	if err := parser.Parse([]string{"-1", "4"}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+		return
+	}
+	equals(t, *one, true)
+	equals(t, *x, 4)
+	if err := parser.Parse([]string{"-n", "-1", "4"}); err == nil {
+		t.Error("parser.Parse([]string{\"-n\", \"-1\", \"4\"}) = nil, want error")
+	}
+	if err := parser.Parse([]string{"-t", "-1", "4"}); err != nil {
+		t.Errorf("parser.Parse: %v", err)
+	}
+	equals(t, *threshold, -1.0)
+	equals(t, *one, false)
+}
+
+func TestEncodedValue(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.txt")
+
+	write := func(args ...string) []byte {
+		pos, opt := Args()
+		out := opt.Encoded('o', "output", "encoding", nil, "utf-8", "output file")
+		if err := NewParser(pos, opt).Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		w, err := out.Writer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprint(w, "日本語")
+		if _, err := io.WriteString(out, "🍣"); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := out.Close(); err != nil {
+			t.Fatal(err)
+		}
+		p, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	equals(t, write("-o", path), []byte("日本語🍣"))
+	equals(t, write("--encoding", "Shift_JIS", "-o", path), []byte{0x93, 0xfa, 0x96, 0x7b, 0x8c, 0xea, 0x1a})
+	equals(t, write("-o", path, "--encoding", "cp932"), []byte{0x93, 0xfa, 0x96, 0x7b, 0x8c, 0xea, 0x1a})
+	equals(t, write("-o", path, "--encoding", "utf-16be"), []byte{0x65, 0xe5, 0x67, 0x2c, 0x8a, 0x9e, 0xd8, 0x3c, 0xdf, 0x63})
+
+	pos, opt := Args()
+	opt.Encoded('o', "output", "encoding", nil, "", "output file")
+	opt.Encoded(0, "log", "log-encoding", nil, "", "log file")
+	opt.Encoded(0, "report", "", nil, "", "report file")
+	err := NewParser(pos, opt).Parse([]string{"--encoding", "klingon"})
+	equals(t, err.Error(), "in flag `--encoding`: `klingon` is not a known character encoding")
+
+	init, err := os.Create(filepath.Join(dir, "init.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer init.Close()
+	out := NewEncodedValue(init, "utf-16le")
+	w, err := out.Writer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprint(w, "a")
+	equals(t, w.Close(), nil)
+	equals(t, out.Close(), nil)
+	_, err = init.WriteString("b")
+	equals(t, err, nil)
+	p, err := os.ReadFile(init.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	equals(t, p, []byte{'a', 0, 'b'})
+
+	value := NewEncodedValue(nil, "latin-1")
+	if err := value.Set(path); err != nil {
+		t.Fatal(err)
+	}
+	_, err = value.Writer()
+	equals(t, err.Error(), "`latin-1` is not a known character encoding")
+	equals(t, value.Close(), nil)
+}
+
+func TestBatch(t *testing.T) {
+	prog := NewProgram()
+	prog.Add("load", "load a table", func(ctx *Context) error {
+		pos, opt := Args()
+		table := pos.String("table", "table to load")
+		if err := ctx.Parse(pos, opt); err != nil {
+			return err
+		}
+		if *table == "broken" {
+			return errors.New("table is broken")
+		}
+		fmt.Fprintln(ctx.Stdout, "loaded", *table)
+		return nil
+	})
+
+	input := strings.Join([]string{
+		"# nightly load",
+		"load users",
+		"",
+		"load broken",
+		"load 'order items'",
+		"unknown",
+		"load --help",
+		"load \"unterminated",
+	}, "\n")
+	stdout := &bytes.Buffer{}
+	ctx := &Context{Name: "etl", Stdin: strings.NewReader(input), Stdout: stdout, Stderr: ioutil.Discard}
+	err := Batch(prog, nil)(ctx)
+	equals(t, strings.HasPrefix(stdout.String(), "loaded users\nloaded order items\nusage: etl load"), true)
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("expected joined errors, got %v", err)
+	}
+	lines := []int{}
+	for _, err := range joined.Unwrap() {
+		var e *BatchError
+		if errors.As(err, &e) {
+			lines = append(lines, e.Line)
+		}
+	}
+	equals(t, lines, []int{4, 6, 8})
+	equals(t, strings.Split(err.Error(), "\n")[:2], []string{
+		"line 4: table is broken",
+		"line 6: unknown command name `unknown`",
+	})
+
+	stdout.Reset()
+	ctx = &Context{Name: "etl", Stdout: stdout}
+	equals(t, Exec(ctx, Batch(prog, strings.NewReader("load a\nload b\n"))), ExitSuccess)
+	equals(t, stdout.String(), "loaded a\nloaded b\n")
+
+	prog.Add("drain", "read the standard input", func(ctx *Context) error {
+		p, err := ioutil.ReadAll(ctx.Stdin)
+		fmt.Fprintf(ctx.Stdout, "drained %d bytes\n", len(p))
+		return err
+	})
+	stdout.Reset()
+	ctx = &Context{Name: "etl", Stdin: strings.NewReader("drain\nload c\n"), Stdout: stdout}
+	equals(t, Exec(ctx, Batch(prog, nil)), ExitSuccess)
+	equals(t, stdout.String(), "drained 0 bytes\nloaded c\n")
+}
+
+func TestDefaultFrom(t *testing.T) {
+	pos, opt := Args()
+	input := pos.String("input", "markdown file")
+	output := opt.String('o', "output", "", "html file")
+	user := opt.String('u', "user", "", "author of the page")
+	tags := opt.StringSlice('t', "tag", nil, "tags of the page")
+	opt.DefaultFrom("output", "derived from INPUT", func() (string, error) {
+		return strings.TrimSuffix(*input, ".md") + ".html", nil
+	})
+	opt.DefaultFrom("user", "current user", func() (string, error) {
+		if *input == "anonymous.md" {
+			return "", errors.New("no user")
+		}
+		return "gopher", nil
+	})
+	opt.DefaultFrom("tag", "from INPUT", func() (string, error) {
+		return strings.TrimSuffix(*input, ".md"), nil
+	})
+	panics(t, func() { opt.DefaultFrom("missing", "", nil) })
+	parser := NewParser(pos, opt)
+
+	equals(t, parser.Parse([]string{"index.md"}), nil)
+	equals(t, *output, "index.html")
+	equals(t, *user, "gopher")
+	equals(t, *tags, []string{"index"})
+	equals(t, opt.Changed("output"), false)
+
+	equals(t, parser.Parse([]string{"about.md", "-o", "out.html", "-u", "ktnyt"}), nil)
+	equals(t, *output, "out.html")
+	equals(t, *user, "ktnyt")
+	equals(t, *tags, []string{"about"})
+
+	equals(t, parser.Parse([]string{"notes.md"}), nil)
+	equals(t, *output, "notes.html")
+	equals(t, *user, "gopher")
+	equals(t, *tags, []string{"notes"})
+
+	err := parser.Parse([]string{"anonymous.md"})
+	equals(t, err.Error(), "in flag `--user`: no user")
+
+	err = parser.Parse([]string{"index.md", "--bogus"})
+	equals(t, errors.Is(err, ErrUnknownFlag), true)
+	equals(t, *output, "")
+
+	help := Help(pos, opt)
+	equals(t, strings.Contains(help, "html file (default: derived from INPUT)"), true)
+	equals(t, strings.Contains(help, "author of the page (default: current user)"), true)
+}
@@ -0,0 +1,54 @@
+// Package flagstest provides utilities for testing commands built with flags.
+package flagstest
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	flags "gopkg.in/ktnyt/flags.v1"
+)
+
+// Result carries the captured output and exit status of a command.
+type Result struct {
+	Stdout string
+	Stderr string
+	Code   int
+}
+
+// Runner runs commands with a synthetic context.
+type Runner struct {
+	Name  string
+	Desc  string
+	Stdin io.Reader
+}
+
+// NewRunner creates a new Runner which feeds the given string to the command
+// as its standard input.
+func NewRunner(name, desc, stdin string) Runner {
+	return Runner{name, desc, strings.NewReader(stdin)}
+}
+
+// Run the command with the given arguments and capture its output.
+func (r Runner) Run(cmd flags.Command, args ...string) Result {
+	stdin := r.Stdin
+	if stdin == nil {
+		stdin = strings.NewReader("")
+	}
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	ctx := &flags.Context{
+		Name:   r.Name,
+		Desc:   r.Desc,
+		Args:   args,
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+	}
+	code := flags.Exec(ctx, cmd)
+	return Result{stdout.String(), stderr.String(), code}
+}
+
+// Run the command with the given arguments and empty standard input.
+func Run(cmd flags.Command, args ...string) Result {
+	return NewRunner("test", "", "").Run(cmd, args...)
+}
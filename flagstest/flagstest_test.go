@@ -0,0 +1,44 @@
+package flagstest
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	flags "gopkg.in/ktnyt/flags.v1"
+)
+
+func TestRun(t *testing.T) {
+	cmd := func(ctx *flags.Context) error {
+		pos, opt := flags.Args()
+		name := pos.String("name", "name to greet")
+		loud := opt.Switch('l', "loud", "greet loudly")
+		if err := ctx.Parse(pos, opt); err != nil {
+			return err
+		}
+		if *name == "nobody" {
+			return errors.New("nobody to greet")
+		}
+		p, err := ioutil.ReadAll(ctx.Stdin)
+		if err != nil {
+			return err
+		}
+		greeting := fmt.Sprintf("hello %s%s", *name, p)
+		if *loud {
+			greeting += "!"
+		}
+		fmt.Fprintln(ctx.Stdout, greeting)
+		return nil
+	}
+
+	res := NewRunner("greet", "greet someone", " and friends").Run(cmd, "-l", "world")
+	if res.Code != 0 || res.Stdout != "hello world and friends!\n" || res.Stderr != "" {
+		t.Errorf("unexpected result: %#v", res)
+	}
+
+	res = Run(cmd, "nobody")
+	if res.Code != 1 || res.Stdout != "" || res.Stderr != "nobody to greet\n" {
+		t.Errorf("unexpected result: %#v", res)
+	}
+}
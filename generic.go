@@ -0,0 +1,296 @@
+package flags
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scalar is the set of types supported by the generic value containers.
+type Scalar interface {
+	bool | string | int | int64 | uint | uint64 | float64 | time.Duration
+}
+
+func parseScalar[T Scalar](s string) (T, error) {
+	var v T
+	var err error
+	switch p := any(&v).(type) {
+	case *bool:
+		*p, err = strconv.ParseBool(s)
+	case *string:
+		*p = s
+	case *int:
+		*p, err = strconv.Atoi(s)
+	case *int64:
+		*p, err = strconv.ParseInt(s, 10, 64)
+	case *uint:
+		var u uint64
+		u, err = strconv.ParseUint(s, 10, strconv.IntSize)
+		*p = uint(u)
+	case *uint64:
+		*p, err = strconv.ParseUint(s, 10, 64)
+	case *float64:
+		*p, err = strconv.ParseFloat(s, 64)
+	case *time.Duration:
+		*p, err = time.ParseDuration(s)
+	}
+	if err != nil {
+		return v, fmt.Errorf(msg("`%s` cannot be interpreted as %T"), s, v)
+	}
+	return v, nil
+}
+
+// scalarName returns the name of the type as given by the value types which
+// predate Var, e.g. `float` for FloatValue.
+func scalarName[T Scalar]() string {
+	var v T
+	switch any(v).(type) {
+	case float64:
+		return "float"
+	case time.Duration:
+		return "duration"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// Var represents an argument value of type T. BoolValue, IntValue,
+// FloatValue, and StringValue are aliases of its instances. The zero value is
+// ready to use and holds the zero value of T.
+type Var[T Scalar] struct {
+	p *T
+}
+
+// New creates a new Var with the given initial value.
+func New[T Scalar](init T) *Var[T] {
+	p := new(T)
+	*p = init
+	return &Var[T]{p}
+}
+
+// ptr returns the pointer to the value, allocating it for a zero Var.
+func (v *Var[T]) ptr() *T {
+	if v.p == nil {
+		v.p = new(T)
+	}
+	return v.p
+}
+
+func (v *Var[T]) state() interface{} { return v.ptr() }
+
+// Get returns the current value.
+func (v *Var[T]) Get() T { return *v.ptr() }
+
+// Ptr returns a pointer to the underlying value.
+func (v *Var[T]) Ptr() *T { return v.ptr() }
+
+// Set will set attempt to convert the given string to a value.
+func (v *Var[T]) Set(s string) error {
+	x, err := parseScalar[T](s)
+	if err != nil {
+		return err
+	}
+	*v.ptr() = x
+	return nil
+}
+
+// String satisfies the fmt.Stringer interface.
+func (v *Var[T]) String() string {
+	return fmt.Sprint(*v.ptr())
+}
+
+// Type returns the name of the type of the value.
+func (v *Var[T]) Type() string {
+	return scalarName[T]()
+}
+
+// IsBoolFlag reports whether the value can be given as a switch.
+func (v *Var[T]) IsBoolFlag() bool {
+	_, ok := any(v.ptr()).(*bool)
+	return ok
+}
+
+// SliceVar represents a variable number argument value of type T.
+// StringSliceValue is an alias of SliceVar[string]. The zero value is ready
+// to use and holds no values.
+type SliceVar[T Scalar] struct {
+	p    *[]T
+	init []T
+}
+
+// Slice creates a new SliceVar with the given initial values.
+func Slice[T Scalar](init ...T) *SliceVar[T] {
+	p := new([]T)
+	*p = init
+	return &SliceVar[T]{p, init[:len(init):len(init)]}
+}
+
+// ptr returns the pointer to the slice, allocating it for a zero SliceVar.
+func (v *SliceVar[T]) ptr() *[]T {
+	if v.p == nil {
+		v.p = new([]T)
+	}
+	return v.p
+}
+
+// Get returns the current values.
+func (v *SliceVar[T]) Get() []T { return *v.ptr() }
+
+// Ptr returns a pointer to the underlying slice.
+func (v *SliceVar[T]) Ptr() *[]T { return v.ptr() }
+
+// Len will return the length of the slice value.
+func (v *SliceVar[T]) Len() int { return len(*v.ptr()) }
+
+// Set will set attempt to convert and append the given string to the slice.
+func (v *SliceVar[T]) Set(s string) error {
+	x, err := parseScalar[T](s)
+	if err != nil {
+		return err
+	}
+	p := v.ptr()
+	*p = append(*p, x)
+	return nil
+}
+
+// Reset restores the initial values.
+func (v *SliceVar[T]) Reset() { *v.ptr() = v.init }
+
+func (v *SliceVar[T]) adopt() {
+	p := v.ptr()
+	v.init = (*p)[:len(*p):len(*p)]
+}
+
+// DefaultString returns the string representation of the initial values.
+func (v *SliceVar[T]) DefaultString() string {
+	return (&SliceVar[T]{p: &v.init}).String()
+}
+
+// Type returns the name of the type of the value.
+func (v *SliceVar[T]) Type() string {
+	return scalarName[T]() + "slice"
+}
+
+// String satisfies the fmt.Stringer interface.
+func (v *SliceVar[T]) String() string {
+	xs := *v.ptr()
+	ss := make([]string, len(xs))
+	for i, x := range xs {
+		ss[i] = fmt.Sprint(x)
+	}
+	return fmt.Sprintf("[%s]", strings.Join(ss, ", "))
+}
+
+// MapVar represents a `key=value` argument value with values of type T.
+// Multiple pairs may be given in one argument separated by commas. The zero
+// value is ready to use and holds no entries.
+type MapVar[T Scalar] struct {
+	p    *map[string]T
+	init map[string]T
+}
+
+// Map creates a new MapVar with the given initial entries.
+func Map[T Scalar](init map[string]T) *MapVar[T] {
+	m := maps.Clone(init)
+	if m == nil {
+		m = make(map[string]T)
+	}
+	return &MapVar[T]{&m, maps.Clone(m)}
+}
+
+// ptr returns the pointer to the map, allocating it and the map for a zero
+// MapVar.
+func (v *MapVar[T]) ptr() *map[string]T {
+	if v.p == nil {
+		v.p = new(map[string]T)
+	}
+	if *v.p == nil {
+		*v.p = make(map[string]T)
+	}
+	return v.p
+}
+
+// Get returns the current entries.
+func (v *MapVar[T]) Get() map[string]T { return *v.ptr() }
+
+// Ptr returns a pointer to the underlying map.
+func (v *MapVar[T]) Ptr() *map[string]T { return v.ptr() }
+
+// Len will return the number of entries.
+func (v *MapVar[T]) Len() int { return len(*v.ptr()) }
+
+// Set will set attempt to convert and add the given pairs to the map.
+func (v *MapVar[T]) Set(s string) error {
+	for _, pair := range strings.Split(s, ",") {
+		i := strings.IndexByte(pair, '=')
+		if i <= 0 {
+			return fmt.Errorf(msg("`%s` is not a key=value pair"), pair)
+		}
+		key := pair[:i]
+		x, err := parseScalar[T](pair[i+1:])
+		if err != nil {
+			return fmt.Errorf(msg("for key `%s`: %v"), key, err)
+		}
+		(*v.ptr())[key] = x
+	}
+	return nil
+}
+
+// Reset restores the initial entries.
+func (v *MapVar[T]) Reset() { *v.ptr() = maps.Clone(v.init) }
+
+func (v *MapVar[T]) adopt() { v.init = maps.Clone(*v.ptr()) }
+
+// DefaultString returns the string representation of the initial entries.
+func (v *MapVar[T]) DefaultString() string {
+	return (&MapVar[T]{p: &v.init}).String()
+}
+
+// Type returns the name of the type of the value, such as stringToInt.
+func (v *MapVar[T]) Type() string {
+	name := scalarName[T]()
+	return "stringTo" + strings.ToUpper(name[:1]) + name[1:]
+}
+
+// String satisfies the fmt.Stringer interface.
+func (v *MapVar[T]) String() string {
+	m := *v.ptr()
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	ss := make([]string, len(keys))
+	for i, k := range keys {
+		ss[i] = fmt.Sprintf("%s=%v", k, m[k])
+	}
+	return fmt.Sprintf("[%s]", strings.Join(ss, ", "))
+}
+
+// StringToStringValue represents a `key=value` argument value.
+type StringToStringValue = MapVar[string]
+
+// StringToIntValue represents a `key=value` argument value with integer
+// values.
+type StringToIntValue = MapVar[int]
+
+// StringToBoolValue represents a `key=value` argument value with boolean
+// values.
+type StringToBoolValue = MapVar[bool]
+
+// NewStringToStringValue creates a new StringToStringValue.
+func NewStringToStringValue(init map[string]string) *StringToStringValue {
+	return Map(init)
+}
+
+// NewStringToIntValue creates a new StringToIntValue.
+func NewStringToIntValue(init map[string]int) *StringToIntValue {
+	return Map(init)
+}
+
+// NewStringToBoolValue creates a new StringToBoolValue.
+func NewStringToBoolValue(init map[string]bool) *StringToBoolValue {
+	return Map(init)
+}
@@ -0,0 +1,45 @@
+package flags
+
+import (
+	goflag "flag"
+	"unicode/utf8"
+)
+
+// AddGoFlag registers a flag of the standard flag package. A flag with a
+// single character name is also registered as a shorthand.
+func (opt *Optional) AddGoFlag(f *goflag.Flag) {
+	var short rune
+	if utf8.RuneCountInString(f.Name) == 1 {
+		short, _ = utf8.DecodeRuneInString(f.Name)
+		if _, ok := opt.Alias[short]; ok || short == 'h' {
+			short = 0
+		}
+	}
+	opt.Register(short, f.Name, f.Value, f.Usage)
+}
+
+// AddGoFlagSet registers all of the flags in a flag set of the standard flag
+// package, such as goflag.CommandLine which is used by many libraries.
+func (opt *Optional) AddGoFlagSet(fs *goflag.FlagSet) {
+	fs.VisitAll(func(f *goflag.Flag) {
+		if !opt.Args.Has(f.Name) {
+			opt.AddGoFlag(f)
+		}
+	})
+}
+
+// GoFlagSet exports the optional argument definitions as a flag set of the
+// standard flag package. The flag set shares the values of the definitions,
+// and shorthands are registered as additional flags.
+func (opt *Optional) GoFlagSet(name string) *goflag.FlagSet {
+	fs := goflag.NewFlagSet(name, goflag.ContinueOnError)
+	opt.VisitAll(func(long string, arg Argument) {
+		fs.Var(arg.Value, long, arg.Usage)
+	})
+	for short, long := range opt.Alias {
+		if fs.Lookup(string(short)) == nil {
+			fs.Var(opt.Args[long].Value, string(short), opt.Args[long].Usage)
+		}
+	}
+	return fs
+}
@@ -17,24 +17,146 @@ func formatHelp(name, desc string) string {
 	return "  " + name + "\n                        " + desc
 }
 
-// ListCommands lists the commands registered to the given program.
+func positionalName(pos *Positional, name string) string {
+	return formatPositional(pos, name, pos.UpperNames)
+}
+
+func formatPositional(pos *Positional, name string, upperNames bool) string {
+	_, variadic := pos.Args[name].Value.(SliceValue)
+	if upperNames {
+		upper := strings.ToUpper(name)
+		switch {
+		case !variadic:
+			return upper
+		case pos.arity(name).Min > 0:
+			return upper + "..."
+		default:
+			return "[" + upper + "...]"
+		}
+	}
+	if variadic {
+		if pos.arity(name).Min > 0 {
+			return fmt.Sprintf("<%s> ...", name)
+		}
+		return fmt.Sprintf("[<%s> ...]", name)
+	}
+	return fmt.Sprintf("<%s>", name)
+}
+
+func arityHelp(a Arity) string {
+	switch {
+	case a.Min == a.Max:
+		return fmt.Sprintf(msg("exactly %d"), a.Min)
+	case a.Max == Unbounded:
+		return fmt.Sprintf(msg("at least %d"), a.Min)
+	case a.Min == 0:
+		return fmt.Sprintf(msg("at most %d"), a.Max)
+	default:
+		return fmt.Sprintf(msg("%d to %d"), a.Min, a.Max)
+	}
+}
+
+// ListCommands lists the commands registered to the given program, including
+// the commands of nested programs. Names and descriptions are aligned in
+// columns and descriptions are wrapped to fit the width of the terminal.
 func ListCommands(prog Program) string {
-	names := make([]string, len(prog.Map))
-	i := 0
-	for name := range prog.Map {
-		names[i] = name
-		i++
+	rows := listCommands(nil, prog, "")
+	width := 0
+	for _, row := range rows {
+		if n := len(row[0]); n > width && n <= maxCommandWidth {
+			width = n
+		}
+	}
+	indent := strings.Repeat(" ", width+4)
+	builder := strings.Builder{}
+	builder.WriteString(msg("available commands:"))
+	for _, row := range rows {
+		name, desc := row[0], row[1]
+		desc = wrap.Space(desc, descWidth(width+4))
+		desc = strings.ReplaceAll(desc, "\n", "\n"+indent)
+		builder.WriteString("\n  " + name)
+		if len(name) > width {
+			builder.WriteString("\n" + indent + desc)
+		} else {
+			builder.WriteString(strings.Repeat(" ", width-len(name)+2) + desc)
+		}
+	}
+	return builder.String()
+}
+
+// ListTopics creates a list of the help topics of the program with the first
+// line of each topic.
+func ListTopics(prog Program) string {
+	names := make([]string, 0, len(prog.Topics))
+	width := 0
+	for name := range prog.Topics {
+		names = append(names, name)
+		if len(name) > width && len(name) <= maxCommandWidth {
+			width = len(name)
+		}
 	}
 	sort.Strings(names)
 	builder := strings.Builder{}
-	builder.WriteString("available commands:")
+	builder.WriteString(msg("help topics:"))
 	for _, name := range names {
-		cmd := prog.Map[name]
-		builder.WriteString("\n" + formatHelp(name, cmd.Desc))
+		desc, _, _ := strings.Cut(strings.TrimSpace(prog.Topics[name]), "\n")
+		builder.WriteString("\n  " + name)
+		if len(name) > width {
+			builder.WriteString("\n" + strings.Repeat(" ", width+4) + desc)
+		} else {
+			builder.WriteString(strings.Repeat(" ", width-len(name)+2) + desc)
+		}
 	}
 	return builder.String()
 }
 
+// maxCommandWidth is the width of the command name column beyond which the
+// description is placed on the next line.
+const maxCommandWidth = 30
+
+func descWidth(indent int) int {
+	if n := terminalWidth() - indent; n > 20 {
+		return n
+	}
+	return 20
+}
+
+func listCommands(rows [][2]string, prog Program, prefix string) [][2]string {
+	for _, name := range prog.names() {
+		cmd := prog.Map[name]
+		rows = append(rows, [2]string{prefix + name, cmd.Desc})
+		if cmd.Prog != nil {
+			rows = listCommands(rows, cmd.Prog.snapshot(), prefix+name+" ")
+		}
+	}
+	olds := make([]string, 0, len(prog.Renamed))
+	for old := range prog.Renamed {
+		if _, ok := prog.Map[old]; !ok {
+			olds = append(olds, old)
+		}
+	}
+	sort.Strings(olds)
+	for _, old := range olds {
+		desc := fmt.Sprintf(msg("deprecated, use `%s` instead"), prog.Renamed[old])
+		rows = append(rows, [2]string{prefix + old, desc})
+	}
+	return rows
+}
+
+func formatExamples(examples []Example) string {
+	parts := []string{msg("examples:")}
+	for i, example := range examples {
+		if i > 0 {
+			parts = append(parts, "")
+		}
+		if example.Desc != "" {
+			parts = append(parts, "  # "+example.Desc)
+		}
+		parts = append(parts, "  $ "+example.Command)
+	}
+	return strings.Join(parts, "\n")
+}
+
 // Usage creates a usage string for the given argument definitions.
 func Usage(pos *Positional, opt *Optional) string {
 	builder := strings.Builder{}
@@ -44,7 +166,7 @@ func Usage(pos *Positional, opt *Optional) string {
 	}
 	if pos != nil {
 		for _, name := range pos.Order {
-			builder.WriteString(fmt.Sprintf(" <%s>", name))
+			builder.WriteString(" " + positionalName(pos, name))
 		}
 		if pos.In != nil {
 			builder.WriteString(" [<infile>]")
@@ -52,19 +174,51 @@ func Usage(pos *Positional, opt *Optional) string {
 		if pos.Out != nil {
 			builder.WriteString(" [<outfile>]")
 		}
+		if pos.Passthrough {
+			builder.WriteString(" [--] [<args> ...]")
+		}
 	}
 	return builder.String()
 }
 
+// CompactUsage makes commands show the usage created by ShortUsage instead of
+// the one created by Usage in their help and parse errors.
+var CompactUsage = false
+
+// ShortUsage creates a usage string for the given argument definitions in
+// the compact form common to Go programs, such as `[flags] SOURCE... DEST`.
+// The flags are summarized as `[flags]` and the positional arguments are
+// written in upper case regardless of the UpperNames field.
+func ShortUsage(pos *Positional, opt *Optional) string {
+	parts := []string{"[flags]"}
+	if pos != nil {
+		for _, name := range pos.Order {
+			parts = append(parts, formatPositional(pos, name, true))
+		}
+		if pos.In != nil {
+			parts = append(parts, "[INFILE]")
+		}
+		if pos.Out != nil {
+			parts = append(parts, "[OUTFILE]")
+		}
+		if pos.Passthrough {
+			parts = append(parts, "[-- ARGS...]")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
 // Help creaes a help string for the given argument definitions.
 func Help(pos *Positional, opt *Optional) string {
 	parts := []string{}
 	if pos != nil {
-		parts = append(parts, "\npositional arguments")
+		parts = append(parts, "\n"+msg("positional arguments"))
 		for _, name := range pos.Order {
 			usage := pos.Args[name].Usage
-			name = fmt.Sprintf("<%s>", name)
-			parts = append(parts, formatHelp(name, usage))
+			if a, ok := pos.Arities[name]; ok {
+				usage = fmt.Sprintf(msg("%s (%s values)"), usage, arityHelp(a))
+			}
+			parts = append(parts, formatHelp(positionalName(pos, name), usage))
 		}
 		if pos.In != nil {
 			usage := wrap.Space(pos.In.Usage, 55)
@@ -76,7 +230,7 @@ func Help(pos *Positional, opt *Optional) string {
 		}
 	}
 	if opt != nil {
-		parts = append(parts, "\noptional arguments:")
+		parts = append(parts, "\n"+msg("optional arguments:"))
 		names := []optionalName{}
 		for long := range opt.Args {
 			name := optionalName{0, long}
@@ -91,15 +245,22 @@ func Help(pos *Positional, opt *Optional) string {
 		for _, name := range names {
 			long, short := name.Long, name.Short
 			arg := opt.Args[long]
-			usage := fmt.Sprintf("%s (value: %s)", arg.Usage, arg.Value)
+			usage := arg.Usage
+			if a, ok := opt.Arities[long]; ok {
+				usage = fmt.Sprintf(msg("%s (%s values)"), usage, arityHelp(a))
+			}
+			if value, ok := opt.helpDefault(long); ok {
+				usage = fmt.Sprintf(msg("%s (default: %s)"), usage, value)
+			}
 			flag := ""
-			switch arg.Value.(type) {
-			case *BoolValue:
+			_, isSlice := arg.Value.(SliceValue)
+			switch {
+			case isBoolFlag(arg.Value):
 				flag = "--" + long
 				if short != 0 {
 					flag = fmt.Sprintf("-%c, %s", short, flag)
 				}
-			case SliceValue:
+			case isSlice:
 				flags := []string{}
 				if short != 0 {
 					flags = append(flags,
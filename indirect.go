@@ -0,0 +1,56 @@
+package flags
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ResolveIndirect resolves a value which may refer to where the actual value
+// is kept. A value of the form `file:path` is replaced by the contents of the
+// file at path without a trailing newline, `env:NAME` by the value of the
+// environment variable NAME, and `literal:text` by text, which allows values
+// starting with one of the prefixes to be given as is. Other values are
+// returned unchanged.
+func ResolveIndirect(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, "file:"):
+		path := strings.TrimPrefix(s, "file:")
+		p, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf(msg("cannot read value from file `%s`: %v"), path, err)
+		}
+		value := strings.TrimSuffix(string(p), "\n")
+		return strings.TrimSuffix(value, "\r"), nil
+	case strings.HasPrefix(s, "env:"):
+		name := strings.TrimPrefix(s, "env:")
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf(msg("environment variable `%s` is not set"), name)
+		}
+		return value, nil
+	case strings.HasPrefix(s, "literal:"):
+		return strings.TrimPrefix(s, "literal:"), nil
+	default:
+		return s, nil
+	}
+}
+
+// Indirect makes the flag with the given long name resolve its arguments with
+// ResolveIndirect, so that `--token file:/run/secrets/token` takes the value
+// from the file. Set the IndirectAll field to do so for every flag.
+func (opt *Optional) Indirect(long string) {
+	if !opt.Args.Has(long) {
+		panic(fmt.Errorf("optional argument with long name `%s` does not exist", long))
+	}
+	if opt.Indirections == nil {
+		opt.Indirections = make(map[string]bool)
+	}
+	opt.Indirections[long] = true
+}
+
+// indirect reports whether the arguments of the flag with the given long name
+// are resolved with ResolveIndirect.
+func (opt *Optional) indirect(long string) bool {
+	return opt.IndirectAll || opt.Indirections[long]
+}
@@ -0,0 +1,184 @@
+package flags
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+)
+
+// ArgumentInfo describes a positional or optional argument definition.
+type ArgumentInfo struct {
+	// Name is the name of a positional argument or the long name of a flag.
+	Name string `json:"name"`
+
+	// Short is the short name of a flag, if any.
+	Short string `json:"short,omitempty"`
+
+	// Type is the name of the expected type as given by TypeName.
+	Type string `json:"type"`
+
+	// Default is the string representation of the default value. It is
+	// masked for secret flags.
+	Default string `json:"default"`
+
+	// Usage is the description of the argument.
+	Usage string `json:"usage"`
+
+	// Variadic is set if the argument takes a variable number of values.
+	Variadic bool `json:"variadic,omitempty"`
+}
+
+// CommandInfo describes a command and its argument definitions, or a program
+// and its commands.
+type CommandInfo struct {
+	// Name is the full name of the command, e.g. `git remote add`.
+	Name string `json:"name"`
+
+	// Desc is the one line description of the command.
+	Desc string `json:"desc"`
+
+	// Doc is the additional documentation of the command.
+	Doc Doc `json:"doc"`
+
+	// Positionals are the positional argument definitions in order.
+	Positionals []ArgumentInfo `json:"positionals,omitempty"`
+
+	// Optionals are the optional argument definitions in lexicographical
+	// order of the long names.
+	Optionals []ArgumentInfo `json:"optionals,omitempty"`
+
+	// Commands are the commands of a program.
+	Commands []CommandInfo `json:"commands,omitempty"`
+}
+
+// errInspect is returned by commands which have described their arguments.
+var errInspect = Exit(ExitSuccess, nil)
+
+// Inspect describes the given command. The argument definitions of a command
+// are collected when it calls Context.Parse, which stops the command there,
+// so nothing past the call is run even if the command ignores the error.
+// Anything before the call is run, with an empty standard input, discarded
+// output, and a canceled context.Context, so commands should parse their
+// arguments before causing any side effects. A command which never calls
+// Parse is run in full and must not be inspected. Commands compiled from a
+// Program are described along with all of their subcommands.
+func Inspect(name, desc string, cmd Command) CommandInfo {
+	info := &CommandInfo{Name: name, Desc: desc}
+	inspectContext(info).run(cmd)
+	return *info
+}
+
+// inspectContext creates a context for filling the given description.
+func inspectContext(info *CommandInfo) *Context {
+	c, cancel := context.WithCancel(context.Background())
+	cancel()
+	return &Context{
+		Name:   info.Name,
+		Desc:   info.Desc,
+		Stdin:  strings.NewReader(""),
+		Stdout: ioutil.Discard,
+		Stderr: ioutil.Discard,
+
+		doc:  info.Doc,
+		ctx:  c,
+		info: info,
+	}
+}
+
+// Inspect describes the program with the given name and description along
+// with all of its commands.
+func (prog *Program) Inspect(name, desc string) CommandInfo {
+	return Inspect(name, desc, prog.Compile())
+}
+
+// inspect fills the description of the command being inspected with the
+// argument definitions.
+func (ctx *Context) inspect(pos *Positional, opt *Optional) error {
+	info := ctx.info
+	info.Positionals, info.Optionals = []ArgumentInfo{}, []ArgumentInfo{}
+	if pos != nil {
+		for _, name := range pos.Order {
+			arg := pos.Args[name]
+			_, variadic := arg.Value.(SliceValue)
+			info.Positionals = append(info.Positionals, argumentInfo(name, "", arg, variadic))
+		}
+		if pos.In != nil {
+			info.Positionals = append(info.Positionals, argumentInfo("infile", "", *pos.In, false))
+		}
+		if pos.Out != nil {
+			info.Positionals = append(info.Positionals, argumentInfo("outfile", "", *pos.Out, false))
+		}
+	}
+	if opt != nil {
+		shorts := make(map[string]string)
+		for short, long := range opt.Alias {
+			shorts[long] = string(short)
+		}
+		opt.VisitAll(func(long string, arg Argument) {
+			_, variadic := arg.Value.(SliceValue)
+			a := argumentInfo(long, shorts[long], arg, variadic)
+			if c, ok := opt.ComputedDefaults[long]; ok {
+				a.Default = c.Desc
+			}
+			if opt.Secrets[long] {
+				a.Default = Masked
+			}
+			info.Optionals = append(info.Optionals, a)
+		})
+	}
+	return errInspect
+}
+
+func argumentInfo(name, short string, arg Argument, variadic bool) ArgumentInfo {
+	return ArgumentInfo{name, short, TypeName(arg.Value), DefaultString(arg.Value), arg.Usage, variadic}
+}
+
+// inspectProgram fills the description of the program being inspected with
+// the descriptions of its commands.
+func (ctx *Context) inspectProgram(prog Program) error {
+	info := ctx.info
+	if doc := prog.Doc; doc.Long != "" || doc.Synopsis != "" || len(doc.Examples) > 0 {
+		info.Doc = doc
+	}
+	info.Commands = []CommandInfo{}
+	for _, name := range prog.names() {
+		v := prog.Map[name]
+		sub := &CommandInfo{Name: ctx.Name + " " + name, Desc: v.Desc, Doc: v.Doc}
+		ctx := &Context{
+			Name:   sub.Name,
+			Desc:   v.Desc,
+			Stdin:  ctx.Stdin,
+			Stdout: ctx.Stdout,
+			Stderr: ctx.Stderr,
+
+			path: ctx.subPath(name),
+			doc:  v.Doc,
+			ctx:  ctx.ctx,
+			info: sub,
+		}
+		ctx.run(v.Cmd)
+		info.Commands = append(info.Commands, *sub)
+	}
+	return errInspect
+}
+
+// DescribeSchema is the version of the document written by DescribeJSON. It
+// is incremented whenever the document changes incompatibly.
+const DescribeSchema = 1
+
+// DescribeJSON describes the program with the given name and all of its
+// commands as a JSON document, running the commands up to Context.Parse as
+// Inspect does. The document is stable for a given program so that it can be
+// compared to detect changes to the command line interface.
+func DescribeJSON(name string, prog *Program) ([]byte, error) {
+	doc := struct {
+		Schema int `json:"schema"`
+		CommandInfo
+	}{DescribeSchema, prog.Inspect(name, "")}
+	p, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(p, '\n'), nil
+}
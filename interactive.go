@@ -0,0 +1,45 @@
+package flags
+
+import (
+	"bufio"
+	"fmt"
+)
+
+// Interactive creates a command which reads command lines from the standard
+// input and dispatches them to the commands of the program until the input
+// ends or the `exit` command is given. The `help` command lists the commands
+// of the program, or shows the help of a command if a name is given.
+func Interactive(prog *Program) Command {
+	return func(ctx *Context) error {
+		ctx.setDefaults()
+		cmd := prog.Compile()
+		scanner := bufio.NewScanner(ctx.Stdin)
+
+		for {
+			fmt.Fprintf(ctx.Stdout, "%s> ", ctx.Name)
+			if !scanner.Scan() {
+				fmt.Fprintln(ctx.Stdout)
+				return scanner.Err()
+			}
+
+			args, err := Split(scanner.Text())
+			if err != nil {
+				ctx.report(err)
+				continue
+			}
+			if len(args) == 0 {
+				continue
+			}
+
+			switch args[0] {
+			case "exit", "quit":
+				return nil
+			case "help":
+				args = append(args[1:], "--help")
+			}
+
+			sub := ctx.child(ctx.Name, ctx.Desc, args)
+			sub.report(sub.run(cmd))
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package flags
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MaxIntRangeSetSize is the largest number of integers an IntRangeSetValue
+// may hold, guarding against expressions such as `0-1000000000`.
+const MaxIntRangeSetSize = 1 << 20
+
+// IntRangeSetValue represents a set of non-negative integers given as a
+// comma separated list of integers and inclusive ranges such as `1,3,5-9`,
+// as used for CPU lists and page selections. The integers are held sorted
+// without duplicates, so overlapping ranges are merged.
+type IntRangeSetValue struct {
+	p *[]int
+}
+
+// NewIntRangeSetValue creates a new IntRangeSetValue.
+func NewIntRangeSetValue(init []int) *IntRangeSetValue {
+	p := new([]int)
+	*p = normalizeInts(init)
+	return &IntRangeSetValue{p}
+}
+
+func (v *IntRangeSetValue) state() interface{} { return v.p }
+
+func normalizeInts(ints []int) []int {
+	ints = append([]int(nil), ints...)
+	sort.Ints(ints)
+	n := 0
+	for i, x := range ints {
+		if i == 0 || x != ints[n-1] {
+			ints[n] = x
+			n++
+		}
+	}
+	return ints[:n]
+}
+
+// ParseIntRangeSet parses a comma separated list of integers and inclusive
+// ranges into a sorted list of integers without duplicates.
+func ParseIntRangeSet(s string) ([]int, error) {
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		lo, hi, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(lo)
+		if err != nil || start < 0 {
+			return nil, fmt.Errorf(msg("`%s` is not a valid range in `%s`"), part, s)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(hi)
+			if err != nil || end < start {
+				return nil, fmt.Errorf(msg("`%s` is not a valid range in `%s`"), part, s)
+			}
+		}
+		if end-start >= MaxIntRangeSetSize {
+			return nil, fmt.Errorf(msg("`%s` exceeds %d integers"), part, MaxIntRangeSetSize)
+		}
+		for x := start; x <= end; x++ {
+			seen[x] = true
+		}
+		if len(seen) > MaxIntRangeSetSize {
+			return nil, fmt.Errorf(msg("`%s` exceeds %d integers"), s, MaxIntRangeSetSize)
+		}
+	}
+	ints := make([]int, 0, len(seen))
+	for x := range seen {
+		ints = append(ints, x)
+	}
+	sort.Ints(ints)
+	return ints, nil
+}
+
+// FormatIntRangeSet formats a sorted list of integers without duplicates in
+// the most compact form accepted by ParseIntRangeSet.
+func FormatIntRangeSet(ints []int) string {
+	parts := []string{}
+	for i := 0; i < len(ints); {
+		j := i
+		for j+1 < len(ints) && ints[j+1] == ints[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, strconv.Itoa(ints[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", ints[i], ints[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
+
+// Set will set attempt to convert the given string to a value. The given
+// set replaces the current one.
+func (v *IntRangeSetValue) Set(s string) error {
+	ints, err := ParseIntRangeSet(s)
+	if err != nil {
+		return err
+	}
+	*v.p = ints
+	return nil
+}
+
+// Get returns the integers in the set in ascending order.
+func (v *IntRangeSetValue) Get() []int { return *v.p }
+
+// Contains reports whether the integer is in the set.
+func (v *IntRangeSetValue) Contains(x int) bool {
+	i := sort.SearchInts(*v.p, x)
+	return i < len(*v.p) && (*v.p)[i] == x
+}
+
+// Type returns the name of the value type.
+func (v *IntRangeSetValue) Type() string { return "ranges" }
+
+// String satisfies the fmt.Stringer interface.
+func (v *IntRangeSetValue) String() string {
+	return FormatIntRangeSet(*v.p)
+}
+
+// IntRangeSet adds a flag taking a set of integers given as ranges such as
+// `1,3,5-9` to the optional argument list.
+func (opt *Optional) IntRangeSet(short rune, long string, init []int, usage string) *[]int {
+	value := NewIntRangeSetValue(init)
+	opt.Register(short, long, value, usage)
+	return value.p
+}
+
+// IntRangeSet adds a set of integers given as ranges such as `1,3,5-9` to
+// the positional argument list.
+func (pos *Positional) IntRangeSet(name, usage string) *[]int {
+	value := NewIntRangeSetValue(nil)
+	pos.Register(name, value, usage)
+	return value.p
+}
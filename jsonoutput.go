@@ -0,0 +1,139 @@
+package flags
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+// JSONOutput makes commands write their help and report errors as JSON
+// documents instead of prose, for command line interfaces driven by other
+// programs such as IDE integrations. The help is written to the standard
+// output stream as a CommandInfo and errors are written to the standard error
+// stream as an ErrorInfo, each as a single line.
+var JSONOutput = false
+
+// ErrorInfo describes an error reported by a command.
+type ErrorInfo struct {
+	// Code is the exit status code for the error.
+	Code int `json:"code"`
+
+	// Message is the error message without the usage.
+	Message string `json:"message"`
+
+	// Usage is the usage line of the command if the error occurred while
+	// parsing its arguments.
+	Usage string `json:"usage,omitempty"`
+
+	// Errors describe the individual parse errors, if any.
+	Errors []ParseErrorInfo `json:"errors,omitempty"`
+
+	// Crash is the crash report if the command panicked.
+	Crash *CrashReport `json:"crash,omitempty"`
+}
+
+// ParseErrorInfo describes a ParseError.
+type ParseErrorInfo struct {
+	// Kind identifies the cause of the error, such as `unknown_flag`.
+	Kind string `json:"kind"`
+
+	// Name is the name of the offending flag or positional argument.
+	Name string `json:"name,omitempty"`
+
+	// Input is the raw input which caused the error.
+	Input string `json:"input,omitempty"`
+
+	// Type is the expected type of the value.
+	Type string `json:"type,omitempty"`
+
+	// Suggestions are the names the input may have been intended as.
+	Suggestions []string `json:"suggestions,omitempty"`
+
+	// Message is the error message.
+	Message string `json:"message"`
+}
+
+var parseErrorKinds = map[error]string{
+	ErrUnknownFlag:        "unknown_flag",
+	ErrAmbiguousFlag:      "ambiguous_flag",
+	ErrMissingValue:       "missing_value",
+	ErrNotBoolean:         "not_boolean",
+	ErrMissingArgument:    "missing_argument",
+	ErrExtraneousArgument: "extraneous_argument",
+	ErrRequiredFlag:       "required_flag",
+	ErrExclusiveFlags:     "exclusive_flags",
+}
+
+// NewErrorInfo describes the given error.
+func NewErrorInfo(err error) ErrorInfo {
+	info := ErrorInfo{Code: ExitCode(err), Message: err.Error()}
+	var u *parseUsageError
+	if errors.As(err, &u) {
+		info.Message, info.Usage = u.err.Error(), u.usage
+	}
+	var p *PanicError
+	if errors.As(err, &p) {
+		info.Message, info.Crash = p.Report.Panic, &p.Report
+	}
+	for _, err := range parseErrors(err) {
+		switch e := err.(type) {
+		case *ParseError:
+			kind, ok := parseErrorKinds[e.Err]
+			if !ok {
+				kind = "invalid_value"
+			}
+			info.Errors = append(info.Errors, ParseErrorInfo{kind, e.Name, e.Input, e.Type, e.Suggestions, e.Error()})
+		case *ConstraintError:
+			kind := parseErrorKinds[e.Err]
+			name := strings.Join(e.Flags, " ")
+			info.Errors = append(info.Errors, ParseErrorInfo{kind, name, "", "", nil, e.Error()})
+		}
+	}
+	return info
+}
+
+// parseErrors collects the parse and constraint errors in the tree of the
+// error.
+func parseErrors(err error) []error {
+	switch e := err.(type) {
+	case *ParseError, *ConstraintError:
+		return []error{e}
+	case interface{ Unwrap() []error }:
+		errs := []error{}
+		for _, err := range e.Unwrap() {
+			errs = append(errs, parseErrors(err)...)
+		}
+		return errs
+	case interface{ Unwrap() error }:
+		return parseErrors(e.Unwrap())
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// parseUsageError carries the usage line of the command along with the
+// errors encountered while parsing its arguments.
+type parseUsageError struct {
+	err   error
+	usage string
+}
+
+// Error satisfies the error interface.
+func (e *parseUsageError) Error() string {
+	return e.err.Error() + "\n" + e.usage
+}
+
+// Unwrap returns the parse errors.
+func (e *parseUsageError) Unwrap() error { return e.err }
+
+// writeHelpJSON writes the description of the command to the standard output
+// stream.
+func (ctx *Context) writeHelpJSON(pos *Positional, opt *Optional) error {
+	info := &CommandInfo{Name: ctx.Name, Desc: ctx.Desc, Doc: ctx.doc}
+	inspectContext(info).inspect(pos, opt)
+	return writeJSON(ctx.Stdout, info)
+}
@@ -0,0 +1,51 @@
+package flags
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// LevelValue represents a log level argument value. The names `debug`,
+// `info`, `warn` (or `warning`), and `error` are accepted regardless of case,
+// optionally with an offset such as `info+2`, as well as the numeric values
+// of the levels.
+type LevelValue slog.Level
+
+// NewLevelValue creates a new LevelValue.
+func NewLevelValue(init slog.Level) *LevelValue {
+	p := new(slog.Level)
+	*p = init
+	return (*LevelValue)(p)
+}
+
+// Set will set attempt to convert the given string to a value.
+func (p *LevelValue) Set(s string) error {
+	if n, err := strconv.Atoi(s); err == nil {
+		*p = LevelValue(n)
+		return nil
+	}
+	t := s
+	if strings.HasPrefix(strings.ToLower(t), "warning") {
+		t = "warn" + t[len("warning"):]
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(t)); err != nil {
+		return fmt.Errorf(msg("`%s` is not a valid log level, expected one of debug, info, warn, or error"), s)
+	}
+	*p = LevelValue(level)
+	return nil
+}
+
+// String satisfies the fmt.Stringer interface.
+func (p LevelValue) String() string {
+	return slog.Level(p).String()
+}
+
+// Level adds a log level flag to the optional argument list.
+func (opt *Optional) Level(short rune, long string, init slog.Level, usage string) *slog.Level {
+	value := NewLevelValue(init)
+	opt.Register(short, long, value, usage)
+	return (*slog.Level)(value)
+}
@@ -0,0 +1,135 @@
+package flags
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrInputTooLarge is returned by the reader of a LimitedValue when the input
+// exceeds one of its limits.
+var ErrInputTooLarge = errors.New("input is too large")
+
+// LimitedValue represents a file argument value for opening whose content is
+// read with limits on its size, protecting commands which read all of their
+// input from being fed far more than they expect. Reading past a limit fails
+// with an error wrapping ErrInputTooLarge. The value is a reader of the
+// content, so the limits cannot be bypassed by reading the file directly.
+type LimitedValue struct {
+	// File is the file read from, whose fields such as Lazy may be set
+	// before parsing.
+	File *OpenValue
+
+	// MaxBytes is the largest number of bytes which may be read. Zero or
+	// less means no limit.
+	MaxBytes int64
+
+	// MaxLines is the largest number of lines which may be read. Zero or
+	// less means no limit.
+	MaxLines int
+
+	r *limitedReader
+}
+
+// NewLimitedValue creates a new LimitedValue.
+func NewLimitedValue(init *os.File, maxBytes int64, maxLines int) *LimitedValue {
+	return &LimitedValue{File: NewOpenValue(init), MaxBytes: maxBytes, MaxLines: maxLines}
+}
+
+// Set will set attempt to convert the given string to a value.
+func (v *LimitedValue) Set(s string) error {
+	v.r = nil
+	return v.File.Set(s)
+}
+
+// String satisfies the fmt.Stringer interface.
+func (v *LimitedValue) String() string {
+	return v.File.String()
+}
+
+// Fd returns the file descriptor of the file.
+func (v *LimitedValue) Fd() uintptr {
+	return v.File.Fd()
+}
+
+// Reader returns the content of the file subject to the limits, opening the
+// file if it is pending.
+func (v *LimitedValue) Reader() (io.ReadCloser, error) {
+	if v.r != nil {
+		return v.r, nil
+	}
+	name := v.String()
+	f, err := v.File.Reader()
+	if err != nil {
+		return nil, err
+	}
+	v.r = &limitedReader{ReadCloser: f, name: name, maxBytes: v.MaxBytes, maxLines: v.MaxLines}
+	return v.r, nil
+}
+
+// Read reads the content of the file subject to the limits.
+func (v *LimitedValue) Read(p []byte) (int, error) {
+	r, err := v.Reader()
+	if err != nil {
+		return 0, err
+	}
+	return r.Read(p)
+}
+
+// Close the file if it was opened by the value. The initial file is left
+// open.
+func (v *LimitedValue) Close() error {
+	v.r = nil
+	return v.File.Close()
+}
+
+// Reset closes the file if it was opened by the value and restores the
+// initial file.
+func (v *LimitedValue) Reset() {
+	v.Close()
+	v.File.Reset()
+}
+
+type limitedReader struct {
+	io.ReadCloser
+	name     string
+	maxBytes int64
+	maxLines int
+	bytes    int64
+	lines    int
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	for i, b := range p[:n] {
+		switch {
+		case r.maxBytes > 0 && r.bytes >= r.maxBytes:
+			return i, fmt.Errorf(msg("%w: `%s` is longer than %d bytes"), ErrInputTooLarge, r.name, r.maxBytes)
+		case r.maxLines > 0 && r.lines >= r.maxLines:
+			return i, fmt.Errorf(msg("%w: `%s` is longer than %d lines"), ErrInputTooLarge, r.name, r.maxLines)
+		}
+		r.bytes++
+		if b == '\n' {
+			r.lines++
+		}
+	}
+	return n, err
+}
+
+// Limited adds a file for reading with limits on its size to the optional
+// argument list. The file will be closed after the command returns if parsed
+// with Context.Parse.
+func (opt *Optional) Limited(short rune, long string, init *os.File, maxBytes int64, maxLines int, usage string) *LimitedValue {
+	value := NewLimitedValue(init, maxBytes, maxLines)
+	opt.Register(short, long, value, usage)
+	return value
+}
+
+// LimitedInput adds a file with limits on its size which when omitted will
+// read from os.Stdin, like Input.
+func (pos *Positional) LimitedInput(maxBytes int64, maxLines int, usage string) *LimitedValue {
+	value := NewLimitedValue(os.Stdin, maxBytes, maxLines)
+	pos.In = &Argument{value, usage}
+	return value
+}
@@ -0,0 +1,190 @@
+package flags
+
+// Messages is the catalog of user-facing messages written by the package,
+// such as help section headers, parse errors, and the errors of values.
+// Keys are the default English format strings listed in MessageFormats and
+// values are their replacements, which must take the same formatting verbs.
+// Formats missing from the catalog are written as is. Panics reporting
+// misuse of the package and the texts of sentinel errors such as ErrHelp are
+// not included.
+//
+//	flags.Messages["available commands:"] = "利用可能なコマンド:"
+var Messages = map[string]string{}
+
+// MessageFormats lists the default formats of the messages which may be
+// replaced through Messages.
+var MessageFormats = []string{
+	// Commands.
+	"%s expected a command.",
+	"unknown command name `%s`",
+	"unknown command name `%s`, did you mean `%s`?",
+	"assuming you meant `%s` instead of `%s`",
+	"`%s` is deprecated, use `%s` instead",
+	"deprecated, use `%s` instead",
+	"available commands:",
+	"help topics:",
+	"examples:",
+	"usage: %s %s",
+
+	// Help.
+	"positional arguments",
+	"optional arguments:",
+	"%s (default: %s)",
+	"%s (%s values)",
+	"exactly %d",
+	"at least %d",
+	"at most %d",
+	"%d to %d",
+
+	// Parse errors.
+	"unknown flag `%s`",
+	"unknown flag `%s`, did you mean `%s`?",
+	"ambiguous flag `%s` could be `%s`",
+	"value not given for flag `%s`",
+	"flag `%s` in `%s` is not boolean",
+	"missing positional argument `%s`",
+	"extraneous argument `%s`",
+	"in flag `%s`: %v",
+	"in positional argument `%s`: %v",
+	"expected exactly %d value(s), got %d",
+	"expected at least %d value(s), got %d",
+	"expected at most %d value(s), got %d",
+	"flag `%s` requires `%s`",
+	"one of `%s` is required",
+	"flags `%s` cannot be given together",
+
+	// Seeds.
+	"using random seed %d for %s",
+
+	// Dry runs.
+	"dry run: would %s",
+
+	// Profiling.
+	"wrote profiles to `%s` after %s",
+
+	// Signals.
+	"command did not stop within %s after %s",
+
+	// Batches.
+	"line %d: %v",
+
+	// Crash reports.
+	"panic: %s",
+	"command: %s",
+	"args: %s",
+
+	// Confirmation.
+	"%s [y/N]: ",
+	"%w: give --%s to proceed",
+
+	// Values.
+	"`%s` cannot be interpreted as %T",
+	"`%s` cannot be interpreted as %T: %v",
+	"`%s` cannot be interpreted as %T written like `%s`",
+	"`%s` cannot be interpreted as a ratio",
+	"`%s` is out of range: expected a percentage from 0%% to 100%%",
+	"`%s` is out of range: expected a ratio from 0 to 1",
+	"`%s` is not a key=value pair",
+	"for key `%s`: %v",
+	"`%s` is not a valid file mode",
+	"`%s` is not a valid UUID",
+	"`%s` is not a valid regular expression: %v",
+	"`%s` is not a valid pattern: %v",
+	"`%s` is not a valid template: %v",
+	"`%s` is not valid JSON: %v",
+	"`%s` decodes to %d bytes, expected %d",
+	"`%s` is not valid base64",
+	"`%s` is not valid hexadecimal",
+	"`%s` is not a valid hex color",
+	"`%s` is not a valid %s color",
+	"`%s` is not a color",
+	"`%s` is not a date of the form YYYY-MM-DD",
+	"`%s` is not a date range of the form YYYY-MM-DD..YYYY-MM-DD",
+	"`%s` starts after it ends",
+	"`%s` does not have 5 or 6 fields",
+	"`%s` has an invalid %s field: %v",
+	"`%s` is not a number from %d to %d",
+	"`%s` is not a valid step",
+	"`%s` is a descending range",
+	"`%s` has invalid build metadata",
+	"`%s` has an invalid pre-release version",
+	"`%s` is not a semantic version",
+	"`%s` is not a valid address: %v",
+	"`%s` is not a valid port number in `%s`",
+	"`%s` is not a valid port number: expected an integer from %d to 65535",
+	"`%s` is not a valid range in `%s`",
+	"`%s` exceeds %d integers",
+	"`%s` is not a valid log level, expected one of debug, info, warn, or error",
+	"`%s` is not a valid seed: expected an integer or `%s`",
+	"`%s` is not a known time zone: expected an IANA name such as `UTC`, `Local`, or `Asia/Tokyo`",
+	"`%s` is not a known character encoding",
+	"`%s` is not a supported output format: expected one of `%s`",
+	"`%s` is not a supported output format",
+	"`%s` is not a valid command line: %v",
+
+	// Files.
+	"no file to read from",
+	"no file to write to",
+	"no files match `%s`",
+	"`%s` is a directory",
+	"`%s` is not a directory",
+	"`%s` already exists",
+	"`%s` is not writable",
+	"%w: `%s` is longer than %d bytes",
+	"%w: `%s` is longer than %d lines",
+	"`%s` has no digest, expected `path#sha256=hex`",
+	"`%s` is not a known digest algorithm",
+	"`%s` is not a valid %s digest",
+	"`%s` has %s digest `%x`, expected `%x`",
+
+	// Fetching.
+	"`%s` is not a valid URL",
+	"fetching `%s`: %s",
+	"fetching `%s`: size of %d bytes exceeds the limit of %d bytes",
+	"no resource to read from",
+	"reading `%s`: size exceeds the limit",
+
+	// Splitting command lines.
+	"trailing backslash",
+	"unterminated single quote",
+	"unterminated double quote",
+
+	// Response files.
+	"response files nested too deeply",
+	"in response file: %v",
+	"in response file `%s`: %v",
+
+	// Indirect values.
+	"cannot read value from file `%s`: %v",
+	"environment variable `%s` is not set",
+
+	// Aliases.
+	"%s:%d: expected `name = command`",
+	"%s:%d: %v",
+	"%s:%d: alias `%s` is empty",
+
+	// Binding.
+	"`%s` is not a path=value pair",
+	"cannot bind `%s`: %v",
+	"no field named `%s`",
+	"map keys of type %s are not supported",
+	"`%s` cannot be set within %s",
+	"`%s` cannot be interpreted as a duration",
+	"fields of type %s are not supported",
+	"`%s` cannot be interpreted as %s",
+
+	// Decoding.
+	"cannot decode argument `%s` into field `%s`: %v",
+	"unknown value type `%s`",
+
+	// Dumps.
+	"unknown dump format `%s`",
+}
+
+// msg returns the replacement of the format in Messages if any.
+func msg(format string) string {
+	if s, ok := Messages[format]; ok {
+		return s
+	}
+	return format
+}
@@ -0,0 +1,64 @@
+package flags
+
+// Mixin is a reusable set of flags, such as database connection flags, which
+// can be attached to the optional argument definitions of many commands. The
+// function registers the flags and returns the values bound to them, so that
+// each invocation of a command gets its own values.
+//
+//	var Database flags.Mixin[DBFlags] = func(opt *flags.Optional) *DBFlags {
+//		return &DBFlags{Host: opt.String(0, "db-host", "localhost", "database host")}
+//	}
+type Mixin[T any] func(opt *Optional) *T
+
+// Attach adds the flags of the mixin to the optional argument definitions and
+// returns the values bound to them. It panics if any of the flags is already
+// defined.
+func Attach[T any](opt *Optional, m Mixin[T]) *T {
+	return AttachPrefix(opt, "", m)
+}
+
+// AttachPrefix adds the flags of the mixin with the prefix prepended to their
+// long names, so that the same mixin may be attached more than once, e.g.
+// with `src-` and `dst-`. Short names are dropped if a prefix is given.
+func AttachPrefix[T any](opt *Optional, prefix string, m Mixin[T]) *T {
+	sub := newOptional()
+	values := m(sub)
+	shorts := make(map[string]rune, len(sub.Alias))
+	if prefix == "" {
+		for short, long := range sub.Alias {
+			shorts[long] = short
+		}
+	}
+	sub.VisitAll(func(long string, arg Argument) {
+		name := prefix + long
+		opt.Register(shorts[long], name, arg.Value, arg.Usage)
+		if f, ok := sub.Completions[long]; ok {
+			opt.Complete(name, f)
+		}
+		if a, ok := sub.Arities[long]; ok {
+			opt.Arity(name, a.Min, a.Max)
+		}
+		if sub.Indirections[long] {
+			opt.Indirect(name)
+		}
+		if sub.Secrets[long] {
+			opt.Secret(name)
+		}
+		if sub.HiddenDefaults[long] {
+			opt.HideDefault(name)
+		}
+		if c, ok := sub.ComputedDefaults[long]; ok {
+			opt.DefaultFrom(name, c.Desc, c.Func)
+		}
+	})
+	for _, c := range sub.constraints {
+		c := c
+		opt.constraints = append(opt.constraints, func(given func(string) bool, flag func(string) string) error {
+			return c(
+				func(long string) bool { return given(prefix + long) },
+				func(long string) string { return flag(prefix + long) },
+			)
+		})
+	}
+	return values
+}
@@ -0,0 +1,137 @@
+package flags
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// HostPortValue represents a `host:port` network address argument value.
+// IPv6 hosts are given in brackets, e.g. `[::1]:8080`.
+type HostPortValue struct {
+	p *string
+
+	// DefaultPort is used if the port is omitted. The port is required if
+	// DefaultPort is empty.
+	DefaultPort string
+}
+
+// NewHostPortValue creates a new HostPortValue.
+func NewHostPortValue(init, defaultPort string) *HostPortValue {
+	p := new(string)
+	*p = init
+	return &HostPortValue{p, defaultPort}
+}
+
+func (v *HostPortValue) state() interface{} { return v.p }
+
+// Set will set attempt to convert the given string to a value.
+func (v *HostPortValue) Set(s string) error {
+	host, port, err := net.SplitHostPort(s)
+	if err != nil && v.DefaultPort != "" {
+		host, port = s, v.DefaultPort
+		if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+			host = host[1 : len(host)-1]
+		}
+		if strings.ContainsAny(host, "[]") || (strings.Contains(host, ":") && net.ParseIP(host) == nil) {
+			return fmt.Errorf(msg("`%s` is not a valid address: %v"), s, err)
+		}
+		err = nil
+	}
+	if err != nil {
+		return fmt.Errorf(msg("`%s` is not a valid address: %v"), s, err)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		return fmt.Errorf(msg("`%s` is not a valid port number in `%s`"), port, s)
+	}
+	*v.p = net.JoinHostPort(host, port)
+	return nil
+}
+
+// Host returns the host part of the address.
+func (v *HostPortValue) Host() string {
+	host, _, _ := net.SplitHostPort(*v.p)
+	return host
+}
+
+// Port returns the port part of the address.
+func (v *HostPortValue) Port() string {
+	_, port, _ := net.SplitHostPort(*v.p)
+	return port
+}
+
+// String satisfies the fmt.Stringer interface.
+func (v *HostPortValue) String() string {
+	return *v.p
+}
+
+// HostPort adds a `host:port` network address flag to the optional argument
+// list. The default port is used if the port is omitted.
+func (opt *Optional) HostPort(short rune, long, init, defaultPort, usage string) *string {
+	value := NewHostPortValue(init, defaultPort)
+	opt.Register(short, long, value, usage)
+	return value.p
+}
+
+// HostPort adds a `host:port` network address to the positional argument
+// list. The default port is used if the port is omitted.
+func (pos *Positional) HostPort(name, defaultPort, usage string) *string {
+	value := NewHostPortValue("", defaultPort)
+	pos.Register(name, value, usage)
+	return value.p
+}
+
+// PortValue represents a network port number argument value in the range of
+// 1 to 65535.
+type PortValue struct {
+	p *uint16
+
+	// Unprivileged rejects the privileged ports below 1024.
+	Unprivileged bool
+}
+
+// NewPortValue creates a new PortValue.
+func NewPortValue(init uint16) *PortValue {
+	p := new(uint16)
+	*p = init
+	return &PortValue{p, false}
+}
+
+func (v *PortValue) state() interface{} { return v.p }
+
+// Set will set attempt to convert the given string to a value.
+func (v *PortValue) Set(s string) error {
+	min := 1
+	if v.Unprivileged {
+		min = 1024
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < min || n > 65535 {
+		return fmt.Errorf(msg("`%s` is not a valid port number: expected an integer from %d to 65535"), s, min)
+	}
+	*v.p = uint16(n)
+	return nil
+}
+
+// Type returns the name of the value type.
+func (v *PortValue) Type() string { return "port" }
+
+// String satisfies the fmt.Stringer interface.
+func (v *PortValue) String() string {
+	return strconv.Itoa(int(*v.p))
+}
+
+// Port adds a network port number flag to the optional argument list.
+func (opt *Optional) Port(short rune, long string, init uint16, usage string) *uint16 {
+	value := NewPortValue(init)
+	opt.Register(short, long, value, usage)
+	return value.p
+}
+
+// Port adds a network port number to the positional argument list.
+func (pos *Positional) Port(name, usage string) *uint16 {
+	value := NewPortValue(0)
+	pos.Register(name, value, usage)
+	return value.p
+}
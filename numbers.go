@@ -0,0 +1,199 @@
+package flags
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NumberFormat describes how numbers are written in a locale.
+type NumberFormat struct {
+	// Group separates groups of three digits in the integer part.
+	Group rune
+
+	// Decimal separates the integer part from the fraction.
+	Decimal rune
+}
+
+// Common number formats.
+var (
+	// EnglishNumbers writes numbers as `1,234.56`.
+	EnglishNumbers = &NumberFormat{',', '.'}
+
+	// EuropeanNumbers writes numbers as `1.234,56`, as in German or Italian.
+	EuropeanNumbers = &NumberFormat{'.', ','}
+
+	// FrenchNumbers writes numbers as `1 234,56`.
+	FrenchNumbers = &NumberFormat{' ', ','}
+
+	// SwissNumbers writes numbers as `1'234.56`.
+	SwissNumbers = &NumberFormat{'\'', '.'}
+)
+
+// Normalize converts the number written in the format into the plain form
+// understood by strconv. Group separators must separate groups of exactly
+// three digits. Strings without separators are returned as is, and false is
+// returned if the separators are misplaced.
+//
+// A number with a single `.` group separator and no decimal separator, such
+// as `1.234` in EuropeanNumbers, reads differently in the plain form and is
+// rejected as ambiguous. Write it as `1234` or `1.234,0` instead. Numbers
+// such as `0.5` are rejected as well since `.` is not a decimal separator in
+// these formats.
+func (f NumberFormat) Normalize(s string) (string, bool) {
+	if !strings.ContainsRune(s, f.Group) && !strings.ContainsRune(s, f.Decimal) {
+		return s, true
+	}
+	if f.Group == '.' && strings.Count(s, ".") == 1 && !strings.ContainsRune(s, f.Decimal) {
+		return s, false
+	}
+	sign, digits := "", s
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		sign, digits = digits[:1], digits[1:]
+	}
+	integer, fraction, hasFraction := strings.Cut(digits, string(f.Decimal))
+	groups := strings.Split(integer, string(f.Group))
+	for i, group := range groups {
+		n := len(group)
+		if n == 0 || n > 3 || (i > 0 && n != 3) || !isDigits(group) {
+			return s, false
+		}
+	}
+	if hasFraction && (fraction == "" || !isDigits(fraction)) {
+		return s, false
+	}
+	plain := sign + strings.Join(groups, "")
+	if hasFraction {
+		plain += "." + fraction
+	}
+	return plain, true
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// example returns 1234.56 written in the format.
+func (f NumberFormat) example() string {
+	return fmt.Sprintf("1%c234%c56", f.Group, f.Decimal)
+}
+
+// LocaleIntValue represents an integer argument value which may be written in
+// a number format of a locale as well as in the plain form.
+type LocaleIntValue struct {
+	p *int
+
+	// Format is the number format accepted in addition to the plain form.
+	// Only the plain form is accepted if nil.
+	Format *NumberFormat
+}
+
+// NewLocaleIntValue creates a new LocaleIntValue.
+func NewLocaleIntValue(init int, format *NumberFormat) *LocaleIntValue {
+	p := new(int)
+	*p = init
+	return &LocaleIntValue{p, format}
+}
+
+func (v *LocaleIntValue) state() interface{} { return v.p }
+
+// Set will set attempt to convert the given string to a value.
+func (v *LocaleIntValue) Set(s string) error {
+	plain, ok := s, true
+	if v.Format != nil {
+		plain, ok = v.Format.Normalize(s)
+	}
+	n, err := strconv.Atoi(plain)
+	if !ok || err != nil {
+		return numberError(s, n, v.Format)
+	}
+	*v.p = n
+	return nil
+}
+
+// String satisfies the fmt.Stringer interface.
+func (v *LocaleIntValue) String() string {
+	return strconv.Itoa(*v.p)
+}
+
+// LocaleFloatValue represents a float argument value which may be written in
+// a number format of a locale as well as in the plain form.
+type LocaleFloatValue struct {
+	p *float64
+
+	// Format is the number format accepted in addition to the plain form.
+	// Only the plain form is accepted if nil.
+	Format *NumberFormat
+}
+
+// NewLocaleFloatValue creates a new LocaleFloatValue.
+func NewLocaleFloatValue(init float64, format *NumberFormat) *LocaleFloatValue {
+	p := new(float64)
+	*p = init
+	return &LocaleFloatValue{p, format}
+}
+
+func (v *LocaleFloatValue) state() interface{} { return v.p }
+
+// Set will set attempt to convert the given string to a value.
+func (v *LocaleFloatValue) Set(s string) error {
+	plain, ok := s, true
+	if v.Format != nil {
+		plain, ok = v.Format.Normalize(s)
+	}
+	f, err := strconv.ParseFloat(plain, 64)
+	if !ok || err != nil {
+		return numberError(s, f, v.Format)
+	}
+	*v.p = f
+	return nil
+}
+
+// String satisfies the fmt.Stringer interface.
+func (v *LocaleFloatValue) String() string {
+	return strconv.FormatFloat(*v.p, 'g', -1, 64)
+}
+
+func numberError(s string, v interface{}, format *NumberFormat) error {
+	if format == nil {
+		return fmt.Errorf(msg("`%s` cannot be interpreted as %T"), s, v)
+	}
+	return fmt.Errorf(msg("`%s` cannot be interpreted as %T written like `%s`"), s, v, format.example())
+}
+
+// LocaleInt adds an integer flag which may be written in the given number
+// format to the optional argument list.
+func (opt *Optional) LocaleInt(short rune, long string, init int, format *NumberFormat, usage string) *int {
+	value := NewLocaleIntValue(init, format)
+	opt.Register(short, long, value, usage)
+	return value.p
+}
+
+// LocaleFloat adds a float flag which may be written in the given number
+// format to the optional argument list.
+func (opt *Optional) LocaleFloat(short rune, long string, init float64, format *NumberFormat, usage string) *float64 {
+	value := NewLocaleFloatValue(init, format)
+	opt.Register(short, long, value, usage)
+	return value.p
+}
+
+// LocaleInt adds an integer argument which may be written in the given
+// number format to the positional argument list.
+func (pos *Positional) LocaleInt(name string, format *NumberFormat, usage string) *int {
+	value := NewLocaleIntValue(0, format)
+	pos.Register(name, value, usage)
+	return value.p
+}
+
+// LocaleFloat adds a float argument which may be written in the given number
+// format to the positional argument list.
+func (pos *Positional) LocaleFloat(name string, format *NumberFormat, usage string) *float64 {
+	value := NewLocaleFloatValue(0, format)
+	pos.Register(name, value, usage)
+	return value.p
+}
@@ -0,0 +1,39 @@
+package flags
+
+import "time"
+
+// Observer is notified of the commands dispatched by a Program, for example
+// to collect usage metrics and error rates.
+type Observer interface {
+	// OnDispatch is called with the full name of the command and its
+	// arguments before the command runs.
+	OnDispatch(name string, args []string)
+
+	// OnComplete is called with the full name of the command, the error it
+	// returned, and the time it took after the command returns.
+	OnComplete(name string, err error, dur time.Duration)
+}
+
+// Observe adds observers notified of each command dispatched by the program.
+func (prog *Program) Observe(obs ...Observer) {
+	defer prog.lock()()
+	prog.Observers = append(prog.Observers, obs...)
+}
+
+// observe runs the command notifying the observers. Observers are not
+// notified when completing arguments.
+func observe(observers []Observer, ctx *Context, cmd Command) error {
+	if len(observers) == 0 || ctx.completing {
+		return ctx.run(cmd)
+	}
+	for _, o := range observers {
+		o.OnDispatch(ctx.Name, ctx.Args)
+	}
+	start := time.Now()
+	err := ctx.run(cmd)
+	dur := time.Since(start)
+	for _, o := range observers {
+		o.OnComplete(ctx.Name, err, dur)
+	}
+	return err
+}
@@ -3,6 +3,10 @@ package flags
 import (
 	"fmt"
 	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
 )
 
 var shortNames = []rune("#%123456789AaBbCcDdEeFfGgHhIiJjKkLlMmNnOoPpQqRrSsTtUuVvWwXxYyZz")
@@ -38,15 +42,400 @@ func (names byShort) Swap(i, j int) {
 type Optional struct {
 	Args  Arguments
 	Alias map[rune]string
+
+	// NoInterspersed stops flag parsing at the first positional argument so
+	// that the remaining arguments can be passed on to a wrapped command.
+	NoInterspersed bool
+
+	// Abbrev allows long flags to be given as an unambiguous prefix of the
+	// name, e.g. `--verb` for `--verbose`.
+	Abbrev bool
+
+	// Normalize maps flag names to a canonical form before they are compared,
+	// allowing different spellings such as `--max_count` and `--max-count`
+	// to resolve to the same flag.
+	Normalize func(name string) string
+
+	// AttachedValues allows the value of the last flag in a group of short
+	// flags to be attached to it as in getopt, e.g. `-n5` for `-n 5` and
+	// `-vofile.txt` for `-v -o file.txt`. The characters following a
+	// non-boolean short flag are always taken as its value, so they are no
+	// longer interpreted as further short flags.
+	AttachedValues bool
+
+	// SlashFlags enables the Windows-style `/flag` and `/flag:value` syntax
+	// in addition to the dash forms. Arguments starting with a slash which do
+	// not name a flag, such as absolute paths, are left as is.
+	SlashFlags bool
+
+	// ResponseFiles enables expanding `@path` arguments into the contents of
+	// the file at path before parsing. See ExpandResponseFiles.
+	ResponseFiles bool
+
+	// Completions maps long names to functions completing their values.
+	Completions map[string]CompleteFunc
+
+	// Arities maps long names of slice flags to the number of values they
+	// accept.
+	Arities map[string]Arity
+
+	// Indirections is the set of long names whose arguments are resolved
+	// with ResolveIndirect. See Indirect.
+	Indirections map[string]bool
+
+	// IndirectAll resolves the arguments of all flags with ResolveIndirect.
+	IndirectAll bool
+
+	// Secrets is the set of long names whose values are masked.
+	Secrets map[string]bool
+
+	// HiddenDefaults is the set of long names whose default values are not
+	// shown in the help. Defaults of secret flags are never shown.
+	HiddenDefaults map[string]bool
+
+	// ShowZeroDefaults shows default values which are the zero value of
+	// their type, such as an empty string or false, in the help. They are
+	// omitted otherwise.
+	ShowZeroDefaults bool
+
+	// ComputedDefaults maps long names to the defaults computed at parse
+	// time. See DefaultFrom.
+	ComputedDefaults map[string]ComputedDefault
+
+	changed  map[string]bool
+	defaults map[string]defaultState
+	index    *flagIndex
+
+	constraints []constraint
 }
 
 func newOptional() *Optional {
-	return &Optional{Arguments{}, make(map[rune]string)}
+	return &Optional{
+		Args:        Arguments{},
+		Alias:       make(map[rune]string),
+		Completions: make(map[string]CompleteFunc),
+		Arities:     make(map[string]Arity),
+		Secrets:     make(map[string]bool),
+
+		Indirections:     make(map[string]bool),
+		HiddenDefaults:   make(map[string]bool),
+		ComputedDefaults: make(map[string]ComputedDefault),
+
+		changed:  make(map[string]bool),
+		defaults: make(map[string]defaultState),
+	}
+}
+
+// Arity sets the number of values the slice flag with the given long name
+// accepts in a parse, not counting its initial values. Use Unbounded as max
+// to accept any number of values.
+func (opt *Optional) Arity(long string, min, max int) {
+	arg, ok := opt.Args[long]
+	if !ok {
+		panic(fmt.Errorf("optional argument with long name `%s` does not exist", long))
+	}
+	if _, ok := arg.Value.(SliceValue); !ok {
+		panic(fmt.Errorf("optional argument with long name `%s` is not a slice", long))
+	}
+	if min < 0 || (max != Unbounded && max < min) {
+		panic(fmt.Errorf("invalid arity %d to %d for optional argument `%s`", min, max, long))
+	}
+	if opt.Arities == nil {
+		opt.Arities = make(map[string]Arity)
+	}
+	opt.Arities[long] = Arity{min, max}
+}
+
+// lengths returns the number of values held by the slice flags with an
+// arity.
+func (opt *Optional) lengths() map[string]int {
+	if len(opt.Arities) == 0 {
+		return nil
+	}
+	lengths := make(map[string]int, len(opt.Arities))
+	for long := range opt.Arities {
+		if v, ok := opt.Args[long].Value.(SliceValue); ok {
+			lengths[long] = v.Len()
+		}
+	}
+	return lengths
+}
+
+// checkArities returns the errors for the slice flags given a number of
+// values outside of their arities since the lengths were taken.
+func (opt *Optional) checkArities(lengths map[string]int) []error {
+	longs := make([]string, 0, len(lengths))
+	for long := range lengths {
+		longs = append(longs, long)
+	}
+	sort.Strings(longs)
+	errs := []error{}
+	for _, long := range longs {
+		value := opt.Args[long].Value.(SliceValue)
+		if err := opt.Arities[long].Check(value.Len() - lengths[long]); err != nil {
+			errs = append(errs, &ParseError{"--" + long, "", TypeName(value), nil, err})
+		}
+	}
+	return errs
+}
+
+// HideDefault hides the default value of the flag with the given long name
+// from the help.
+func (opt *Optional) HideDefault(long string) {
+	if !opt.Args.Has(long) {
+		panic(fmt.Errorf("optional argument with long name `%s` does not exist", long))
+	}
+	opt.HiddenDefaults[long] = true
+}
+
+// recordDefaults remembers the values of flags not yet parsed as their
+// defaults.
+func (opt *Optional) recordDefaults() {
+	if opt.defaults == nil {
+		opt.defaults = make(map[string]defaultState)
+	}
+	if len(opt.defaults) == len(opt.Args) {
+		return
+	}
+	for long, arg := range opt.Args {
+		if _, ok := opt.defaults[long]; !ok {
+			opt.defaults[long] = recordDefault(arg.Value)
+		}
+	}
+}
+
+// Reset restores the flags given in the last parse to their defaults. It is
+// called before each parse, so that the flags can be parsed multiple times.
+func (opt *Optional) Reset() {
+	for long := range opt.changed {
+		if d, ok := opt.defaults[long]; ok && opt.Args.Has(long) {
+			d.restore(opt.Args[long].Value)
+		}
+	}
+	// Computed defaults are set without being marked as changed.
+	for long := range opt.ComputedDefaults {
+		if d, ok := opt.defaults[long]; ok && opt.Args.Has(long) {
+			d.restore(opt.Args[long].Value)
+		}
+	}
+	clear(opt.changed)
+}
+
+// helpDefault returns the default value of the flag to show in the help.
+func (opt *Optional) helpDefault(long string) (string, bool) {
+	if opt.Secrets[long] || opt.HiddenDefaults[long] {
+		return "", false
+	}
+	if c, ok := opt.ComputedDefaults[long]; ok {
+		return c.Desc, true
+	}
+	value := DefaultString(opt.Args[long].Value)
+	if d, ok := opt.defaults[long]; ok {
+		value = d.s
+	}
+	if !opt.ShowZeroDefaults && isZeroString(value) {
+		return "", false
+	}
+	return value, true
+}
+
+// isZeroString reports whether the string represents a zero value.
+func isZeroString(s string) bool {
+	switch s {
+	case "", "0", "false", "[]", "0s":
+		return true
+	}
+	return false
+}
+
+// Changed reports whether the flag with the given long name was given in the
+// last parse, as opposed to holding its default value.
+func (opt *Optional) Changed(long string) bool {
+	return opt.changed[long]
+}
+
+// Visit calls f for each flag given in the last parse in lexicographical
+// order of the long names.
+func (opt *Optional) Visit(f func(long string, arg Argument)) {
+	opt.VisitAll(func(long string, arg Argument) {
+		if opt.changed[long] {
+			f(long, arg)
+		}
+	})
+}
+
+// VisitAll calls f for each flag in lexicographical order of the long names.
+func (opt *Optional) VisitAll(f func(long string, arg Argument)) {
+	names := make([]string, 0, len(opt.Args))
+	for long := range opt.Args {
+		names = append(names, long)
+	}
+	sort.Strings(names)
+	for _, long := range names {
+		f(long, opt.Args[long])
+	}
+}
+
+// Complete registers a function for completing the values of the flag with
+// the given long name.
+func (opt *Optional) Complete(long string, f CompleteFunc) {
+	if !opt.Args.Has(long) {
+		panic(fmt.Errorf("optional argument with long name `%s` does not exist", long))
+	}
+	opt.Completions[long] = f
+}
+
+// Extensions restricts the completion of the file path taken by the flag with
+// the given long name, e.g. one backed by an OpenValue or a CreateValue, to
+// files with any of the given extensions and to directories.
+func (opt *Optional) Extensions(long string, exts ...string) {
+	opt.Complete(long, CompleteFiles(exts...))
+}
+
+// Lookup the registered long name for the given flag name, resolving
+// normalization and abbreviations if enabled. Names are looked up in constant
+// time and abbreviations in logarithmic time in the number of flags, so there
+// is no practical limit on the number of flags.
+func (opt *Optional) Lookup(long string) (string, error) {
+	if opt.Args.Has(long) {
+		return long, nil
+	}
+	idx := opt.lookupIndex()
+	key := opt.normalize(long)
+	if name, ok := idx.names[key]; ok {
+		return name, nil
+	}
+	if opt.Abbrev && long != "" {
+		candidates := []string{}
+		for i := sort.SearchStrings(idx.keys, key); i < len(idx.keys); i++ {
+			if !strings.HasPrefix(idx.keys[i], key) {
+				break
+			}
+			candidates = append(candidates, idx.names[idx.keys[i]])
+		}
+		switch len(candidates) {
+		case 0:
+		case 1:
+			return candidates[0], nil
+		default:
+			sort.Strings(candidates)
+			for i := range candidates {
+				candidates[i] = "--" + candidates[i]
+			}
+			flag := "--" + long
+			return "", &ParseError{flag, flag, "", candidates, ErrAmbiguousFlag}
+		}
+	}
+	flag := "--" + long
+	return "", &ParseError{flag, flag, "", opt.suggest(long), ErrUnknownFlag}
+}
+
+// flagIndex maps the normalized flag names to the long names. The keys are
+// kept sorted for looking up abbreviations.
+type flagIndex struct {
+	normalize uintptr
+	names     map[string]string
+	keys      []string
+}
+
+func funcPointer(f func(string) string) uintptr {
+	if f == nil {
+		return 0
+	}
+	return reflect.ValueOf(f).Pointer()
+}
+
+// lookupIndex returns the index of the flag names, rebuilding it if flags
+// were added to Args directly or the normalization function was changed.
+func (opt *Optional) lookupIndex() *flagIndex {
+	idx := opt.index
+	if idx != nil && len(idx.names) == len(opt.Args) && idx.normalize == funcPointer(opt.Normalize) {
+		return idx
+	}
+	idx = &flagIndex{
+		normalize: funcPointer(opt.Normalize),
+		names:     make(map[string]string, len(opt.Args)),
+		keys:      make([]string, 0, len(opt.Args)),
+	}
+	for long := range opt.Args {
+		key := opt.normalize(long)
+		idx.names[key] = long
+		idx.keys = append(idx.keys, key)
+	}
+	sort.Strings(idx.keys)
+	opt.index = idx
+	return idx
+}
+
+// insert adds a flag name to the index.
+func (idx *flagIndex) insert(key, long string) {
+	idx.names[key] = long
+	i := sort.SearchStrings(idx.keys, key)
+	idx.keys = append(idx.keys, "")
+	copy(idx.keys[i+1:], idx.keys[i:])
+	idx.keys[i] = key
+}
+
+// suggest returns the flags with names similar to the given name.
+func (opt *Optional) suggest(long string) []string {
+	names := make([]string, 0, len(opt.Args))
+	for name := range opt.Args {
+		names = append(names, name)
+	}
+	suggestions := similar(opt.normalize(long), names)
+	for i := range suggestions {
+		suggestions[i] = "--" + suggestions[i]
+	}
+	return suggestions
+}
+
+// translateSlash converts a Windows-style flag to its dash form.
+func (opt *Optional) translateSlash(arg string) string {
+	if !strings.HasPrefix(arg, "/") || len(arg) < 2 {
+		return arg
+	}
+	body := arg[1:]
+	if body == "?" {
+		return "--help"
+	}
+	name, value, hasValue := body, "", false
+	if i := strings.IndexAny(body, ":="); i >= 0 {
+		name, value, hasValue = body[:i], body[i+1:], true
+	}
+	long := ""
+	if rr := []rune(name); len(rr) == 1 {
+		long = opt.Alias[rr[0]]
+	}
+	if long == "" {
+		var err error
+		if long, err = opt.Lookup(name); err != nil {
+			return arg
+		}
+	}
+	if hasValue {
+		return "--" + long + "=" + value
+	}
+	return "--" + long
+}
+
+func (opt *Optional) normalize(name string) string {
+	if opt.Normalize == nil {
+		return name
+	}
+	return opt.Normalize(name)
+}
+
+// DashNormalize is a normalization function which maps underscores to dashes
+// and lowercases the flag name.
+func DashNormalize(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", "-"))
 }
 
 // Optional represents the optional command line arguments.
 func (opt *Optional) Register(short rune, long string, value Value, usage string) {
-	if opt.Args.Has(long) {
+	idx := opt.lookupIndex()
+	key := opt.normalize(long)
+	if _, ok := idx.names[key]; ok || opt.Args.Has(long) {
 		panic(fmt.Errorf("optional argument with long name `%s` already exists", long))
 	}
 	if _, ok := opt.Alias[short]; ok {
@@ -56,54 +445,133 @@ func (opt *Optional) Register(short rune, long string, value Value, usage string
 		opt.Alias[short] = long
 	}
 	opt.Args[long] = Argument{value, usage}
+	idx.insert(key, long)
 }
 
 // Switch adds a command line switch to the optional argument list.
 func (opt *Optional) Switch(short rune, long string, usage string) *bool {
 	value := NewBoolValue(false)
 	opt.Register(short, long, value, usage)
-	return (*bool)(value)
+	return value.p
 }
 
 // Int adds an integer flag to the optional argument list.
 func (opt *Optional) Int(short rune, long string, init int, usage string) *int {
 	value := NewIntValue(init)
 	opt.Register(short, long, value, usage)
-	return (*int)(value)
+	return value.p
 }
 
 // Float adds an float flag to the optional argument list.
 func (opt *Optional) Float(short rune, long string, init float64, usage string) *float64 {
 	value := NewFloatValue(init)
 	opt.Register(short, long, value, usage)
-	return (*float64)(value)
+	return value.p
 }
 
 // String adds a string flag to the optional argument list.
 func (opt *Optional) String(short rune, long, init, usage string) *string {
 	value := NewStringValue(init)
 	opt.Register(short, long, value, usage)
+	return value.p
+}
+
+// FileMode adds a file permission flag to the optional argument list.
+func (opt *Optional) FileMode(short rune, long string, init os.FileMode, usage string) *os.FileMode {
+	value := NewFileModeValue(init)
+	opt.Register(short, long, value, usage)
+	return (*os.FileMode)(value)
+}
+
+// UUID adds a UUID flag to the optional argument list.
+func (opt *Optional) UUID(short rune, long, init, usage string) *string {
+	value := NewUUIDValue(init)
+	opt.Register(short, long, value, usage)
 	return (*string)(value)
 }
 
-// Open adds a file for reading to the optional argument list.
-func (opt *Optional) Open(short rune, long string, init *os.File, usage string) *os.File {
+// Regexp adds a regular expression flag to the optional argument list.
+func (opt *Optional) Regexp(short rune, long string, init *regexp.Regexp, usage string) *regexp.Regexp {
+	value := NewRegexpValue(init)
+	opt.Register(short, long, value, usage)
+	return (*regexp.Regexp)(value)
+}
+
+// Open adds a file for reading to the optional argument list. The file will
+// be closed after the command returns if parsed with Context.Parse.
+func (opt *Optional) Open(short rune, long string, init *os.File, usage string) *OpenValue {
 	value := NewOpenValue(init)
 	opt.Register(short, long, value, usage)
-	return (*os.File)(value)
+	return value
 }
 
-// Create adds a file for writing to the positional argument list.
-func (opt *Optional) Create(short rune, long string, init *os.File, usage string) *os.File {
+// Create adds a file for writing to the optional argument list. The file will
+// be closed after the command returns if parsed with Context.Parse.
+func (opt *Optional) Create(short rune, long string, init *os.File, usage string) *CreateValue {
 	value := NewCreateValue(init)
 	opt.Register(short, long, value, usage)
-	return (*os.File)(value)
+	return value
+}
+
+// Dir adds an existing directory flag to the optional argument list.
+func (opt *Optional) Dir(short rune, long, init, usage string) *string {
+	value := NewDirValue(init)
+	opt.Register(short, long, value, usage)
+	return value.p
+}
+
+// OutputDir adds a writable directory flag to the optional argument list.
+// The directory will be created if it does not exist.
+func (opt *Optional) OutputDir(short rune, long, init, usage string) *string {
+	value := NewDirValue(init)
+	value.Create, value.Writable = true, true
+	opt.Register(short, long, value, usage)
+	return value.p
 }
 
 // StringSlice adds a string slice flag to the optional argument list.
 func (opt *Optional) StringSlice(short rune, long string, init []string, usage string) *[]string {
 	value := NewStringSliceValue(init)
 	opt.Register(short, long, value, usage)
+	return value.p
+}
+
+// StringSet adds a string set flag to the optional argument list. Repeated
+// values are collapsed into one.
+func (opt *Optional) StringSet(short rune, long string, init []string, usage string) *StringSetValue {
+	value := NewStringSetValue(init)
+	opt.Register(short, long, value, usage)
+	return value
+}
+
+// StringToString adds a `key=value` flag to the optional argument list.
+func (opt *Optional) StringToString(short rune, long string, init map[string]string, usage string) *map[string]string {
+	value := NewStringToStringValue(init)
+	opt.Register(short, long, value, usage)
+	return value.Ptr()
+}
+
+// StringToInt adds a `key=value` flag with integer values to the optional
+// argument list.
+func (opt *Optional) StringToInt(short rune, long string, init map[string]int, usage string) *map[string]int {
+	value := NewStringToIntValue(init)
+	opt.Register(short, long, value, usage)
+	return value.Ptr()
+}
+
+// StringToBool adds a `key=value` flag with boolean values to the optional
+// argument list.
+func (opt *Optional) StringToBool(short rune, long string, init map[string]bool, usage string) *map[string]bool {
+	value := NewStringToBoolValue(init)
+	opt.Register(short, long, value, usage)
+	return value.Ptr()
+}
+
+// Glob adds a file path slice flag to the optional argument list. Shell-style
+// patterns in the arguments are expanded to the matching paths.
+func (opt *Optional) Glob(short rune, long string, init []string, usage string) *[]string {
+	value := NewGlobSliceValue(init)
+	opt.Register(short, long, value, usage)
 	return (*[]string)(value)
 }
 
@@ -111,5 +579,5 @@ func (opt *Optional) StringSlice(short rune, long string, init []string, usage s
 func (opt *Optional) OpenSlice(short rune, long string, init []*os.File, usage string) *[]*os.File {
 	value := NewOpenSliceValue(init)
 	opt.Register(short, long, value, usage)
-	return (*[]*os.File)(value)
+	return value.p
 }
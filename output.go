@@ -0,0 +1,233 @@
+package flags
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Encoder writes a value in an output format.
+type Encoder func(w io.Writer, v interface{}) error
+
+// Encoders maps the names of the output formats to their encoders. Register
+// additional formats by adding to the map before parsing.
+var Encoders = map[string]Encoder{
+	"json":  EncodeJSON,
+	"yaml":  EncodeYAML,
+	"table": EncodeTable,
+}
+
+// DefaultOutputFormat is the format used by Context.Render if the command
+// has no output flag.
+var DefaultOutputFormat = "table"
+
+// EncodeJSON writes the value as indented JSON.
+func EncodeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// EncodeYAML writes the value as YAML.
+func EncodeYAML(w io.Writer, v interface{}) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// EncodeTable writes the value as a table with aligned columns. Slices of
+// structs or maps are written with a row per element and a column per field
+// or key, structs and maps with a row per field or key, and anything else as
+// is. Columns are named by the json tags of the fields if any.
+func EncodeTable(w io.Writer, v interface{}) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		columns := []string{}
+		if rv.Len() > 0 {
+			columns = tableColumns(rv.Index(0))
+		}
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(columns, "\t")))
+		for i := 0; i < rv.Len(); i++ {
+			fmt.Fprintln(tw, strings.Join(tableRow(rv.Index(i), columns), "\t"))
+		}
+	case reflect.Struct, reflect.Map:
+		columns := tableColumns(rv)
+		for i, cell := range tableRow(rv, columns) {
+			fmt.Fprintf(tw, "%s\t%s\n", strings.ToUpper(columns[i]), cell)
+		}
+	default:
+		fmt.Fprintln(tw, v)
+	}
+	return tw.Flush()
+}
+
+// fieldName returns the column name of the struct field or an empty string
+// if the field is not written.
+func fieldName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return f.Name
+	}
+	return name
+}
+
+func tableColumns(rv reflect.Value) []string {
+	rv = reflect.Indirect(rv)
+	columns := []string{}
+	switch rv.Kind() {
+	case reflect.Struct:
+		for i := 0; i < rv.NumField(); i++ {
+			if name := fieldName(rv.Type().Field(i)); name != "" {
+				columns = append(columns, name)
+			}
+		}
+	case reflect.Map:
+		for _, key := range rv.MapKeys() {
+			columns = append(columns, fmt.Sprint(key.Interface()))
+		}
+		sort.Strings(columns)
+	default:
+		columns = append(columns, "value")
+	}
+	return columns
+}
+
+func tableRow(rv reflect.Value, columns []string) []string {
+	rv = reflect.Indirect(rv)
+	cells := make([]string, len(columns))
+	switch rv.Kind() {
+	case reflect.Struct:
+		fields := make(map[string]reflect.Value)
+		for i := 0; i < rv.NumField(); i++ {
+			if name := fieldName(rv.Type().Field(i)); name != "" {
+				fields[name] = rv.Field(i)
+			}
+		}
+		for i, column := range columns {
+			if f, ok := fields[column]; ok {
+				cells[i] = fmt.Sprint(f.Interface())
+			}
+		}
+	case reflect.Map:
+		values := make(map[string]reflect.Value)
+		for _, key := range rv.MapKeys() {
+			values[fmt.Sprint(key.Interface())] = rv.MapIndex(key)
+		}
+		for i, column := range columns {
+			if x, ok := values[column]; ok {
+				cells[i] = fmt.Sprint(x.Interface())
+			}
+		}
+	default:
+		if rv.IsValid() {
+			cells[0] = fmt.Sprint(rv.Interface())
+		}
+	}
+	return cells
+}
+
+// OutputValue represents an output format argument value naming one of the
+// Encoders.
+type OutputValue string
+
+// NewOutputValue creates a new OutputValue.
+func NewOutputValue(init string) *OutputValue {
+	p := new(string)
+	*p = init
+	return (*OutputValue)(p)
+}
+
+func outputFormats() []string {
+	formats := make([]string, 0, len(Encoders))
+	for format := range Encoders {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// Set will set attempt to convert the given string to a value.
+func (p *OutputValue) Set(s string) error {
+	if _, ok := Encoders[s]; !ok {
+		return fmt.Errorf(msg("`%s` is not a supported output format: expected one of `%s`"), s, strings.Join(outputFormats(), "`, `"))
+	}
+	*p = OutputValue(s)
+	return nil
+}
+
+// Type returns the name of the value type.
+func (p *OutputValue) Type() string { return "format" }
+
+// String satisfies the fmt.Stringer interface.
+func (p OutputValue) String() string { return string(p) }
+
+// Encode writes the value to the writer in the output format.
+func (p OutputValue) Encode(w io.Writer, v interface{}) error {
+	enc, ok := Encoders[string(p)]
+	if !ok {
+		return fmt.Errorf(msg("`%s` is not a supported output format"), string(p))
+	}
+	return enc(w, v)
+}
+
+// Output adds the standard `-o, --output` format flag to the optional
+// argument list, completing the names of the Encoders.
+func (opt *Optional) Output(init string) *OutputValue {
+	value := NewOutputValue(init)
+	usage := fmt.Sprintf("output format (one of %s)", strings.Join(outputFormats(), ", "))
+	opt.Register('o', "output", value, usage)
+	opt.Complete("output", func(string) []string { return outputFormats() })
+	return value
+}
+
+// Render writes the value to the standard output stream of the context in
+// the format given by the output flag added with Optional.Output, or in
+// DefaultOutputFormat if the command has no such flag.
+func (ctx *Context) Render(v interface{}) error {
+	ctx.setDefaults()
+	format := OutputValue(DefaultOutputFormat)
+	if ctx.output != nil {
+		format = *ctx.output
+	}
+	return format.Encode(ctx.Stdout, v)
+}
+
+// Produce creates a command which renders the value returned by the function
+// with Context.Render.
+func Produce(f func(ctx *Context) (interface{}, error)) Command {
+	return func(ctx *Context) error {
+		v, err := f(ctx)
+		if err != nil {
+			return err
+		}
+		return ctx.Render(v)
+	}
+}
+
+// collectOutput remembers the output flag of the command, if any.
+func (ctx *Context) collectOutput(opt *Optional) {
+	ctx.output = nil
+	if opt == nil {
+		return
+	}
+	if arg, ok := opt.Args["output"]; ok {
+		ctx.output, _ = arg.Value.(*OutputValue)
+	}
+}
@@ -3,7 +3,9 @@ package flags
 import (
 	"errors"
 	"fmt"
+	"reflect"
 	"strings"
+	"unicode/utf8"
 )
 
 // ArgumentType represents the type of argument.
@@ -43,131 +45,351 @@ func NewParser(pos *Positional, opt *Optional) Parser {
 	return Parser{pos, opt}
 }
 
+func (parser Parser) flagError(name, input string, err error) error {
+	value := parser.Opt.Args[name].Value
+	return &ParseError{"--" + name, input, TypeName(value), nil, err}
+}
+
+// setFlag sets the value of the flag with the given long name.
+func (parser Parser) setFlag(name, s string) error {
+	input := s
+	if parser.Opt.indirect(name) {
+		var err error
+		if s, err = ResolveIndirect(s); err != nil {
+			return parser.flagError(name, input, err)
+		}
+	}
+	if err := parser.Opt.Args[name].Value.Set(s); err != nil {
+		return parser.flagError(name, input, err)
+	}
+	if Debug != nil {
+		trace("set", "flag", "--"+name, "value", parser.Opt.mask(name, s))
+	}
+	return nil
+}
+
+// setPositional sets the value of the positional argument with the given
+// name.
+func (parser Parser) setPositional(name string, value Value, s string) error {
+	if err := value.Set(s); err != nil {
+		return positionalError(name, s, value, err)
+	}
+	if Debug != nil {
+		trace("set", "positional", "<"+name+">", "value", s)
+	}
+	return nil
+}
+
+// holdsNumber reports whether the value, or each element of a slice value,
+// holds a number.
+func holdsNumber(v Value) bool {
+	rv := reflect.ValueOf(unwrapValue(v))
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	// Containers such as Var hold a pointer to the number.
+	if rv.Kind() == reflect.Struct && rv.NumField() > 0 && rv.Field(0).Kind() == reflect.Ptr {
+		rv = reflect.Zero(rv.Field(0).Type().Elem())
+	}
+	t := rv.Type()
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// isShort reports whether the negative number names a short flag by its
+// first digit.
+func (parser Parser) isShort(number string) bool {
+	r, _ := utf8.DecodeRuneInString(number[1:])
+	_, ok := parser.Opt.Alias[r]
+	return ok
+}
+
+// isValue reports whether the argument may be the value of a flag. Negative
+// numbers are values of numeric flags, and of any flag if no short flag is
+// named by their first digit.
+func (parser Parser) isValue(arg string, numeric bool) bool {
+	if TypeOf(arg) == ValueType {
+		return true
+	}
+	return isNegativeNumber(arg) && (numeric || !parser.isShort(arg))
+}
+
 func (parser Parser) handleValue(name string, args []string) ([]string, error) {
 	pos, opt := parser.Pos, parser.Opt
 	head := ""
 
-	switch v := opt.Args[name].Value.(type) {
-	// Do not accept value arguments behind boolean flags.
-	case *BoolValue:
-		*v = BoolValue(true)
+	opt.changed[name] = true
+	value := opt.Args[name].Value
+	if isBoolFlag(value) {
+		// Do not accept value arguments behind boolean flags.
+		return args, parser.setFlag(name, "true")
+	}
+	numeric := holdsNumber(value)
 
+	switch value.(type) {
 	case SliceValue:
+		// Take the leading values while leaving enough values behind for
+		// the positional arguments. Counting stops as soon as it is known
+		// that all of the leading values can be taken.
+		c := 0
+		for c < len(args) && parser.isValue(args[c], numeric) {
+			c++
+		}
 		n := 0
-		for _, arg := range args {
-			if TypeOf(arg) == ValueType {
+		for i, arg := range args {
+			if arg == "--" || n >= c+pos.Len() {
+				break
+			}
+			if i < c || parser.isValue(arg, false) {
 				n++
 			}
 		}
 
-		for TypeOf(args[0]) == ValueType && n > pos.Len() {
+		for ; c > 0 && n > pos.Len(); c, n = c-1, n-1 {
 			head, args = shift(args)
-			v.Set(head)
-			n--
+			if err := parser.setFlag(name, head); err != nil {
+				return args, err
+			}
 		}
 
 	default:
+		if len(args) == 0 || !parser.isValue(args[0], numeric) || args[0] == "--" {
+			return args, parser.flagError(name, "", ErrMissingValue)
+		}
 		head, args = shift(args)
-		if TypeOf(head) != ValueType {
-			return nil, fmt.Errorf("value not given for flag `--%s`", name)
+		if err := parser.setFlag(name, head); err != nil {
+			return args, err
 		}
-		v.Set(head)
 	}
 
 	return args, nil
 }
 
-var errHelp = errors.New("help")
+func positionalError(name, input string, value Value, err error) error {
+	return &ParseError{"<" + name + ">", input, TypeName(value), nil, err}
+}
+
+// ErrHelp is the error returned if the -h or --help flag is given but not
+// defined, or if a Program prints help for `help [command]` or a help topic.
+// Run will exit with ExitSuccess after the help has been printed.
+var ErrHelp = errors.New("flags: help requested")
 
-// Parse the given arguments using the argument definitions.
+// Parse the given arguments using the argument definitions. Flags and
+// positional arguments may be interleaved unless the NoInterspersed field of
+// the optional argument definitions is set. All arguments following a `--`
+// are treated as positional arguments. Parsing continues past errors so that
+// all of them are reported at once, joined in a single error.
 func (parser Parser) Parse(args []string) error {
+	if parser.Pos == nil {
+		parser.Pos = newPositional()
+	}
+	if parser.Opt == nil {
+		parser.Opt = newOptional()
+	}
 	pos, opt := parser.Pos, parser.Opt
-	optmap := make(map[string]string)
 	head := ""
-	extra := []string{}
+	extra := make([]string, 0, len(args))
+	errs := []error{}
+	if opt.changed == nil {
+		opt.changed = make(map[string]bool)
+	}
+	opt.Reset()
+	opt.recordDefaults()
+	lengths := opt.lengths()
+	pos.Reset()
+	pos.recordDefaults()
+	pos.rest = nil
+
+	if opt.ResponseFiles {
+		var err error
+		if args, err = ExpandResponseFiles(args); err != nil {
+			return err
+		}
+	}
+
+	// The trace shows the arguments with the values of secret flags masked.
+	var masked []string
+	if Debug != nil {
+		masked = opt.maskTrace(args)
+		if opt.ResponseFiles {
+			trace("expand", "args", masked)
+		}
+	}
 
 	for len(args) > 0 {
+		arg := ""
+		if Debug != nil {
+			arg = masked[len(masked)-len(args)]
+			trace("token", "arg", arg)
+		}
 		head, args = shift(args)
 
-		switch TypeOf(head) {
+		if opt.SlashFlags {
+			head = opt.translateSlash(head)
+		}
+		tok := lexArg(head)
+		if tok.Kind == NumberToken && !parser.isShort(head) {
+			tok.Kind = ValueToken
+		}
 
-		// Process long flag name.
-		case LongType:
-			long := head[2:]
+		switch tok.Kind {
+
+		case TerminatorToken:
+			if pos.Passthrough {
+				pos.rest = args
+			} else {
+				extra = append(extra, args...)
+			}
+			args = nil
 
-			if long == "help" {
-				return errHelp
+		// Process long flag name.
+		case LongToken:
+			if tok.Name == "help" && !opt.Args.Has("help") {
+				return ErrHelp
 			}
 
-			switch i := strings.IndexByte(head, '='); i {
-			case -1:
-				if !opt.Args.Has(long) {
-					return fmt.Errorf("unknown flag `--%s`", long)
+			switch {
+			case !tok.HasValue:
+				name, err := opt.Lookup(tok.Name)
+				if err != nil {
+					errs = append(errs, err)
+					continue
 				}
-				var err error
-				args, err = parser.handleValue(long, args)
+				if Debug != nil {
+					trace("match", "arg", arg, "flag", "--"+name)
+				}
+				args, err = parser.handleValue(name, args)
 				if err != nil {
-					return fmt.Errorf("in flag `--%s`: %v", long, err)
+					errs = append(errs, err)
 				}
 
 			// Flag has form `--long=value`.
 			default:
-				name, value := long[:i], long[i+1:]
-				if !opt.Args.Has(name) {
-					return fmt.Errorf("unknown flag `--%s`", name)
+				name, err := opt.Lookup(tok.Name)
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				if Debug != nil {
+					trace("match", "arg", arg, "flag", "--"+name)
+				}
+				opt.changed[name] = true
+				if err := parser.setFlag(name, tok.Value); err != nil {
+					errs = append(errs, err)
 				}
-				optmap[name] = value
 			}
 
 		// Process short flag name.
-		case ShortType:
-			rr := []rune(head[1:])
-			var r rune
+		case ShortToken, NumberToken:
+			rest := tok.Name
 
-			for len(rr) > 0 {
-				r, rr = rr[0], rr[1:]
+			for len(rest) > 0 {
+				r, size := utf8.DecodeRuneInString(rest)
+				rest = rest[size:]
 
-				if r == 'h' {
-					return errHelp
+				if _, ok := opt.Alias['h']; r == 'h' && !ok {
+					return ErrHelp
 				}
 
 				name, ok := opt.Alias[r]
 				if !ok {
-					return fmt.Errorf("unknown shorthand `%c`", r)
+					flag := fmt.Sprintf("-%c", r)
+					errs = append(errs, &ParseError{flag, head, "", nil, ErrUnknownFlag})
+					continue
+				}
+				if Debug != nil {
+					trace("match", "arg", arg, "flag", "--"+name)
 				}
 
-				switch len(rr) {
+				switch len(rest) {
 				// The last shorthand flag can be a non-boolean value
 				case 0:
 					var err error
 					args, err = parser.handleValue(name, args)
 					if err != nil {
-						return fmt.Errorf("in flag `--%s`: %v", name, err)
+						errs = append(errs, err)
 					}
 
 				default:
-					switch v := opt.Args[name].Value.(type) {
-					case *BoolValue:
-						*v = BoolValue(true)
-					default:
-						return fmt.Errorf("flag `%s for shorthand `%c` is not boolean", name, r)
+					value := opt.Args[name].Value
+					if !isBoolFlag(value) && opt.AttachedValues {
+						// The rest of the group is the value of the flag.
+						opt.changed[name] = true
+						if err := parser.setFlag(name, rest); err != nil {
+							errs = append(errs, err)
+						}
+						rest = ""
+						continue
+					}
+					if !isBoolFlag(value) {
+						errs = append(errs, parser.flagError(name, head, ErrNotBoolean))
+						continue
+					}
+					opt.changed[name] = true
+					if err := parser.setFlag(name, "true"); err != nil {
+						errs = append(errs, err)
 					}
 				}
 			}
 
 		// The argument is not associated to a flag.
 		default:
+			if pos.Passthrough && len(extra) == len(pos.Order) {
+				pos.rest = append([]string{head}, args...)
+				args = nil
+				continue
+			}
 			extra = append(extra, head)
+			if opt.NoInterspersed {
+				extra = append(extra, args...)
+				args = nil
+			}
 		}
 	}
 
 	for i, name := range pos.Order {
+		value := pos.Args[name].Value
+
+		// A variadic argument takes all values not needed by the rest.
+		if _, ok := value.(SliceValue); ok {
+			n := len(extra) - (len(pos.Order) - i - 1)
+			if n < 0 {
+				n = 0
+			}
+			if v, ok := value.(stdinValue); ok && n == 0 && v.useStdin() {
+				continue
+			}
+			if err := pos.arity(name).Check(n); err != nil {
+				input := strings.Join(extra[:n], " ")
+				errs = append(errs, positionalError(name, input, value, err))
+			}
+			for ; n > 0; n-- {
+				head, extra = shift(extra)
+				if err := parser.setPositional(name, value, head); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			continue
+		}
+
 		if len(extra) == 0 {
-			missing := strings.Join(pos.Order[i:], "`, `")
-			return fmt.Errorf("missing positional argument(s): `%s`", missing)
+			for _, name := range pos.Order[i:] {
+				value := pos.Args[name].Value
+				errs = append(errs, positionalError(name, "", value, ErrMissingArgument))
+			}
+			break
 		}
 		head, extra = shift(extra)
-		pos.Args[name].Value.Set(head)
+		if err := parser.setPositional(name, value, head); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
 	for len(extra) > 0 {
@@ -175,18 +397,25 @@ func (parser Parser) Parse(args []string) error {
 		case pos.needInput():
 			head, extra = shift(extra)
 			if err := pos.In.Value.Set(head); err != nil {
-				return fmt.Errorf("in positional input file: %v", err)
+				errs = append(errs, &ParseError{"<infile>", head, "file", nil, err})
 			}
 		case pos.needOutput():
 			head, extra = shift(extra)
 			if err := pos.Out.Value.Set(head); err != nil {
-				return fmt.Errorf("in positional output file: %v", err)
+				errs = append(errs, &ParseError{"<outfile>", head, "file", nil, err})
 			}
 		default:
-			extras := strings.Join(extra, "`, `")
-			return fmt.Errorf("extraneous arguments: `%s`", extras)
+			head, extra = shift(extra)
+			errs = append(errs, &ParseError{"", head, "", nil, ErrExtraneousArgument})
 		}
 	}
 
-	return nil
+	if len(errs) == 0 {
+		errs = parser.computeDefaults()
+	}
+	opt.traceDefaults()
+	errs = append(errs, opt.checkArities(lengths)...)
+	errs = append(errs, opt.checkConstraints()...)
+
+	return errors.Join(errs...)
 }
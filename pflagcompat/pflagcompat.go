@@ -0,0 +1,100 @@
+// Package pflagcompat adapts flag definitions between flags and the
+// github.com/spf13/pflag package, allowing a codebase built on pflag or cobra
+// to be migrated incrementally.
+package pflagcompat
+
+import (
+	"unicode/utf8"
+
+	"github.com/spf13/pflag"
+	flags "gopkg.in/ktnyt/flags.v1"
+)
+
+// noOptValue adapts a pflag value which may be given without an argument,
+// such as a boolean or count flag, into a switch.
+type noOptValue struct {
+	pflag.Value
+	noOpt string
+}
+
+// Set will set attempt to convert the given string to a value. The parser
+// sets switches to `true`, which is replaced by the value used by pflag when
+// the flag is given without an argument.
+func (v noOptValue) Set(s string) error {
+	if s == "true" {
+		s = v.noOpt
+	}
+	return v.Value.Set(s)
+}
+
+// IsBoolFlag reports whether the value can be given as a switch.
+func (v noOptValue) IsBoolFlag() bool { return true }
+
+// Unwrap returns the pflag value.
+func (v noOptValue) Unwrap() flags.Value { return v.Value }
+
+// AddFlag registers a pflag flag on the optional argument definitions along
+// with its shorthand, if any. Flags which pflag accepts without an argument
+// are registered as switches. Hidden and deprecated flags are registered as
+// well.
+func AddFlag(opt *flags.Optional, f *pflag.Flag) {
+	var short rune
+	if f.Shorthand != "" {
+		short, _ = utf8.DecodeRuneInString(f.Shorthand)
+		if _, ok := opt.Alias[short]; ok || short == 'h' {
+			short = 0
+		}
+	}
+	var value flags.Value = f.Value
+	if f.NoOptDefVal != "" {
+		value = noOptValue{f.Value, f.NoOptDefVal}
+	}
+	opt.Register(short, f.Name, value, f.Usage)
+}
+
+// AddFlagSet registers all of the flags in a pflag flag set which are not
+// registered on the optional argument definitions yet.
+func AddFlagSet(opt *flags.Optional, fs *pflag.FlagSet) {
+	fs.VisitAll(func(f *pflag.Flag) {
+		if !opt.Args.Has(f.Name) {
+			AddFlag(opt, f)
+		}
+	})
+}
+
+// typedValue adapts a flags value into a pflag value.
+type typedValue struct {
+	flags.Value
+}
+
+// Type returns the name of the value type.
+func (v typedValue) Type() string { return flags.TypeName(v.Value) }
+
+// Unwrap returns the flags value.
+func (v typedValue) Unwrap() flags.Value { return v.Value }
+
+// Value adapts a flags value into a pflag value.
+func Value(v flags.Value) pflag.Value {
+	if p, ok := v.(pflag.Value); ok {
+		return p
+	}
+	return typedValue{v}
+}
+
+// FlagSet exports the optional argument definitions as a pflag flag set. The
+// flag set shares the values of the definitions, so flags parsed by either
+// package are visible to both.
+func FlagSet(opt *flags.Optional, name string) *pflag.FlagSet {
+	shorts := make(map[string]string)
+	for short, long := range opt.Alias {
+		shorts[long] = string(short)
+	}
+	fs := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	opt.VisitAll(func(long string, arg flags.Argument) {
+		f := fs.VarPF(Value(arg.Value), long, shorts[long], arg.Usage)
+		if b, ok := arg.Value.(flags.BoolFlag); ok && b.IsBoolFlag() {
+			f.NoOptDefVal = "true"
+		}
+	})
+	return fs
+}
@@ -0,0 +1,55 @@
+package pflagcompat
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+	flags "gopkg.in/ktnyt/flags.v1"
+)
+
+func TestAddFlagSet(t *testing.T) {
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	port := fs.IntP("port", "p", 80, "port to listen on")
+	verbose := fs.BoolP("verbose", "v", false, "verbose output")
+	level := fs.CountP("level", "l", "verbosity level")
+	tags := fs.StringSlice("tag", nil, "tags to apply")
+
+	pos, opt := flags.Args()
+	opt.Int('x', "extra", 0, "an extra flag")
+	AddFlagSet(opt, fs)
+
+	args := []string{"-p", "8080", "-v", "-ll", "--tag", "a,b", "--tag", "c"}
+	if err := flags.NewParser(pos, opt).Parse(args); err != nil {
+		t.Fatalf("parser.Parse: %v", err)
+	}
+	if *port != 8080 || !*verbose || *level != 2 {
+		t.Errorf("got port=%d verbose=%t level=%d", *port, *verbose, *level)
+	}
+	if len(*tags) != 3 || (*tags)[2] != "c" {
+		t.Errorf("got tags=%v", *tags)
+	}
+	if got := flags.TypeName(opt.Args["port"].Value); got != "int" {
+		t.Errorf("TypeName = %q, want int", got)
+	}
+}
+
+func TestFlagSet(t *testing.T) {
+	_, opt := flags.Args()
+	name := opt.String('n', "name", "foo", "name of the thing")
+	force := opt.Switch('f', "force", "force it")
+	count := opt.Int(0, "count", 1, "number of things")
+
+	fs := FlagSet(opt, "test")
+	if err := fs.Parse([]string{"-n", "bar", "--force", "--count=3", "rest"}); err != nil {
+		t.Fatalf("fs.Parse: %v", err)
+	}
+	if *name != "bar" || !*force || *count != 3 {
+		t.Errorf("got name=%q force=%t count=%d", *name, *force, *count)
+	}
+	if got := fs.Lookup("count").Value.Type(); got != "int" {
+		t.Errorf("Type() = %q, want int", got)
+	}
+	if got := fs.Args(); len(got) != 1 || got[0] != "rest" {
+		t.Errorf("fs.Args() = %v, want [rest]", got)
+	}
+}
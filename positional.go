@@ -3,18 +3,63 @@ package flags
 import (
 	"fmt"
 	"os"
+	"regexp"
 )
 
+// Unbounded is used as the maximum arity of a variadic argument which
+// accepts any number of values.
+const Unbounded = -1
+
+// Arity represents the number of values a variadic argument accepts.
+type Arity struct {
+	Min int
+	Max int
+}
+
+// Check if the given number of values satisfies the arity.
+func (a Arity) Check(n int) error {
+	switch {
+	case a.Min == a.Max && n != a.Min:
+		return fmt.Errorf(msg("expected exactly %d value(s), got %d"), a.Min, n)
+	case n < a.Min:
+		return fmt.Errorf(msg("expected at least %d value(s), got %d"), a.Min, n)
+	case a.Max != Unbounded && n > a.Max:
+		return fmt.Errorf(msg("expected at most %d value(s), got %d"), a.Max, n)
+	default:
+		return nil
+	}
+}
+
 // Positional represents the positional command line arguments.
 type Positional struct {
-	Order []string
-	Args  Arguments
-	In    *Argument
-	Out   *Argument
+	Order       []string
+	Args        Arguments
+	In          *Argument
+	Out         *Argument
+	Arities     map[string]Arity
+	Completions map[string]CompleteFunc
+
+	// Passthrough stops parsing at `--` or at the first value beyond one per
+	// positional argument, leaving the remaining arguments unparsed for
+	// wrapper commands such as `mytool exec -- someprog --its-flags`. The
+	// remaining arguments are given by Rest.
+	Passthrough bool
+
+	// UpperNames shows the names of the arguments in upper case in the usage
+	// and help, e.g. `SOURCE DEST` instead of `<source> <dest>`.
+	UpperNames bool
+
+	defaults map[string]defaultState
+	rest     []string
 }
 
 func newPositional() *Positional {
-	return &Positional{[]string{}, Arguments{}, nil, nil}
+	return &Positional{
+		Order:       []string{},
+		Args:        Arguments{},
+		Arities:     make(map[string]Arity),
+		Completions: make(map[string]CompleteFunc),
+	}
 }
 
 // Len returns the number of positional arguments.
@@ -29,63 +74,196 @@ func (pos *Positional) Register(name string, value Value, usage string) {
 	pos.Args[name] = Argument{value, usage}
 }
 
+// Rest returns the arguments left unparsed by the last parse if Passthrough
+// is set.
+func (pos *Positional) Rest() []string { return pos.rest }
+
+// Get returns the value of the argument with the given name. It panics if
+// no such argument exists.
+func (pos *Positional) Get(name string) Value {
+	arg, ok := pos.Args[name]
+	if !ok {
+		panic(fmt.Errorf("positional argument with name `%s` does not exist", name))
+	}
+	return arg.Value
+}
+
+// Reset restores the positional arguments given in the last parse to their
+// defaults. It is called before each parse, so that the arguments can be
+// parsed multiple times.
+func (pos *Positional) Reset() {
+	for name, d := range pos.defaults {
+		if arg, ok := pos.Args[name]; ok {
+			d.restore(arg.Value)
+		}
+	}
+}
+
+// recordDefaults remembers the values of arguments not yet parsed as their
+// defaults.
+func (pos *Positional) recordDefaults() {
+	if pos.defaults == nil {
+		pos.defaults = make(map[string]defaultState)
+	}
+	if len(pos.defaults) == len(pos.Args) {
+		return
+	}
+	for name, arg := range pos.Args {
+		if _, ok := pos.defaults[name]; !ok {
+			pos.defaults[name] = recordDefault(arg.Value)
+		}
+	}
+}
+
+// Arity sets the number of values the variadic argument with the given name
+// accepts. Use Unbounded as max to accept any number of values.
+func (pos *Positional) Arity(name string, min, max int) {
+	arg, ok := pos.Args[name]
+	if !ok {
+		panic(fmt.Errorf("positional argument with name `%s` does not exist", name))
+	}
+	if _, ok := arg.Value.(SliceValue); !ok {
+		panic(fmt.Errorf("positional argument with name `%s` is not variadic", name))
+	}
+	if min < 0 || (max != Unbounded && max < min) {
+		panic(fmt.Errorf("invalid arity %d to %d for positional argument `%s`", min, max, name))
+	}
+	pos.Arities[name] = Arity{min, max}
+}
+
+// Complete registers a function for completing the values of the argument
+// with the given name.
+func (pos *Positional) Complete(name string, f CompleteFunc) {
+	if !pos.Args.Has(name) {
+		panic(fmt.Errorf("positional argument with name `%s` does not exist", name))
+	}
+	pos.Completions[name] = f
+}
+
+// Extensions restricts the completion of the file path taken by the argument
+// with the given name, e.g. one backed by an OpenValue or a CreateValue, to
+// files with any of the given extensions and to directories.
+func (pos *Positional) Extensions(name string, exts ...string) {
+	pos.Complete(name, CompleteFiles(exts...))
+}
+
+func (pos *Positional) arity(name string) Arity {
+	if a, ok := pos.Arities[name]; ok {
+		return a
+	}
+	return Arity{0, Unbounded}
+}
+
 // Bool adds a string value to the positional argument list.
 func (pos *Positional) Bool(name, usage string) *bool {
 	value := NewBoolValue(false)
 	pos.Register(name, value, usage)
-	return (*bool)(value)
+	return value.p
 }
 
 // Int adds a string value to the positional argument list.
 func (pos *Positional) Int(name, usage string) *int {
 	value := NewIntValue(0)
 	pos.Register(name, value, usage)
-	return (*int)(value)
+	return value.p
 }
 
 // String adds a string value to the positional argument list.
 func (pos *Positional) String(name, usage string) *string {
 	value := NewStringValue("")
 	pos.Register(name, value, usage)
+	return value.p
+}
+
+// UUID adds a UUID value to the positional argument list.
+func (pos *Positional) UUID(name, usage string) *string {
+	value := NewUUIDValue("")
+	pos.Register(name, value, usage)
 	return (*string)(value)
 }
 
-// Open adds a file for reading to the positional argument list.
-func (pos *Positional) Open(name, usage string) *os.File {
+// Regexp adds a regular expression value to the positional argument list.
+func (pos *Positional) Regexp(name, usage string) *regexp.Regexp {
+	value := NewRegexpValue(nil)
+	pos.Register(name, value, usage)
+	return (*regexp.Regexp)(value)
+}
+
+// Open adds a file for reading to the positional argument list. The file
+// will be closed after the command returns if parsed with Context.Parse.
+func (pos *Positional) Open(name, usage string) *OpenValue {
 	value := NewOpenValue(nil)
 	pos.Register(name, value, usage)
-	return (*os.File)(value)
+	return value
 }
 
-// Create adds a file for writing to the positional argument list.
-func (pos *Positional) Create(name, usage string) *os.File {
+// Create adds a file for writing to the positional argument list. The file
+// will be closed after the command returns if parsed with Context.Parse.
+func (pos *Positional) Create(name, usage string) *CreateValue {
 	value := NewCreateValue(nil)
 	pos.Register(name, value, usage)
-	return (*os.File)(value)
+	return value
+}
+
+// Dir adds an existing directory to the positional argument list.
+func (pos *Positional) Dir(name, usage string) *string {
+	value := NewDirValue("")
+	pos.Register(name, value, usage)
+	return value.p
+}
+
+// OutputDir adds a writable directory to the positional argument list. The
+// directory will be created if it does not exist.
+func (pos *Positional) OutputDir(name, usage string) *string {
+	value := NewDirValue("")
+	value.Create, value.Writable = true, true
+	pos.Register(name, value, usage)
+	return value.p
+}
+
+// Glob adds a variable number of file paths to the positional argument list.
+// Shell-style patterns in the arguments are expanded to the matching paths.
+func (pos *Positional) Glob(name, usage string) *[]string {
+	value := NewGlobSliceValue(nil)
+	pos.Register(name, value, usage)
+	return (*[]string)(value)
 }
 
 // Input adds a file which when omitted will read from os.Stdin.
-func (pos *Positional) Input(usage string) *os.File {
+func (pos *Positional) Input(usage string) *OpenValue {
 	value := NewOpenValue(os.Stdin)
 	pos.In = &Argument{value, usage}
-	return (*os.File)(value)
+	return value
+}
+
+// Inputs adds a variable number of files for reading to the positional
+// argument list. The standard input is used if no files are given and the
+// standard input is not a terminal.
+func (pos *Positional) Inputs(name, usage string) *[]*os.File {
+	value := NewInputSliceValue()
+	pos.Register(name, value, usage)
+	return value.p
 }
 
 func (pos *Positional) needInput() bool {
-	value := pos.In.Value.(*OpenValue)
-	f := (*os.File)(value)
-	return pos.In != nil && isTerminal(f.Fd())
+	if pos.In == nil {
+		return false
+	}
+	value := pos.In.Value.(interface{ Fd() uintptr })
+	return isTerminal(value.Fd())
 }
 
-// Output adds a file which when omitted will read from os.Stdout.
-func (pos *Positional) Output(usage string) *os.File {
+// Output adds a file which when omitted will write to os.Stdout.
+func (pos *Positional) Output(usage string) *CreateValue {
 	value := NewCreateValue(os.Stdout)
 	pos.Out = &Argument{value, usage}
-	return (*os.File)(value)
+	return value
 }
 
 func (pos *Positional) needOutput() bool {
-	value := pos.Out.Value.(*CreateValue)
-	f := (*os.File)(value)
-	return pos.Out != nil && isTerminal(f.Fd())
+	if pos.Out == nil {
+		return false
+	}
+	value := pos.Out.Value.(interface{ Fd() uintptr })
+	return isTerminal(value.Fd())
 }
@@ -0,0 +1,83 @@
+package flags
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"time"
+)
+
+// ProfileFlag is the long name of the flag added by Profile.
+const ProfileFlag = "profile"
+
+// Profile returns a middleware which adds a `--profile` flag to the
+// arguments parsed by the command and any subcommands it dispatches to. When
+// given a directory, the command is profiled from the end of parsing until
+// it returns, after which the CPU profile and the heap profile are written to
+// `cpu.pprof` and `heap.pprof` in the directory along with a note of the
+// elapsed time on the standard error stream. The profiles can be examined
+// with `go tool pprof`.
+//
+//	prog.Use(flags.Profile())
+func Profile() Middleware {
+	return func(cmd Command) Command {
+		return func(ctx *Context) error {
+			ctx.profile = true
+			return cmd(ctx)
+		}
+	}
+}
+
+// addProfile registers the profile flag unless the command defines a flag
+// with the same name itself.
+func (ctx *Context) addProfile(opt *Optional) *Var[string] {
+	if arg, ok := opt.Args[ProfileFlag]; ok {
+		value, _ := arg.Value.(*Var[string])
+		return value
+	}
+	value := New("")
+	opt.Register(0, ProfileFlag, value, "write CPU and heap profiles to the directory")
+	return value
+}
+
+// startProfile starts profiling the command into the directory. The
+// profiling is stopped and the profiles are written after the command
+// returns.
+func (ctx *Context) startProfile(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	cpu, err := os.Create(filepath.Join(dir, "cpu.pprof"))
+	if err != nil {
+		return err
+	}
+	if err := pprof.StartCPUProfile(cpu); err != nil {
+		cpu.Close()
+		return err
+	}
+	start := time.Now()
+	ctx.Defer(func() error {
+		elapsed := time.Since(start)
+		pprof.StopCPUProfile()
+		if err := cpu.Close(); err != nil {
+			return err
+		}
+		heap, err := os.Create(filepath.Join(dir, "heap.pprof"))
+		if err != nil {
+			return err
+		}
+		defer heap.Close()
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(heap); err != nil {
+			return err
+		}
+		fmt.Fprintf(ctx.Stderr, msg("wrote profiles to `%s` after %s")+"\n", dir, elapsed.Round(time.Millisecond))
+		return heap.Close()
+	})
+	return nil
+}
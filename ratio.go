@@ -0,0 +1,80 @@
+package flags
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RatioValue represents a ratio argument value in the range of 0 to 1. A
+// number with a `%` suffix is always interpreted as a percentage.
+type RatioValue struct {
+	p *float64
+
+	// Percent interprets numbers without a `%` suffix as percentages, so that
+	// `75` is equivalent to `75%`.
+	Percent bool
+}
+
+// NewRatioValue creates a new RatioValue.
+func NewRatioValue(init float64) *RatioValue {
+	p := new(float64)
+	*p = init
+	return &RatioValue{p, false}
+}
+
+func (v *RatioValue) state() interface{} { return v.p }
+
+// Set will set attempt to convert the given string to a value.
+func (v *RatioValue) Set(s string) error {
+	t, percent := strings.CutSuffix(strings.TrimSpace(s), "%")
+	f, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+	if err != nil {
+		return fmt.Errorf(msg("`%s` cannot be interpreted as a ratio"), s)
+	}
+	if percent || v.Percent {
+		f /= 100
+	}
+	if f < 0 || f > 1 {
+		if percent || v.Percent {
+			return fmt.Errorf(msg("`%s` is out of range: expected a percentage from 0%% to 100%%"), s)
+		}
+		return fmt.Errorf(msg("`%s` is out of range: expected a ratio from 0 to 1"), s)
+	}
+	*v.p = f
+	return nil
+}
+
+// Type returns the name of the value type.
+func (v *RatioValue) Type() string {
+	if v.Percent {
+		return "percentage"
+	}
+	return "ratio"
+}
+
+// String satisfies the fmt.Stringer interface.
+func (v *RatioValue) String() string {
+	if v.Percent {
+		return strconv.FormatFloat(*v.p*100, 'g', -1, 64) + "%"
+	}
+	return strconv.FormatFloat(*v.p, 'g', -1, 64)
+}
+
+// Ratio adds a ratio flag to the optional argument list. The value is given
+// either as a number from 0 to 1 or as a percentage with a `%` suffix.
+func (opt *Optional) Ratio(short rune, long string, init float64, usage string) *float64 {
+	value := NewRatioValue(init)
+	opt.Register(short, long, value, usage)
+	return value.p
+}
+
+// Percentage adds a percentage flag to the optional argument list. The
+// value is given as a number from 0 to 100 with an optional `%` suffix and
+// is stored as a ratio from 0 to 1.
+func (opt *Optional) Percentage(short rune, long string, init float64, usage string) *float64 {
+	value := NewRatioValue(init)
+	value.Percent = true
+	opt.Register(short, long, value, usage)
+	return value.p
+}
@@ -0,0 +1,93 @@
+package flags
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ValueFactory creates a new value of a registered type.
+type ValueFactory func() Value
+
+var registry = struct {
+	sync.RWMutex
+	factories map[string]ValueFactory
+}{factories: map[string]ValueFactory{
+	"bool":     func() Value { return NewBoolValue(false) },
+	"int":      func() Value { return NewIntValue(0) },
+	"float":    func() Value { return NewFloatValue(0) },
+	"string":   func() Value { return NewStringValue("") },
+	"duration": func() Value { return New(time.Duration(0)) },
+	"strings":  func() Value { return NewStringSliceValue(nil) },
+	"ranges":   func() Value { return NewIntRangeSetValue(nil) },
+	"color":    func() Value { return NewColorValue(RGBA{}) },
+	"cron":     func() Value { return NewCronValue("") },
+	"command":  func() Value { return NewCommandLineValue(nil) },
+}}
+
+// RegisterValueType registers a value type under the given name so that
+// values of the type can be created by name with NewValue, OfType, and the
+// `type` tag of the fields bound by BindValue. Registering a name again
+// replaces the previous factory.
+//
+//	flags.RegisterValueType("ipnet", func() flags.Value { return &IPNetValue{} })
+func RegisterValueType(name string, factory ValueFactory) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.factories[name] = factory
+}
+
+// NewValue creates a new value of the type registered under the given name.
+func NewValue(name string) (Value, error) {
+	registry.RLock()
+	factory, ok := registry.factories[name]
+	registry.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf(msg("unknown value type `%s`"), name)
+	}
+	return factory(), nil
+}
+
+// ValueTypes returns the names of the registered value types in sorted
+// order.
+func ValueTypes() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	names := make([]string, 0, len(registry.factories))
+	for name := range registry.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OfType adds a flag whose value is of the type registered under the given
+// name to the optional argument list. The initial value is set from init
+// unless it is empty and becomes the default of the flag. It panics if the
+// type is unknown or init is invalid.
+func (opt *Optional) OfType(short rune, long, name, init, usage string) Value {
+	value, err := NewValue(name)
+	if err != nil {
+		panic(err)
+	}
+	if init != "" {
+		if err := value.Set(init); err != nil {
+			panic(fmt.Errorf("invalid initial value for optional argument `%s`: %v", long, err))
+		}
+		adoptDefault(value)
+	}
+	opt.Register(short, long, value, usage)
+	return value
+}
+
+// OfType adds a value of the type registered under the given name to the
+// positional argument list. It panics if the type is unknown.
+func (pos *Positional) OfType(name, typ, usage string) Value {
+	value, err := NewValue(typ)
+	if err != nil {
+		panic(err)
+	}
+	pos.Register(name, value, usage)
+	return value
+}
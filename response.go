@@ -0,0 +1,69 @@
+package flags
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// maxResponseDepth limits the nesting of response files.
+const maxResponseDepth = 16
+
+// ExpandResponseFiles replaces each argument of the form `@path` with the
+// arguments contained in the file at path. Each line of the file is a single
+// argument. Blank lines and lines starting with `#` are ignored, and response
+// files may refer to other response files. Arguments following `--` are left
+// as is.
+func ExpandResponseFiles(args []string) ([]string, error) {
+	return expandResponseFiles(args, 0)
+}
+
+func expandResponseFiles(args []string, depth int) ([]string, error) {
+	if depth > maxResponseDepth {
+		return nil, errors.New(msg("response files nested too deeply"))
+	}
+	expanded := []string{}
+	for i, arg := range args {
+		if arg == "--" {
+			return append(expanded, args[i:]...), nil
+		}
+		if !strings.HasPrefix(arg, "@") || len(arg) == 1 {
+			expanded = append(expanded, arg)
+			continue
+		}
+		lines, err := readResponseFile(arg[1:])
+		if err != nil {
+			return nil, err
+		}
+		lines, err = expandResponseFiles(lines, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, lines...)
+	}
+	return expanded, nil
+}
+
+func readResponseFile(name string) ([]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf(msg("in response file: %v"), err)
+	}
+	defer f.Close()
+
+	lines := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf(msg("in response file `%s`: %v"), name, err)
+	}
+	return lines, nil
+}
@@ -0,0 +1,92 @@
+package flags
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+)
+
+// RandomSeed is the keyword accepted by SeedValue for generating a seed.
+const RandomSeed = "random"
+
+// SeedValue represents a random seed argument value given as an integer or
+// as `random`, in which case a seed is generated. Generated seeds are
+// reported to the standard error stream by Context.Parse and given by String
+// so that the run can be reproduced.
+type SeedValue struct {
+	p         *int64
+	init      string
+	generated bool
+}
+
+// NewSeedValue creates a new SeedValue. It panics if the initial value is
+// neither an integer nor `random`.
+func NewSeedValue(init string) *SeedValue {
+	v := &SeedValue{p: new(int64), init: init}
+	if err := v.Set(init); err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Set will set attempt to convert the given string to a value.
+func (v *SeedValue) Set(s string) error {
+	if s == RandomSeed {
+		*v.p, v.generated = rand.Int64(), true
+		return nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf(msg("`%s` is not a valid seed: expected an integer or `%s`"), s, RandomSeed)
+	}
+	*v.p, v.generated = n, false
+	return nil
+}
+
+// Get returns the seed.
+func (v *SeedValue) Get() int64 { return *v.p }
+
+// Generated reports whether the seed was generated.
+func (v *SeedValue) Generated() bool { return v.generated }
+
+// Reset restores the initial value, generating a new seed if it is `random`.
+func (v *SeedValue) Reset() { v.Set(v.init) }
+
+// DefaultString returns the initial value.
+func (v *SeedValue) DefaultString() string { return v.init }
+
+// Type returns the name of the value type.
+func (v *SeedValue) Type() string { return "seed" }
+
+// String satisfies the fmt.Stringer interface.
+func (v *SeedValue) String() string {
+	return strconv.FormatInt(*v.p, 10)
+}
+
+// Seed adds a random seed flag to the optional argument list. The initial
+// value is an integer or `random`.
+func (opt *Optional) Seed(short rune, long, init, usage string) *int64 {
+	value := NewSeedValue(init)
+	opt.Register(short, long, value, usage)
+	return value.p
+}
+
+// reportSeeds writes the seeds generated while parsing to the standard error
+// stream.
+func (ctx *Context) reportSeeds(pos *Positional, opt *Optional) {
+	report := func(name string, arg Argument) {
+		if v, ok := arg.Value.(*SeedValue); ok && v.Generated() {
+			fmt.Fprintf(ctx.Stderr, msg("using random seed %d for %s")+"\n", v.Get(), name)
+		}
+	}
+	if pos != nil {
+		for _, name := range pos.Order {
+			report("<"+name+">", pos.Args[name])
+		}
+	}
+	if opt != nil {
+		opt.VisitAll(func(long string, arg Argument) {
+			report("--"+long, arg)
+		})
+	}
+}
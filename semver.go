@@ -0,0 +1,189 @@
+package flags
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version represents a semantic version.
+type Version struct {
+	Major uint64
+	Minor uint64
+	Patch uint64
+	Pre   string
+	Build string
+}
+
+func isNumeric(s string) bool {
+	for _, c := range s {
+		if c < '0' || '9' < c {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func validIdentifiers(s string, numeric bool) bool {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return false
+		}
+		for _, c := range id {
+			if !(c == '-' || '0' <= c && c <= '9' || 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z') {
+				return false
+			}
+		}
+		if numeric && len(id) > 1 && id[0] == '0' && isNumeric(id) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseVersion parses a semantic version of the form
+// `MAJOR.MINOR.PATCH[-PRERELEASE][+BUILD]` with an optional `v` prefix.
+func ParseVersion(s string) (Version, error) {
+	v := Version{}
+	t := strings.TrimPrefix(s, "v")
+	if i := strings.IndexByte(t, '+'); i >= 0 {
+		t, v.Build = t[:i], t[i+1:]
+		if !validIdentifiers(v.Build, false) {
+			return v, fmt.Errorf(msg("`%s` has invalid build metadata"), s)
+		}
+	}
+	if i := strings.IndexByte(t, '-'); i >= 0 {
+		t, v.Pre = t[:i], t[i+1:]
+		if !validIdentifiers(v.Pre, true) {
+			return v, fmt.Errorf(msg("`%s` has an invalid pre-release version"), s)
+		}
+	}
+	parts := strings.Split(t, ".")
+	if len(parts) != 3 {
+		return v, fmt.Errorf(msg("`%s` is not a semantic version"), s)
+	}
+	nn := make([]uint64, 3)
+	for i, part := range parts {
+		if !isNumeric(part) || (len(part) > 1 && part[0] == '0') {
+			return v, fmt.Errorf(msg("`%s` is not a semantic version"), s)
+		}
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return v, fmt.Errorf(msg("`%s` is not a semantic version"), s)
+		}
+		nn[i] = n
+	}
+	v.Major, v.Minor, v.Patch = nn[0], nn[1], nn[2]
+	return v, nil
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func comparePre(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	aa, bb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aa) && i < len(bb); i++ {
+		x, y := aa[i], bb[i]
+		xn, yn := isNumeric(x), isNumeric(y)
+		switch {
+		case xn && yn:
+			m, _ := strconv.ParseUint(x, 10, 64)
+			n, _ := strconv.ParseUint(y, 10, 64)
+			if c := compareUint(m, n); c != 0 {
+				return c
+			}
+		case xn:
+			return -1
+		case yn:
+			return 1
+		case x != y:
+			return strings.Compare(x, y)
+		}
+	}
+	return compareUint(uint64(len(aa)), uint64(len(bb)))
+}
+
+// Compare returns -1, 0, or 1 if the version has a lower, equal, or higher
+// precedence than the other. Build metadata is ignored.
+func (v Version) Compare(other Version) int {
+	if c := compareUint(v.Major, other.Major); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Minor, other.Minor); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Patch, other.Patch); c != 0 {
+		return c
+	}
+	return comparePre(v.Pre, other.Pre)
+}
+
+// Less reports whether the version has a lower precedence than the other.
+func (v Version) Less(other Version) bool { return v.Compare(other) < 0 }
+
+// String satisfies the fmt.Stringer interface.
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// SemverValue represents a semantic version argument value.
+type SemverValue Version
+
+// NewSemverValue creates a new SemverValue.
+func NewSemverValue(init Version) *SemverValue {
+	p := new(Version)
+	*p = init
+	return (*SemverValue)(p)
+}
+
+// Set will set attempt to convert the given string to a value.
+func (p *SemverValue) Set(s string) error {
+	v, err := ParseVersion(s)
+	if err != nil {
+		return err
+	}
+	*p = SemverValue(v)
+	return nil
+}
+
+// String satisfies the fmt.Stringer interface.
+func (p SemverValue) String() string {
+	return Version(p).String()
+}
+
+// Semver adds a semantic version flag to the optional argument list.
+func (opt *Optional) Semver(short rune, long string, init Version, usage string) *Version {
+	value := NewSemverValue(init)
+	opt.Register(short, long, value, usage)
+	return (*Version)(value)
+}
+
+// Semver adds a semantic version to the positional argument list.
+func (pos *Positional) Semver(name, usage string) *Version {
+	value := NewSemverValue(Version{})
+	pos.Register(name, value, usage)
+	return (*Version)(value)
+}
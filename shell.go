@@ -0,0 +1,147 @@
+package flags
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Split the string into words using shell quoting rules. Words are separated
+// by unquoted whitespace. Single quotes preserve the enclosed characters
+// literally, double quotes preserve the enclosed characters except for
+// backslash escapes of `"` and `\`, and an unquoted backslash escapes the
+// following character.
+func Split(s string) ([]string, error) {
+	words := []string{}
+	word := strings.Builder{}
+	inWord := false
+	rr := []rune(s)
+
+	for i := 0; i < len(rr); i++ {
+		r := rr[i]
+		switch {
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+
+		case r == '\\':
+			i++
+			if i == len(rr) {
+				return nil, errors.New(msg("trailing backslash"))
+			}
+			word.WriteRune(rr[i])
+			inWord = true
+
+		case r == '\'':
+			j := i + 1
+			for j < len(rr) && rr[j] != '\'' {
+				j++
+			}
+			if j == len(rr) {
+				return nil, errors.New(msg("unterminated single quote"))
+			}
+			word.WriteString(string(rr[i+1 : j]))
+			i, inWord = j, true
+
+		case r == '"':
+			j := i + 1
+			for ; j < len(rr) && rr[j] != '"'; j++ {
+				if rr[j] == '\\' && j+1 < len(rr) && (rr[j+1] == '"' || rr[j+1] == '\\') {
+					j++
+				}
+				word.WriteRune(rr[j])
+			}
+			if j == len(rr) {
+				return nil, errors.New(msg("unterminated double quote"))
+			}
+			i, inWord = j, true
+
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// Quote the word so that Split reads it back as a single word. Words without
+// special characters are returned as is.
+func Quote(word string) string {
+	if word == "" {
+		return "''"
+	}
+	if !strings.ContainsFunc(word, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(`'"\$`+"`", r)
+	}) {
+		return word
+	}
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
+
+// Join the words into a string which Split reads back as the same words.
+func Join(words []string) string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = Quote(word)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// CommandLineValue represents a command line given as a single argument,
+// such as `--exec "prog -a 'b c'"`, which is split into words using shell
+// quoting rules.
+type CommandLineValue struct {
+	p *[]string
+}
+
+// NewCommandLineValue creates a new CommandLineValue.
+func NewCommandLineValue(init []string) *CommandLineValue {
+	p := new([]string)
+	*p = append([]string(nil), init...)
+	return &CommandLineValue{p}
+}
+
+func (v *CommandLineValue) state() interface{} { return v.p }
+
+// Set will set attempt to convert the given string to a value.
+func (v *CommandLineValue) Set(s string) error {
+	words, err := Split(s)
+	if err != nil {
+		return fmt.Errorf(msg("`%s` is not a valid command line: %v"), s, err)
+	}
+	*v.p = words
+	return nil
+}
+
+// Get returns the words of the command line.
+func (v *CommandLineValue) Get() []string { return *v.p }
+
+// Type returns the name of the value type.
+func (v *CommandLineValue) Type() string { return "command" }
+
+// String satisfies the fmt.Stringer interface.
+func (v *CommandLineValue) String() string { return Join(*v.p) }
+
+// CommandLine adds a flag taking a command line split using shell quoting
+// rules to the optional argument list.
+func (opt *Optional) CommandLine(short rune, long string, init []string, usage string) *[]string {
+	value := NewCommandLineValue(init)
+	opt.Register(short, long, value, usage)
+	return value.p
+}
+
+// CommandLine adds a command line split using shell quoting rules to the
+// positional argument list.
+func (pos *Positional) CommandLine(name, usage string) *[]string {
+	value := NewCommandLineValue(nil)
+	pos.Register(name, value, usage)
+	return value.p
+}
@@ -0,0 +1,53 @@
+package flags
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// GracePeriod is how long Run waits for a command to return after the
+// context.Context of the command has been canceled by a signal.
+var GracePeriod = 10 * time.Second
+
+// signalCode returns the conventional exit status for a process terminated
+// by the signal, 128 plus the signal number.
+func signalCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return ExitFailure
+}
+
+// execSignals executes the command like Exec while canceling its
+// context.Context when a signal is received. The command is given the grace
+// period to return, or until another signal is received, after which the
+// status for the first signal is returned even if the command is still
+// running.
+func execSignals(ctx *Context, cmd Command, signals <-chan os.Signal) int {
+	ctx.setDefaults()
+	c, cancel := context.WithCancel(ctx.Context())
+	defer cancel()
+	ctx.ctx = c
+
+	done := make(chan int, 1)
+	go func() { done <- Exec(ctx, cmd) }()
+
+	select {
+	case code := <-done:
+		return code
+	case sig := <-signals:
+		cancel()
+		timer := time.NewTimer(GracePeriod)
+		defer timer.Stop()
+		select {
+		case <-done:
+		case <-signals:
+		case <-timer.C:
+			fmt.Fprintf(ctx.Stderr, msg("command did not stop within %s after %s")+"\n", GracePeriod, sig)
+		}
+		return signalCode(sig)
+	}
+}
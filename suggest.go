@@ -0,0 +1,72 @@
+package flags
+
+import (
+	"fmt"
+	"sort"
+)
+
+// distance returns the optimal string alignment distance between the
+// strings, i.e. the number of insertions, deletions, substitutions, and
+// transpositions of adjacent characters needed to turn one into the other.
+func distance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(s)][len(t)]
+}
+
+// maxSuggestDistance is the largest distance of a suggested name.
+const maxSuggestDistance = 2
+
+// similar returns the candidates closest to the name, provided that they are
+// within maxSuggestDistance and closer than the length of the name.
+func similar(name string, candidates []string) []string {
+	best, names := maxSuggestDistance+1, []string{}
+	for _, candidate := range candidates {
+		d := distance(name, candidate)
+		if d >= len([]rune(name)) {
+			continue
+		}
+		switch {
+		case d < best:
+			best, names = d, []string{candidate}
+		case d == best:
+			names = append(names, candidate)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// autocorrect returns the only candidate similar to the name, confirming the
+// correction with the user if the standard input is interactive.
+func (ctx *Context) autocorrect(name string, candidates []string) (string, bool) {
+	names := similar(name, candidates)
+	if len(names) != 1 {
+		return "", false
+	}
+	if f, ok := ctx.Stdin.(interface{ Fd() uintptr }); ok && !isTerminal(f.Fd()) {
+		fmt.Fprintf(ctx.Stderr, msg("assuming you meant `%s` instead of `%s`")+"\n", names[0], name)
+		return names[0], true
+	}
+	ok, err := ctx.Confirm(fmt.Sprintf(msg("unknown command name `%s`, did you mean `%s`?"), name, names[0]))
+	return names[0], ok && err == nil
+}
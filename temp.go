@@ -0,0 +1,186 @@
+package flags
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+func checkDir(s string) error {
+	info, err := os.Stat(s)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf(msg("`%s` is not a directory"), s)
+	}
+	return nil
+}
+
+// TempDirValue represents a temporary directory argument value. The argument
+// is the parent directory in which the temporary directory is created, which
+// defaults to os.TempDir. The temporary directory is removed with all of its
+// contents when the value is closed, which happens after the command returns
+// if parsed with Context.Parse.
+type TempDirValue struct {
+	dir  string
+	path string
+
+	// Pattern is the pattern of the directory name as given to
+	// ioutil.TempDir.
+	Pattern string
+
+	// Lazy defers creating the directory until Path is called.
+	Lazy bool
+}
+
+// NewTempDirValue creates a new TempDirValue.
+func NewTempDirValue(pattern string) *TempDirValue {
+	return &TempDirValue{Pattern: pattern}
+}
+
+// Set will set attempt to convert the given string to a value.
+func (v *TempDirValue) Set(s string) error {
+	if err := checkDir(s); err != nil {
+		return err
+	}
+	v.Close()
+	v.dir = s
+	if v.Lazy {
+		return nil
+	}
+	_, err := v.Path()
+	return err
+}
+
+// Path returns the path of the temporary directory, creating it if it does
+// not exist yet.
+func (v *TempDirValue) Path() (string, error) {
+	if v.path == "" {
+		path, err := ioutil.TempDir(v.dir, v.Pattern)
+		if err != nil {
+			return "", err
+		}
+		v.path = path
+	}
+	return v.path, nil
+}
+
+// String satisfies the fmt.Stringer interface.
+func (v *TempDirValue) String() string {
+	if v.path != "" {
+		return v.path
+	}
+	return v.dir
+}
+
+// Close removes the temporary directory if it was created.
+func (v *TempDirValue) Close() error {
+	if v.path == "" {
+		return nil
+	}
+	path := v.path
+	v.path = ""
+	return os.RemoveAll(path)
+}
+
+// Reset removes the temporary directory if it was created and restores the
+// default parent directory.
+func (v *TempDirValue) Reset() {
+	v.Close()
+	v.dir = ""
+}
+
+// TempFileValue represents a temporary file argument value. The argument is
+// the directory in which the temporary file is created, which defaults to
+// os.TempDir. The temporary file is closed and removed when the value is
+// closed, which happens after the command returns if parsed with
+// Context.Parse.
+type TempFileValue struct {
+	*os.File
+	dir string
+
+	// Pattern is the pattern of the file name as given to ioutil.TempFile.
+	Pattern string
+
+	// Lazy defers creating the file until Writer is called.
+	Lazy bool
+}
+
+// NewTempFileValue creates a new TempFileValue.
+func NewTempFileValue(pattern string) *TempFileValue {
+	return &TempFileValue{Pattern: pattern}
+}
+
+// Set will set attempt to convert the given string to a value.
+func (v *TempFileValue) Set(s string) error {
+	if err := checkDir(s); err != nil {
+		return err
+	}
+	v.Close()
+	v.dir = s
+	if v.Lazy {
+		return nil
+	}
+	_, err := v.Writer()
+	return err
+}
+
+// Writer returns the temporary file, creating it if it does not exist yet.
+func (v *TempFileValue) Writer() (*os.File, error) {
+	if v.File == nil {
+		f, err := ioutil.TempFile(v.dir, v.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		v.File = f
+	}
+	return v.File, nil
+}
+
+// String satisfies the fmt.Stringer interface.
+func (v *TempFileValue) String() string {
+	if v.File != nil {
+		return v.Name()
+	}
+	return v.dir
+}
+
+// Close and remove the temporary file if it was created.
+func (v *TempFileValue) Close() error {
+	if v.File == nil {
+		return nil
+	}
+	f := v.File
+	v.File = nil
+	err := f.Close()
+	if rerr := os.Remove(f.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// Reset removes the temporary file if it was created and restores the
+// default directory.
+func (v *TempFileValue) Reset() {
+	v.Close()
+	v.dir = ""
+}
+
+// TempDir adds a temporary directory flag to the optional argument list. The
+// flag selects the parent directory and the temporary directory is removed
+// after the command returns if parsed with Context.Parse.
+func (opt *Optional) TempDir(short rune, long, pattern, usage string) *TempDirValue {
+	value := NewTempDirValue(pattern)
+	opt.Register(short, long, value, usage)
+	return value
+}
+
+// TempFile adds a temporary file flag to the optional argument list. The
+// flag selects the parent directory and the temporary file is removed after
+// the command returns if parsed with Context.Parse.
+func (opt *Optional) TempFile(short rune, long, pattern, usage string) *TempFileValue {
+	value := NewTempFileValue(pattern)
+	opt.Register(short, long, value, usage)
+	return value
+}
@@ -0,0 +1,55 @@
+package flags
+
+import (
+	"fmt"
+	"text/template"
+)
+
+// TemplateValue represents a text/template argument value. An argument of
+// the form `@path` is parsed from the contents of the file at path.
+type TemplateValue struct {
+	*template.Template
+	text string
+
+	// Funcs are made available to the template before parsing.
+	Funcs template.FuncMap
+}
+
+// NewTemplateValue creates a new TemplateValue. It panics if init is not a
+// valid template.
+func NewTemplateValue(init string) *TemplateValue {
+	v := &TemplateValue{}
+	if err := v.Set(init); err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Set will set attempt to convert the given string to a value.
+func (v *TemplateValue) Set(s string) error {
+	p, err := readIndirect(s)
+	if err != nil {
+		return err
+	}
+	t, err := template.New("").Funcs(v.Funcs).Parse(string(p))
+	if err != nil {
+		return fmt.Errorf(msg("`%s` is not a valid template: %v"), s, err)
+	}
+	v.Template, v.text = t, string(p)
+	return nil
+}
+
+// Type returns the name of the value type.
+func (v *TemplateValue) Type() string { return "template" }
+
+// String satisfies the fmt.Stringer interface.
+func (v *TemplateValue) String() string {
+	return v.text
+}
+
+// Template adds a text/template flag to the optional argument list.
+func (opt *Optional) Template(short rune, long, init, usage string) *TemplateValue {
+	value := NewTemplateValue(init)
+	opt.Register(short, long, value, usage)
+	return value
+}
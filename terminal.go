@@ -1,7 +1,21 @@
 package flags
 
-import isatty "github.com/mattn/go-isatty"
+import (
+	"os"
+	"strconv"
+
+	isatty "github.com/mattn/go-isatty"
+)
 
 func isTerminal(fd uintptr) bool {
 	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
 }
+
+// terminalWidth returns the width of the terminal as given by the COLUMNS
+// environment variable, defaulting to 80 columns.
+func terminalWidth() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return 80
+}
@@ -0,0 +1,52 @@
+package flags
+
+import (
+	"context"
+	"time"
+)
+
+// TimeoutFlag is the long name of the flag added by Timeout.
+const TimeoutFlag = "timeout"
+
+// Timeout returns a middleware which adds a `--timeout` duration flag to the
+// arguments parsed by the command and any subcommands it dispatches to. The
+// context.Context of the command is canceled once the duration has elapsed
+// after parsing. The init duration is used if the flag is not given, and a
+// duration of zero or less disables the timeout. Commands which do not call
+// Context.Parse are not limited.
+//
+//	prog.Use(flags.Timeout(30 * time.Second))
+func Timeout(init time.Duration) Middleware {
+	return func(cmd Command) Command {
+		return func(ctx *Context) error {
+			d := init
+			ctx.timeout = &d
+			return cmd(ctx)
+		}
+	}
+}
+
+// addTimeout registers the timeout flag unless the command defines a flag
+// with the same name itself.
+func (ctx *Context) addTimeout(opt *Optional) *Var[time.Duration] {
+	if arg, ok := opt.Args[TimeoutFlag]; ok {
+		value, _ := arg.Value.(*Var[time.Duration])
+		return value
+	}
+	value := New(*ctx.timeout)
+	opt.Register(0, TimeoutFlag, value, "limit the execution time of the command (0 for no limit)")
+	return value
+}
+
+// applyTimeout limits the context.Context of the command to the duration.
+func (ctx *Context) applyTimeout(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	c, cancel := context.WithTimeout(ctx.Context(), d)
+	ctx.ctx = c
+	ctx.Defer(func() error {
+		cancel()
+		return nil
+	})
+}
@@ -0,0 +1,47 @@
+package flags
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimezoneValue represents a time zone argument value resolved with
+// time.LoadLocation.
+type TimezoneValue struct {
+	*time.Location
+}
+
+// NewTimezoneValue creates a new TimezoneValue. UTC is used if init is nil.
+func NewTimezoneValue(init *time.Location) *TimezoneValue {
+	if init == nil {
+		init = time.UTC
+	}
+	return &TimezoneValue{init}
+}
+
+// Set will set attempt to convert the given string to a value.
+func (v *TimezoneValue) Set(s string) error {
+	name := s
+	if strings.EqualFold(name, "UTC") {
+		name = "UTC"
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "" {
+		return fmt.Errorf(msg("`%s` is not a known time zone: expected an IANA name such as `UTC`, `Local`, or `Asia/Tokyo`"), s)
+	}
+	v.Location = loc
+	return nil
+}
+
+// String satisfies the fmt.Stringer interface.
+func (v *TimezoneValue) String() string {
+	return v.Location.String()
+}
+
+// Timezone adds a time zone flag to the optional argument list.
+func (opt *Optional) Timezone(short rune, long string, init *time.Location, usage string) *TimezoneValue {
+	value := NewTimezoneValue(init)
+	opt.Register(short, long, value, usage)
+	return value
+}
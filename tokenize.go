@@ -0,0 +1,123 @@
+package flags
+
+import (
+	"strconv"
+	"strings"
+)
+
+// TokenKind represents the kind of a Token.
+type TokenKind int
+
+const (
+	// ValueToken is a plain value such as `file.txt` or `-`.
+	ValueToken TokenKind = iota
+
+	// LongToken is a long flag such as `--name` or `--name=value`.
+	LongToken
+
+	// ShortToken is a group of one or more short flags such as `-v` or
+	// `-abc`, possibly with a value attached to one of them.
+	ShortToken
+
+	// NumberToken is a negative number such as `-3` or `-2.5e3`, which may
+	// be either a value or a group of short flags named by digits.
+	NumberToken
+
+	// TerminatorToken is the `--` which ends the flags.
+	TerminatorToken
+)
+
+// Token is an argument classified by Tokenize.
+type Token struct {
+	Kind TokenKind
+
+	// Raw is the argument as given.
+	Raw string
+
+	// Name is the name of a long flag or the characters of a group of short
+	// flags, without the leading dashes.
+	Name string
+
+	// Value is the value of a long flag given as `--name=value`.
+	Value string
+
+	// HasValue reports whether the long flag was given with a value.
+	HasValue bool
+}
+
+// String returns the argument the token was read from.
+func (t Token) String() string {
+	switch t.Kind {
+	case LongToken:
+		if t.HasValue {
+			return "--" + t.Name + "=" + t.Value
+		}
+		return "--" + t.Name
+	case ShortToken:
+		return "-" + t.Name
+	case TerminatorToken:
+		return "--"
+	default:
+		return t.Raw
+	}
+}
+
+// Tokenize classifies each of the arguments lexically, without regard to
+// the flags which are defined, yielding one token per argument. The grammar
+// is as follows, with the first matching rule applied to each argument:
+//
+//	args       = { token } [ terminator { value } ]
+//	terminator = "--"
+//	long       = "--" name [ "=" value ]    name is without "="
+//	number     = "-" digit ...              a negative number, e.g. -3, -2.5e3,
+//	                                        or -0x10
+//	short      = "-" char { char }          a group of short flags
+//	value      = any other argument, including "-" and ""
+//
+// A number is anything strconv reads as a float or as an integer with a base
+// prefix. All arguments following the terminator are values. Whether a
+// number is a value or a group of short flags, and where the value attached
+// to a short flag in a group starts, is decided by the parser from the flag
+// definitions.
+func Tokenize(args []string) []Token {
+	tokens := make([]Token, len(args))
+	terminated := false
+	for i, arg := range args {
+		if terminated {
+			tokens[i] = Token{Kind: ValueToken, Raw: arg}
+			continue
+		}
+		tokens[i] = lexArg(arg)
+		terminated = tokens[i].Kind == TerminatorToken
+	}
+	return tokens
+}
+
+// lexArg classifies a single argument.
+func lexArg(arg string) Token {
+	switch {
+	case arg == "--":
+		return Token{Kind: TerminatorToken, Raw: arg}
+	case strings.HasPrefix(arg, "--"):
+		name, value, hasValue := strings.Cut(arg[2:], "=")
+		return Token{Kind: LongToken, Raw: arg, Name: name, Value: value, HasValue: hasValue}
+	case isNegativeNumber(arg):
+		return Token{Kind: NumberToken, Raw: arg, Name: arg[1:]}
+	case strings.HasPrefix(arg, "-") && arg != "-":
+		return Token{Kind: ShortToken, Raw: arg, Name: arg[1:]}
+	default:
+		return Token{Kind: ValueToken, Raw: arg}
+	}
+}
+
+// isNegativeNumber reports whether the argument is a negative number.
+func isNegativeNumber(arg string) bool {
+	if len(arg) < 2 || arg[0] != '-' || (arg[1] < '0' || arg[1] > '9') && arg[1] != '.' {
+		return false
+	}
+	if _, err := strconv.ParseFloat(arg, 64); err == nil {
+		return true
+	}
+	_, err := strconv.ParseInt(arg, 0, 64)
+	return err == nil
+}
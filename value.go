@@ -1,5 +1,7 @@
 package flags
 
+import "reflect"
+
 // Value represents a command line argument value.
 type Value interface {
 	Set(value string) error
@@ -12,6 +14,24 @@ type SliceValue interface {
 	Len() int
 }
 
+// BoolFlag represents a value which may be given as a switch without a
+// value argument.
+type BoolFlag interface {
+	Value
+	IsBoolFlag() bool
+}
+
+func isBoolFlag(v Value) bool {
+	b, ok := v.(BoolFlag)
+	return ok && b.IsBoolFlag()
+}
+
+// stdinValue represents a value which may fall back to the standard input if
+// no arguments are given.
+type stdinValue interface {
+	useStdin() bool
+}
+
 // Argument represents a value-usages pair.
 type Argument struct {
 	Value Value
@@ -26,3 +46,102 @@ func (args *Arguments) Has(name string) bool {
 	_, ok := (*args)[name]
 	return ok
 }
+
+// Resetter represents a value which can be restored to its default so that
+// the same arguments can be parsed multiple times.
+type Resetter interface {
+	Reset()
+}
+
+// Defaulter represents a value which reports its default as a string.
+type Defaulter interface {
+	DefaultString() string
+}
+
+// defaultState records the default of a value so that it can be restored
+// before parsing again.
+type defaultState struct {
+	s string
+	p reflect.Value
+	v reflect.Value
+}
+
+// stater is implemented by values which hold their state behind a pointer
+// other than themselves, such as Var.
+type stater interface {
+	state() interface{}
+}
+
+// DefaultString returns the default of the value if it is a Defaulter and
+// the current value otherwise.
+func DefaultString(value Value) string {
+	if d, ok := value.(Defaulter); ok {
+		return d.DefaultString()
+	}
+	return value.String()
+}
+
+func recordDefault(value Value) defaultState {
+	d := defaultState{s: DefaultString(value)}
+	if resetter(value) != nil {
+		return d
+	}
+	var p interface{} = unwrapValue(value)
+	if s, ok := p.(stater); ok {
+		p = s.state()
+	}
+	// Keep a copy of what the value points to. Slices are only ever
+	// appended to, so the slice header is enough to restore the default.
+	rv := reflect.ValueOf(p)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		d.p = rv
+		d.v = reflect.New(rv.Type().Elem()).Elem()
+		d.v.Set(rv.Elem())
+	}
+	return d
+}
+
+// restore the value to the recorded default. Values which are not a
+// Resetter are restored by copying back what they pointed to, so values
+// with state which such a shallow copy cannot restore, or with side effects
+// such as open files, must be Resetters. Set is never called, as it may have
+// side effects.
+func (d defaultState) restore(value Value) {
+	if r := resetter(value); r != nil {
+		r.Reset()
+		return
+	}
+	if d.p.IsValid() {
+		d.p.Elem().Set(d.v)
+	}
+}
+
+// adopter represents a Resetter which can adopt its current value as the
+// initial value restored by Reset and reported by DefaultString.
+type adopter interface {
+	adopt()
+}
+
+// adoptDefault makes the current value the default of the value or the
+// innermost wrapped value if it is an adopter.
+func adoptDefault(value Value) {
+	if a, ok := value.(adopter); ok {
+		a.adopt()
+		return
+	}
+	if a, ok := unwrapValue(value).(adopter); ok {
+		a.adopt()
+	}
+}
+
+// resetter returns the value or the innermost wrapped value if it is a
+// Resetter.
+func resetter(value Value) Resetter {
+	if r, ok := value.(Resetter); ok {
+		return r
+	}
+	if r, ok := unwrapValue(value).(Resetter); ok {
+		return r
+	}
+	return nil
+}
@@ -1,218 +1,674 @@
 package flags
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 )
 
 // BoolValue represents a boolean argument value.
-type BoolValue bool
+type BoolValue = Var[bool]
 
 // NewBoolValue creates a new BoolValue.
 func NewBoolValue(init bool) *BoolValue {
-	p := new(bool)
-	*p = init
-	return (*BoolValue)(p)
+	return New(init)
 }
 
-// Set will set attempt to convert the given string to a value.
-func (p *BoolValue) Set(s string) error {
-	v, err := strconv.ParseBool(s)
-	if err != nil {
-		return fmt.Errorf("`%s` cannot be interpreted as %T", s, v)
-	}
-	*p = BoolValue(v)
-	return nil
+// IntValue represents a integer argument value.
+type IntValue = Var[int]
+
+// NewIntValue creates a new IntValue.
+func NewIntValue(init int) *IntValue {
+	return New(init)
 }
 
-// String satisfies the fmt.Stringer interface.
-func (p BoolValue) String() string {
-	return strconv.FormatBool(bool(p))
+// FloatValue represents a float argument value.
+type FloatValue = Var[float64]
+
+// NewFloatValue creates a new FloatValue.
+func NewFloatValue(init float64) *FloatValue {
+	return New(init)
 }
 
-// IntValue represents a integer argument value.
-type IntValue int
+// StringValue represents a string argument value.
+type StringValue = Var[string]
 
-// NewIntValue creates a new IntValue.
-func NewIntValue(init int) *IntValue {
-	p := new(int)
+// NewStringValue creates a new StringValue.
+func NewStringValue(init string) *StringValue {
+	return New(init)
+}
+
+// FileModeValue represents a file permission argument value. Octal notation
+// such as `0644` and symbolic notation such as `u+rw,go-w` are accepted, the
+// latter being applied to the current value. The octal setuid, setgid, and
+// sticky bits such as in `2755` are mapped to the corresponding os.FileMode
+// bits.
+type FileModeValue os.FileMode
+
+// specialModes maps the octal setuid, setgid, and sticky bits to their
+// os.FileMode counterparts.
+var specialModes = []struct {
+	octal uint64
+	mode  os.FileMode
+}{
+	{04000, os.ModeSetuid},
+	{02000, os.ModeSetgid},
+	{01000, os.ModeSticky},
+}
+
+// NewFileModeValue creates a new FileModeValue.
+func NewFileModeValue(init os.FileMode) *FileModeValue {
+	p := new(os.FileMode)
 	*p = init
-	return (*IntValue)(p)
+	return (*FileModeValue)(p)
 }
 
 // Set will set attempt to convert the given string to a value.
-func (p *IntValue) Set(s string) error {
-	v, err := strconv.Atoi(s)
-	if err != nil {
-		return fmt.Errorf("`%s` cannot be interpreted as %T", s, v)
+func (p *FileModeValue) Set(s string) error {
+	if n, err := strconv.ParseUint(s, 8, 32); err == nil {
+		if n > 07777 {
+			return fmt.Errorf(msg("`%s` is not a valid file mode"), s)
+		}
+		mode := os.FileMode(n).Perm()
+		for _, special := range specialModes {
+			if n&special.octal != 0 {
+				mode |= special.mode
+			}
+		}
+		*p = FileModeValue(mode)
+		return nil
+	}
+	mode := os.FileMode(*p) & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+	for _, clause := range strings.Split(s, ",") {
+		i := strings.IndexAny(clause, "+-=")
+		if i < 0 {
+			return fmt.Errorf(msg("`%s` is not a valid file mode"), s)
+		}
+		who, op, perms := clause[:i], clause[i], clause[i+1:]
+		if who == "" {
+			who = "a"
+		}
+		var mask, bits os.FileMode
+		for _, c := range who {
+			switch c {
+			case 'u':
+				mask |= 0700
+			case 'g':
+				mask |= 0070
+			case 'o':
+				mask |= 0007
+			case 'a':
+				mask |= 0777
+			default:
+				return fmt.Errorf(msg("`%s` is not a valid file mode"), s)
+			}
+		}
+		for _, c := range perms {
+			switch c {
+			case 'r':
+				bits |= 0444
+			case 'w':
+				bits |= 0222
+			case 'x':
+				bits |= 0111
+			default:
+				return fmt.Errorf(msg("`%s` is not a valid file mode"), s)
+			}
+		}
+		switch op {
+		case '+':
+			mode |= bits & mask
+		case '-':
+			mode &^= bits & mask
+		case '=':
+			mode = mode&^mask | bits&mask
+		}
 	}
-	*p = IntValue(v)
+	*p = FileModeValue(mode)
 	return nil
 }
 
 // String satisfies the fmt.Stringer interface.
-func (p IntValue) String() string {
-	return strconv.Itoa(int(p))
+func (p FileModeValue) String() string {
+	n := uint64(os.FileMode(p).Perm())
+	for _, special := range specialModes {
+		if os.FileMode(p)&special.mode != 0 {
+			n |= special.octal
+		}
+	}
+	return fmt.Sprintf("%04o", n)
 }
 
-// FloatValue represents a float argument value.
-type FloatValue float64
+// UUIDValue represents a UUID argument value. The canonical form, the form
+// enclosed in braces, and the form without dashes are accepted and stored in
+// the lowercase canonical form.
+type UUIDValue string
 
-// NewFloatValue creates a new FloatValue.
-func NewFloatValue(init float64) *FloatValue {
-	p := new(float64)
+// NewUUIDValue creates a new UUIDValue.
+func NewUUIDValue(init string) *UUIDValue {
+	p := new(string)
 	*p = init
-	return (*FloatValue)(p)
+	return (*UUIDValue)(p)
 }
 
 // Set will set attempt to convert the given string to a value.
-func (p *FloatValue) Set(s string) error {
-	v, err := strconv.ParseFloat(s, 64)
-	if err != nil {
-		return fmt.Errorf("`%s` cannot be interpreted as %T", s, v)
+func (p *UUIDValue) Set(s string) error {
+	t := strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}")
+	if len(t) != len(s) && len(t) != len(s)-2 {
+		return fmt.Errorf(msg("`%s` is not a valid UUID"), s)
+	}
+	if len(t) == 36 {
+		for _, i := range []int{8, 13, 18, 23} {
+			if t[i] != '-' {
+				return fmt.Errorf(msg("`%s` is not a valid UUID"), s)
+			}
+		}
+		t = strings.ReplaceAll(t, "-", "")
 	}
-	*p = FloatValue(v)
+	if len(t) != 32 {
+		return fmt.Errorf(msg("`%s` is not a valid UUID"), s)
+	}
+	t = strings.ToLower(t)
+	for _, c := range t {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return fmt.Errorf(msg("`%s` is not a valid UUID"), s)
+		}
+	}
+	*p = UUIDValue(t[:8] + "-" + t[8:12] + "-" + t[12:16] + "-" + t[16:20] + "-" + t[20:])
 	return nil
 }
 
 // String satisfies the fmt.Stringer interface.
-func (p FloatValue) String() string {
-	return strconv.FormatFloat(float64(p), 'g', -1, 64)
+func (p UUIDValue) String() string {
+	return string(p)
 }
 
-// StringValue represents a string argument value.
-type StringValue string
+// RegexpValue represents a regular expression argument value.
+type RegexpValue regexp.Regexp
 
-// NewStringValue creates a new StringValue.
-func NewStringValue(init string) *StringValue {
-	p := new(string)
-	*p = init
-	return (*StringValue)(p)
+// NewRegexpValue creates a new RegexpValue. The empty pattern which matches
+// any string is used if init is nil.
+func NewRegexpValue(init *regexp.Regexp) *RegexpValue {
+	if init == nil {
+		init = regexp.MustCompile("")
+	}
+	p := new(regexp.Regexp)
+	*p = *init
+	return (*RegexpValue)(p)
 }
 
 // Set will set attempt to convert the given string to a value.
-func (p *StringValue) Set(s string) error {
-	*p = StringValue(s)
+func (p *RegexpValue) Set(s string) error {
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return fmt.Errorf(msg("`%s` is not a valid regular expression: %v"), s, err)
+	}
+	*p = RegexpValue(*re)
 	return nil
 }
 
 // String satisfies the fmt.Stringer interface.
-func (p StringValue) String() string {
-	return string(p)
+func (p *RegexpValue) String() string {
+	return (*regexp.Regexp)(p).String()
 }
 
 // OpenValue represents a file argument value for opening.
-type OpenValue os.File
+type OpenValue struct {
+	*os.File
+	init   *os.File
+	opened bool
+	path   string
+
+	// Lazy defers opening the file until Reader is called. The path is
+	// only validated when set.
+	Lazy bool
+}
 
 // NewOpenValue creates a new OpenValue.
 func NewOpenValue(init *os.File) *OpenValue {
-	p := new(os.File)
-	if init != nil {
-		*p = *init
-	}
-	return (*OpenValue)(p)
+	return &OpenValue{File: init, init: init}
 }
 
 // Set will set attempt to convert the given string to a value.
-func (p *OpenValue) Set(s string) error {
+func (v *OpenValue) Set(s string) error {
+	if v.Lazy {
+		info, err := os.Stat(s)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return fmt.Errorf(msg("`%s` is a directory"), s)
+		}
+		v.Close()
+		v.path = s
+		return nil
+	}
 	f, err := os.Open(s)
 	if err != nil {
 		return err
 	}
-	*p = OpenValue(*f)
+	v.Close()
+	v.File, v.opened = f, true
 	return nil
 }
 
+// Reader returns the file for reading, opening it if it is pending.
+func (v *OpenValue) Reader() (io.ReadCloser, error) {
+	if v.path != "" {
+		f, err := os.Open(v.path)
+		if err != nil {
+			return nil, err
+		}
+		v.File, v.opened, v.path = f, true, ""
+	}
+	if v.File == nil {
+		return nil, errors.New(msg("no file to read from"))
+	}
+	return v.File, nil
+}
+
 // String satisfies the fmt.Stringer interface.
-func (p *OpenValue) String() string {
-	return (*os.File)(p).Name()
+func (v *OpenValue) String() string {
+	switch {
+	case v.path != "":
+		return v.path
+	case v.File == nil:
+		return ""
+	default:
+		return v.Name()
+	}
+}
+
+// Close the file if it was opened by the value. The initial file is left
+// open.
+func (v *OpenValue) Close() error {
+	v.path = ""
+	if !v.opened {
+		return nil
+	}
+	v.opened = false
+	return v.File.Close()
+}
+
+// Reset closes the file if it was opened by the value and restores the
+// initial file without reopening it.
+func (v *OpenValue) Reset() {
+	v.Close()
+	v.File = v.init
 }
 
-// CreateValue represents a file argument value for creating.
-type CreateValue os.File
+// CreateValue represents a file argument value for creating. The file is
+// truncated if it exists unless Append or Exclusive is set.
+type CreateValue struct {
+	*os.File
+	init    *os.File
+	created bool
+	path    string
+
+	// Append to the file instead of truncating it.
+	Append bool
+
+	// Exclusive fails if the file already exists.
+	Exclusive bool
+
+	// Perm is the permission bits used if the file is created.
+	Perm os.FileMode
+
+	// Lazy defers creating the file until Writer is called. The path is
+	// only validated when set.
+	Lazy bool
+}
 
 // NewCreateValue creates a new CreateValue.
 func NewCreateValue(init *os.File) *CreateValue {
-	p := new(os.File)
-	if init != nil {
-		*p = *init
+	return &CreateValue{File: init, init: init, Perm: 0666}
+}
+
+func (v *CreateValue) create(s string) (*os.File, error) {
+	flag := os.O_WRONLY | os.O_CREATE
+	switch {
+	case v.Append:
+		flag |= os.O_APPEND
+	default:
+		flag |= os.O_TRUNC
+	}
+	if v.Exclusive {
+		flag |= os.O_EXCL
 	}
-	return (*CreateValue)(p)
+	return os.OpenFile(s, flag, v.Perm)
 }
 
 // Set will set attempt to convert the given string to a value.
-func (p *CreateValue) Set(s string) error {
-	f, err := os.Create(s)
+func (v *CreateValue) Set(s string) error {
+	if v.Lazy {
+		info, err := os.Stat(s)
+		switch {
+		case os.IsNotExist(err):
+			dir, err := os.Stat(filepath.Dir(s))
+			if err != nil {
+				return err
+			}
+			if !dir.IsDir() {
+				return fmt.Errorf(msg("`%s` is not a directory"), filepath.Dir(s))
+			}
+		case err != nil:
+			return err
+		case info.IsDir():
+			return fmt.Errorf(msg("`%s` is a directory"), s)
+		case v.Exclusive:
+			return fmt.Errorf(msg("`%s` already exists"), s)
+		}
+		v.Close()
+		v.path = s
+		return nil
+	}
+	f, err := v.create(s)
 	if err != nil {
 		return err
 	}
-	*p = CreateValue(*f)
+	v.Close()
+	v.File, v.created = f, true
 	return nil
 }
 
+// Writer returns the file for writing, creating it if it is pending.
+func (v *CreateValue) Writer() (io.WriteCloser, error) {
+	if v.path != "" {
+		f, err := v.create(v.path)
+		if err != nil {
+			return nil, err
+		}
+		v.File, v.created, v.path = f, true, ""
+	}
+	if v.File == nil {
+		return nil, errors.New(msg("no file to write to"))
+	}
+	return v.File, nil
+}
+
 // String satisfies the fmt.Stringer interface.
-func (p *CreateValue) String() string {
-	return (*os.File)(p).Name()
+func (v *CreateValue) String() string {
+	switch {
+	case v.path != "":
+		return v.path
+	case v.File == nil:
+		return ""
+	default:
+		return v.Name()
+	}
+}
+
+// Close the file if it was created by the value. The initial file is left
+// open.
+func (v *CreateValue) Close() error {
+	v.path = ""
+	if !v.created {
+		return nil
+	}
+	v.created = false
+	return v.File.Close()
+}
+
+// Reset closes the file if it was created by the value and restores the
+// initial file without creating it again.
+func (v *CreateValue) Reset() {
+	v.Close()
+	v.File = v.init
+}
+
+// DirValue represents a directory path argument value.
+type DirValue struct {
+	p *string
+
+	// Create the directory and any missing parents if it does not exist.
+	Create bool
+
+	// Writable requires the directory to be writable.
+	Writable bool
+}
+
+// NewDirValue creates a new DirValue.
+func NewDirValue(init string) *DirValue {
+	p := new(string)
+	*p = init
+	return &DirValue{p, false, false}
+}
+
+func (v *DirValue) state() interface{} { return v.p }
+
+// Set will set attempt to convert the given string to a value.
+func (v *DirValue) Set(s string) error {
+	info, err := os.Stat(s)
+	switch {
+	case os.IsNotExist(err) && v.Create:
+		if err := os.MkdirAll(s, 0755); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case !info.IsDir():
+		return fmt.Errorf(msg("`%s` is not a directory"), s)
+	}
+	if v.Writable {
+		f, err := ioutil.TempFile(s, ".flags")
+		if err != nil {
+			return fmt.Errorf(msg("`%s` is not writable"), s)
+		}
+		f.Close()
+		os.Remove(f.Name())
+	}
+	*v.p = s
+	return nil
+}
+
+// String satisfies the fmt.Stringer interface.
+func (v *DirValue) String() string {
+	return *v.p
 }
 
 // StringSliceValue represents a variable number string argument value.
-type StringSliceValue []string
+type StringSliceValue = SliceVar[string]
 
 // NewStringSliceValue creates a new StringSliceValue.
 func NewStringSliceValue(init []string) *StringSliceValue {
+	return Slice(init...)
+}
+
+// StringSetValue represents a variable number string argument value which
+// ignores duplicate entries. The order of first appearance is preserved.
+type StringSetValue struct {
+	elems []string
+	seen  map[string]bool
+	init  []string
+}
+
+// NewStringSetValue creates a new StringSetValue.
+func NewStringSetValue(init []string) *StringSetValue {
+	v := &StringSetValue{nil, make(map[string]bool), init}
+	v.Reset()
+	return v
+}
+
+// Reset restores the initial elements.
+func (v *StringSetValue) Reset() {
+	v.elems = nil
+	clear(v.seen)
+	for _, s := range v.init {
+		v.Set(s)
+	}
+}
+
+func (v *StringSetValue) adopt() { v.init = slices.Clone(v.elems) }
+
+// DefaultString returns the string representation of the initial elements.
+func (v *StringSetValue) DefaultString() string {
+	return NewStringSetValue(v.init).String()
+}
+
+// Len will return the number of distinct elements.
+func (v *StringSetValue) Len() int { return len(v.elems) }
+
+// Set will add the given string to the set.
+func (v *StringSetValue) Set(s string) error {
+	if !v.seen[s] {
+		v.seen[s] = true
+		v.elems = append(v.elems, s)
+	}
+	return nil
+}
+
+// Contains reports whether the given string is in the set.
+func (v *StringSetValue) Contains(s string) bool { return v.seen[s] }
+
+// Slice returns the elements of the set in order of first appearance.
+func (v *StringSetValue) Slice() []string {
+	return append([]string(nil), v.elems...)
+}
+
+// Get returns the elements of the set in order of first appearance.
+func (v *StringSetValue) Get() []string { return v.Slice() }
+
+// String satisfies the fmt.Stringer interface.
+func (v *StringSetValue) String() string {
+	return fmt.Sprintf("[%s]", strings.Join(v.elems, ", "))
+}
+
+// GlobSliceValue represents a variable number file path argument value which
+// expands shell-style patterns regardless of the shell in use.
+type GlobSliceValue []string
+
+// NewGlobSliceValue creates a new GlobSliceValue.
+func NewGlobSliceValue(init []string) *GlobSliceValue {
 	p := new([]string)
 	*p = init
-	return (*StringSliceValue)(p)
+	return (*GlobSliceValue)(p)
 }
 
 // Len will return the length of the slice value.
-func (v StringSliceValue) Len() int { return len(v) }
+func (v GlobSliceValue) Len() int { return len(v) }
 
-// Set will set attempt to convert and append the given string to the slice.
-func (p *StringSliceValue) Set(s string) error {
+// Set will expand the given pattern and append the matches to the slice.
+func (p *GlobSliceValue) Set(s string) error {
 	ss := []string(*p)
-	ss = append(ss, s)
-	*p = StringSliceValue(ss)
+	if !strings.ContainsAny(s, "*?[") {
+		*p = GlobSliceValue(append(ss, s))
+		return nil
+	}
+	matches, err := filepath.Glob(s)
+	if err != nil {
+		return fmt.Errorf(msg("`%s` is not a valid pattern: %v"), s, err)
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf(msg("no files match `%s`"), s)
+	}
+	*p = GlobSliceValue(append(ss, matches...))
 	return nil
 }
 
 // String satisfies the fmt.Stringer interface.
-func (p StringSliceValue) String() string {
+func (p GlobSliceValue) String() string {
 	return fmt.Sprintf("[%s]", strings.Join([]string(p), ", "))
 }
 
 // OpenSliceValue represents a variable number open argument value.
-type OpenSliceValue []*os.File
+type OpenSliceValue struct {
+	p      *[]*os.File
+	init   []*os.File
+	opened []*os.File
+}
 
 // NewOpenSliceValue creates a new OpenSliceValue.
 func NewOpenSliceValue(init []*os.File) *OpenSliceValue {
 	p := new([]*os.File)
 	*p = init
-	return (*OpenSliceValue)(p)
+	return &OpenSliceValue{p, init[:len(init):len(init)], nil}
 }
 
+// Get returns the files in the slice.
+func (v *OpenSliceValue) Get() []*os.File { return *v.p }
+
 // Len will return the length of the slice value.
-func (v OpenSliceValue) Len() int { return len(v) }
+func (v *OpenSliceValue) Len() int { return len(*v.p) }
 
 // Set will set attempt to convert and append the given string to the slice.
-func (p *OpenSliceValue) Set(s string) error {
-	ff := []*os.File(*p)
+func (v *OpenSliceValue) Set(s string) error {
 	f, err := os.Open(s)
 	if err != nil {
 		return err
 	}
-	ff = append(ff, f)
-	*p = OpenSliceValue(ff)
+	v.opened = append(v.opened, f)
+	*v.p = append(*v.p, f)
 	return nil
 }
 
 // String satisfies the fmt.Stringer interface.
-func (v OpenSliceValue) String() string {
-	ss := make([]string, len(v))
-	for i, f := range v {
+func (v *OpenSliceValue) String() string {
+	ss := make([]string, len(*v.p))
+	for i, f := range *v.p {
 		ss[i] = f.Name()
 	}
 	return fmt.Sprintf("[%s]", strings.Join(ss, ", "))
 }
+
+// Close the files which were opened by the value. The initial files are left
+// open.
+func (v *OpenSliceValue) Close() error {
+	var err error
+	for _, f := range v.opened {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	v.opened = nil
+	return err
+}
+
+// Reset closes the files which were opened by the value and restores the
+// initial files.
+func (v *OpenSliceValue) Reset() {
+	v.Close()
+	*v.p = v.init
+}
+
+// InputSliceValue represents a variable number open argument value which
+// defaults to the standard input when no files are given and the standard
+// input is not a terminal, as filter commands such as grep and sed do.
+type InputSliceValue struct {
+	*OpenSliceValue
+
+	// Stdin is the file used when no files are given.
+	Stdin *os.File
+}
+
+// NewInputSliceValue creates a new InputSliceValue reading from os.Stdin by
+// default.
+func NewInputSliceValue() *InputSliceValue {
+	return &InputSliceValue{NewOpenSliceValue(nil), os.Stdin}
+}
+
+func (v *InputSliceValue) useStdin() bool {
+	if v.Len() > 0 || v.Stdin == nil || isTerminal(v.Stdin.Fd()) {
+		return false
+	}
+	*v.p = append(*v.p, v.Stdin)
+	return true
+}
+
+// Close the files which were opened by the value. The standard input is left
+// open.
+func (v *InputSliceValue) Close() error {
+	return v.OpenSliceValue.Close()
+}
+
+// Reset closes the files which were opened by the value and removes all of
+// the files from the slice. The standard input is left open.
+func (v *InputSliceValue) Reset() {
+	v.OpenSliceValue.Reset()
+}